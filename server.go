@@ -4,56 +4,151 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"html/template"
+	"io"
 	"log"
+	"math"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/mail"
 	"net/smtp"
+	"net/url"
 	"os"
+	"os/signal"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	_ "time/tzdata" // the runtime image ships without a zoneinfo database
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // 5. FUNCTIONS AND ERROR HANDLING
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserAlreadyExists  = errors.New("user already exists")
-	ErrTokenExpired       = errors.New("token has expired")
-	ErrPetNotFound        = errors.New("pet not found")
-	ErrInvalidPayment     = errors.New("invalid payment details")
-	ErrEmailFailed        = errors.New("email delivery failed")
+	ErrInvalidCredentials   = errors.New("invalid credentials")
+	ErrUserAlreadyExists    = errors.New("user already exists")
+	ErrTokenExpired         = errors.New("token has expired")
+	ErrSessionIdle          = errors.New("session expired due to inactivity")
+	ErrPetNotFound          = errors.New("pet not found")
+	ErrInvalidPayment       = errors.New("invalid payment details")
+	ErrEmailFailed          = errors.New("email delivery failed")
+	ErrInquiryNotFound      = errors.New("adoption inquiry not found")
+	ErrDonationNotFound     = errors.New("donation not found")
+	ErrServiceNotFound      = errors.New("service not found")
+	ErrInvalidCategory      = errors.New("invalid service category")
+	ErrPackageNotFound      = errors.New("service package not found")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrInvalidRating        = errors.New("rating must be between 1 and 5")
+	ErrBookingNotFound      = errors.New("booking not found")
+	ErrBookingCancelled     = errors.New("booking is already cancelled")
+	ErrBookingCompleted     = errors.New("booking is already completed")
+	ErrBookingConflict      = errors.New("this service is already booked for that date and time")
+	ErrBookingNotCompleted  = errors.New("only completed bookings can be rated")
+	ErrBookingRated         = errors.New("booking has already been rated")
+	ErrTestimonialNotFound  = errors.New("testimonial not found")
+	ErrInvalidVolunteer     = errors.New("name, a valid email and availability are required")
+	ErrInvalidInquiryStatus = errors.New("status must be Pending, Approved or Rejected")
+	ErrFosterNotFound       = errors.New("foster application not found")
+	ErrRefreshTooEarly      = errors.New("token can't be refreshed yet")
+	ErrEventNotFound        = errors.New("event not found")
+	ErrInvalidEmail         = errors.New("invalid email address")
+	ErrServiceInUse         = errors.New("service is part of an active package")
+	ErrInvalidEventTime     = errors.New("endsAt must be after startsAt")
+	ErrDonationNotPending   = errors.New("donation is not awaiting payment")
+	ErrFeeAlreadyPaid       = errors.New("adoption fee has already been recorded")
+	ErrDonationLinked       = errors.New("donation already pays for another adoption")
+	ErrPetAlreadyAdopted    = errors.New("pet has already been adopted")
+	ErrContactNotFound      = errors.New("contact message not found")
+	ErrWaitlistFull         = errors.New("the waitlist for this pet is full")
 )
 
 // 6. INTERFACE
 type Filterable interface {
 	Filter(pets []Pet) []Pet
+	Match(pet Pet) bool
 	Name() string
 }
 
+// filterPets keeps the pets for which match returns true.
+func filterPets(petList []Pet, match func(Pet) bool) []Pet {
+	result := make([]Pet, 0)
+	for _, p := range petList {
+		if match(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
 // 4. MAP AND STRUCTS
 type Pet struct {
-	ID           string            `json:"id"`
-	Name         string            `json:"name"`
-	Species      string            `json:"species"`
-	Breed        string            `json:"breed"`
-	Age          int               `json:"age"`
-	Gender       string            `json:"gender"`
-	Description  string            `json:"description"`
-	Status       string            `json:"status"` // Available, Adopted, Under Care
-	IsVaccinated bool              `json:"isVaccinated"`
-	CreatedAt    time.Time         `json:"createdAt"`
-	Tags         []string          `json:"tags"`       // 3. ARRAY AND SLICE
-	Attributes   map[string]string `json:"attributes"` // 4. MAP AND STRUCTS
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Species       string            `json:"species"`
+	Breed         string            `json:"breed"`
+	Age           int               `json:"age"`
+	Gender        string            `json:"gender"`
+	Description   string            `json:"description"`
+	Status        string            `json:"status"`               // Available, Adopted, Under Care
+	CareReason    string            `json:"careReason,omitempty"` // why a pet is Under Care
+	AdoptionFee   float64           `json:"adoptionFee"`
+	IsVaccinated  bool              `json:"isVaccinated"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	Tags          []string          `json:"tags"`                   // 3. ARRAY AND SLICE
+	Attributes    map[string]string `json:"attributes"`             // 4. MAP AND STRUCTS
+	Images        []string          `json:"images"`                 // photo URLs
+	BrokenImages  []string          `json:"brokenImages,omitempty"` // Images that failed the reachability check
+	Featured      bool              `json:"featured"`               // pet of the week; at most one at a time
+	FeaturedAt    *time.Time        `json:"featuredAt,omitempty"`
+	FeaturedUntil *time.Time        `json:"featuredUntil,omitempty"`
+	NeedsReview   bool              `json:"needsReview,omitempty"` // Available past staleListingAge with no inquiries
+	Medical       *MedicalInfo      `json:"medical,omitempty"`
 }
 
+// MedicalInfo is a pet's vet history as entered by staff.
+type MedicalInfo struct {
+	LastCheckup string `json:"lastCheckup,omitempty"` // date of the last vet visit, e.g. 2024-03-15
+}
+
+// MarshalJSON adds the computed ageCategory so frontends don't each derive it.
+func (p Pet) MarshalJSON() ([]byte, error) {
+	type petJSON Pet // drops the method set to avoid recursion
+	return json.Marshal(struct {
+		petJSON
+		AgeCategory string `json:"ageCategory"`
+	}{petJSON(p), ageCategory(p)})
+}
+
+// careReasons are the allowed CareReason values for a pet "Under Care".
+var careReasons = []string{"Medical", "Behavioral", "Quarantine", "Recovery"}
+
+// serviceCategories is the canonical set of service categories; inputs are
+// matched case-insensitively and stored in this spelling.
+var serviceCategories = []string{"Care", "Medical", "Training", "Grooming", "Boarding"}
+
 type Service struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
@@ -66,11 +161,21 @@ type Service struct {
 }
 
 type ContactForm struct {
+	ID      string    `json:"id"`
 	Name    string    `json:"name"`
 	Email   string    `json:"email"`
 	Purpose string    `json:"purpose"`
 	Message string    `json:"message"`
 	SentAt  time.Time `json:"sentAt"`
+	Handled bool      `json:"-"` // set by staff via MarkContactHandled, never by the submitter
+
+	CaptchaToken string `json:"captchaToken,omitempty"` // cleared once verified
+}
+
+// contactMessageView is how staff see a contact message, Handled included.
+type contactMessageView struct {
+	ContactForm
+	Handled bool `json:"handled"`
 }
 
 type ServiceBooking struct {
@@ -85,8 +190,69 @@ type ServiceBooking struct {
 	Notes     string    `json:"notes"`
 	Status    string    `json:"status"`
 	BookedAt  time.Time `json:"bookedAt"`
+	PackageID string    `json:"packageId,omitempty"` // set on bookings created from a package
+	Rated     bool      `json:"rated,omitempty"`     // a rating was submitted for this booking
+}
+
+// ServiceDetail is a service with its live rating.
+type ServiceDetail struct {
+	Service
+	Rating      float64 `json:"rating"`
+	RatingCount int     `json:"ratingCount"`
+}
+
+// BookingDetail is a booking with the service it was made for. Service is
+// nil if that service has since been deleted.
+type BookingDetail struct {
+	ServiceBooking
+	Service *Service `json:"service"`
+}
+
+// ServicePackage bundles several services at a discounted price.
+type ServicePackage struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	ServiceIDs     []string `json:"serviceIds"`
+	Price          float64  `json:"price"`
+	SuggestedPrice float64  `json:"suggestedPrice"`
+	Active         bool     `json:"active"`
+}
+
+// Usernames that could pass for staff are reserved outright; blocked words are
+// rejected anywhere in a username. Both are extended via RESERVED_USERNAMES and
+// BLOCKED_USERNAME_WORDS.
+var (
+	reservedUsernames = []string{
+		"admin", "administrator", "root", "system", "support", "help", "helpdesk",
+		"staff", "moderator", "mod", "official", "security", "billing", "noreply",
+		"pawtner", "pawtnerhope", "pawtnerhopefoundation",
+	}
+	blockedUsernameWords = []string{"fuck", "shit", "bitch", "cunt", "nigger", "faggot"}
+
+	// Throwaway email domains rejected at registration, loaded from
+	// DISPOSABLE_DOMAINS_FILE (default disposable_domains.txt)
+	disposableDomains = map[string]bool{}
+)
+
+// validateUsername rejects reserved names and blocked words, ignoring case.
+func validateUsername(username string) error {
+	lower := strings.ToLower(strings.TrimSpace(username))
+	for _, name := range reservedUsernames {
+		if lower == name {
+			return fmt.Errorf("the username %q is reserved", username)
+		}
+	}
+	for _, word := range blockedUsernameWords {
+		if strings.Contains(lower, word) {
+			return errors.New("this username is not allowed")
+		}
+	}
+	return nil
 }
 
+// validRoles are the only values User.Role may take.
+var validRoles = []string{"user", "staff", "admin"}
+
 type User struct {
 	ID        string    `json:"id"`
 	Email     string    `json:"email"`
@@ -96,16 +262,18 @@ type User struct {
 	IsAdmin   bool      `json:"isadmin" bson:"isadmin"`
 	CreatedAt time.Time `json:"createdAt"`
 	IsActive  bool      `json:"isActive"`
+	Favorites []string  `json:"favorites"` // shortlisted pet IDs
 }
 
 type AuthToken struct {
-	Token     string    `json:"token"`
-	UserID    string    `json:"userId"`
-	ExpiresAt time.Time `json:"expiresAt"`
-	Role      string    `json:"role"`
-	IsAdmin   bool      `json:"isadmin"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
+	Token      string    `json:"token"`
+	UserID     string    `json:"userId"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	Role       string    `json:"role"`
+	IsAdmin    bool      `json:"isadmin"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
 }
 
 type Donation struct {
@@ -115,9 +283,16 @@ type Donation struct {
 	Amount             float64   `json:"amount"`
 	PaymentMethod      string    `json:"paymentMethod"`
 	TransactionID      string    `json:"transactionId"`
-	Status             string    `json:"status"` // Pending, Completed, Failed
+	Status             string    `json:"status"` // Pending, Completed, Failed, Expired
 	CreatedAt          time.Time `json:"createdAt"`
-	PaymentViaDeeplink bool      `json:"paymentViaDeeplink"` // true when paid via mobile UPI deeplink
+	PaymentViaDeeplink bool      `json:"paymentViaDeeplink"`       // true when paid via mobile UPI deeplink
+	UserID             string    `json:"userId,omitempty"`         // set when the donor was logged in
+	DonorPhone         string    `json:"donorPhone,omitempty"`     // optional, for SMS thank-you
+	Campaign           string    `json:"campaign,omitempty"`       // fundraising campaign the gift was made to
+	DedicationType     string    `json:"dedicationType,omitempty"` // "memory" or "honor"
+	DedicationName     string    `json:"dedicationName,omitempty"`
+	HonoreeEmail       string    `json:"honoreeEmail,omitempty"` // optional, notified of the dedication
+	CaptchaToken       string    `json:"captchaToken,omitempty"` // cleared once verified
 }
 
 type Receipt struct {
@@ -129,6 +304,25 @@ type Receipt struct {
 	Message    string    `json:"message"`
 }
 
+type GivingStatement struct {
+	Year        int        `json:"year"`
+	DonorName   string     `json:"donorName"`
+	DonorEmail  string     `json:"donorEmail"`
+	Donations   []Donation `json:"donations"`
+	Total       float64    `json:"total"`
+	GeneratedAt time.Time  `json:"generatedAt"`
+}
+
+type DonationSummary struct {
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	TotalAmount      float64   `json:"totalAmount"`
+	DonationCount    int       `json:"donationCount"`
+	DonorCount       int       `json:"donorCount"`
+	TopCampaign      string    `json:"topCampaign"`
+	TopCampaignTotal float64   `json:"topCampaignTotal"`
+}
+
 type AdoptionInquiry struct {
 	ID          string    `json:"id"`
 	PetID       string    `json:"petId"`
@@ -138,14 +332,170 @@ type AdoptionInquiry struct {
 	Message     string    `json:"message"`
 	Status      string    `json:"status"` // Pending, Approved, Rejected
 	CreatedAt   time.Time `json:"createdAt"`
+	DonationID  string    `json:"donationId,omitempty"` // adoption-fee payment, linked once approved
+	FeePaid     bool      `json:"feePaid"`
+	FeeAmount   float64   `json:"feeAmount,omitempty"`
+
+	Answers map[string]string `json:"answers,omitempty"` // keyed by AdoptionQuestion.Key
+
+	// The pet's status before this inquiry's approval marked it Adopted,
+	// restored if the approval is withdrawn
+	PetStatusBefore string `json:"-"`
+}
+
+// AdoptionQuestion is a required question on the adoption form.
+type AdoptionQuestion struct {
+	Key    string `json:"key"`
+	Prompt string `json:"prompt"`
+}
+
+// Testimonial is a supporter's story. Submissions stay hidden until an
+// admin approves them.
+type Testimonial struct {
+	ID         string     `json:"id"`
+	Author     string     `json:"author"`
+	Text       string     `json:"text"`
+	PetName    string     `json:"petName,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	Approved   bool       `json:"approved"`
+	ApprovedAt *time.Time `json:"approvedAt,omitempty"`
+}
+
+// Free text from the public is stored as typed and HTML-escaped whenever it
+// is written out, so a page that drops it into its markup can't be made to
+// run script. The MarshalJSON methods below do the escaping.
+
+// MarshalJSON escapes the author, text and pet name.
+func (t Testimonial) MarshalJSON() ([]byte, error) {
+	type testimonialJSON Testimonial // drops the method set to avoid recursion
+	out := testimonialJSON(t)
+	out.Author, out.Text, out.PetName = html.EscapeString(t.Author), html.EscapeString(t.Text), html.EscapeString(t.PetName)
+	return json.Marshal(out)
+}
+
+// questionsForSpecies returns the adoption questions for a species.
+func questionsForSpecies(species string) []AdoptionQuestion {
+	for name, questions := range adoptionQuestions {
+		if strings.EqualFold(name, species) {
+			return questions
+		}
+	}
+	return defaultAdoptionQuestions
+}
+
+// missingAnswers lists the keys of questions left blank in answers.
+func missingAnswers(questions []AdoptionQuestion, answers map[string]string) []string {
+	var missing []string
+	for _, q := range questions {
+		if strings.TrimSpace(answers[q.Key]) == "" {
+			missing = append(missing, q.Key)
+		}
+	}
+	return missing
+}
+
+// FosterApplication is a user's offer to foster, optionally a specific pet.
+// It is reviewed like an adoption inquiry.
+type FosterApplication struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	PetID     string    `json:"petId,omitempty"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"` // Pending, Approved, Rejected
+	CreatedAt time.Time `json:"createdAt"`
+
+	// The pet's status before this application's approval moved it to
+	// "In Foster", restored if the approval is withdrawn
+	PetStatusBefore string `json:"-"`
+}
+
+// MarshalJSON escapes the applicant's free text.
+func (a FosterApplication) MarshalJSON() ([]byte, error) {
+	type fosterJSON FosterApplication
+	out := fosterJSON(a)
+	out.Name, out.Phone, out.Message = html.EscapeString(a.Name), html.EscapeString(a.Phone), html.EscapeString(a.Message)
+	return json.Marshal(out)
+}
+
+// Volunteer is a sign-up from someone offering their time.
+type Volunteer struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Phone        string    `json:"phone"`
+	Interests    []string  `json:"interests"`    // e.g. "dog walking", "events"
+	Availability string    `json:"availability"` // free text, e.g. "weekends"
+	CreatedAt    time.Time `json:"createdAt"`
+	Status       string    `json:"status"` // Pending, Contacted, Active, Inactive
+
+	CaptchaToken string `json:"captchaToken,omitempty"` // cleared once verified
+}
+
+// MarshalJSON escapes the volunteer's free text.
+func (v Volunteer) MarshalJSON() ([]byte, error) {
+	type volunteerJSON Volunteer
+	out := volunteerJSON(v)
+	out.Name, out.Phone, out.Availability = html.EscapeString(v.Name), html.EscapeString(v.Phone), html.EscapeString(v.Availability)
+	if v.Interests != nil {
+		out.Interests = make([]string, len(v.Interests))
+		for i, interest := range v.Interests {
+			out.Interests[i] = html.EscapeString(interest)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Event is an adoption drive or other shelter event. Only published events
+// appear on the public calendar.
+type Event struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartsAt    time.Time `json:"startsAt"`
+	EndsAt      time.Time `json:"endsAt"`
+	Location    string    `json:"location"`
+	Published   bool      `json:"published"`
+}
+
+// MarshalJSON escapes the title, description and location.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type eventJSON Event
+	out := eventJSON(e)
+	out.Title, out.Description, out.Location = html.EscapeString(e.Title), html.EscapeString(e.Description), html.EscapeString(e.Location)
+	return json.Marshal(out)
+}
+
+// FailedLogin records a rejected login attempt for security review.
+type FailedLogin struct {
+	ID     string    `json:"id"`
+	Email  string    `json:"email"`
+	IP     string    `json:"ip"`
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"` // missing_credentials, unknown_email or wrong_password
+	// Further attempts from the same IP folded into this record once it hit
+	// failedLoginRecordLimit
+	Repeats int `json:"repeats,omitempty"`
+}
+
+// AuditEntry records an administrative action for later review.
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`  // email of the user who acted
+	Action string    `json:"action"` // e.g. "donation.resend-receipt"
+	Target string    `json:"target"` // ID of the record acted on
+	Detail string    `json:"detail,omitempty"`
 }
 
 // 11. GOROUTINES AND CHANNELS
 type NotificationJob struct {
-	To      string
-	Subject string
-	Body    string
-	JobType string
+	To        string
+	Subject   string
+	Body      string
+	JobType   string
+	PlainText bool // send as text/plain instead of HTML
 }
 
 type PaymentConfirmation struct {
@@ -155,12 +505,21 @@ type PaymentConfirmation struct {
 	Error         string
 }
 
+// pendingRegistrationTTL is how long a verification code stays valid.
+const pendingRegistrationTTL = 5 * time.Minute
+
+// maxVerifyAttempts is how many wrong codes a pending registration survives.
+// It counts across resends, since a resend keeps the same code.
+const maxVerifyAttempts = 5
+
 type PendingRegistration struct {
 	Email          string
 	Username       string
 	HashedPassword string
 	Code           string
 	ExpiresAt      time.Time
+	Reminded       bool // "code expiring" reminder already sent
+	Attempts       int  // wrong codes entered so far
 }
 
 // SMTP config (loaded from .env)
@@ -169,6 +528,10 @@ var (
 	smtpPass string
 	smtpHost string = "smtp.gmail.com"
 	smtpPort string = "587"
+
+	// Refuse to send unless the server upgrades to TLS (SMTP_REQUIRE_TLS)
+	smtpRequireTLS    bool   = true
+	smtpTLSMinVersion uint16 = tls.VersionTLS12
 )
 
 // loadEnv reads KEY=VALUE lines from a .env file and calls os.Setenv.
@@ -195,12 +558,246 @@ func loadEnv(filename string) {
 	}
 }
 
+// loadDomainList reads one domain per line, skipping blanks and # comments.
+func loadDomainList(filename string) (map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = true
+	}
+	return domains, scanner.Err()
+}
+
+// isDisposableEmail reports whether email's domain, or any parent domain, is
+// on the disposable-domain blocklist.
+func isDisposableEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for domain != "" {
+		if disposableDomains[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("[CONFIG] Ignoring invalid %s=%q", key, v)
+		return def
+	}
+	return n
+}
+
+// envDuration reads a duration environment variable (e.g. "30m"), falling back to def.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("[CONFIG] Ignoring invalid %s=%q", key, v)
+		return def
+	}
+	return d
+}
+
+// envBool reads a boolean environment variable ("true", "1", ...), falling back to def.
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("[CONFIG] Ignoring invalid %s=%q", key, v)
+		return def
+	}
+	return b
+}
+
+// envTLSVersion reads a minimum TLS version ("1.2" or "1.3"), falling back to def.
+func envTLSVersion(key string, def uint16) uint16 {
+	switch v := os.Getenv(key); v {
+	case "":
+		return def
+	case "1.2":
+		return tls.VersionTLS12
+	case "1.3":
+		return tls.VersionTLS13
+	default:
+		log.Printf("[CONFIG] Ignoring invalid %s=%q (want 1.2 or 1.3)", key, v)
+		return def
+	}
+}
+
 // 1. VARIABLES, VALUES AND TYPES
 var (
 	serverStartTime time.Time = time.Now()
 	serverVersion   string    = "1.0.0"
 	maxPets         int       = 100
 
+	// Donation thank-you SMS (off unless SMS_ENABLED=true)
+	smsEnabled bool      = false
+	smsSender  SMSSender = logSMSSender{}
+
+	// Builds donation receipts; swap in a custom generator for other formats
+	receiptGenerator ReceiptGenerator = defaultReceiptGenerator{}
+
+	// CAPTCHA on public forms (off unless CAPTCHA_ENABLED=true)
+	captchaEnabled  bool            = false
+	captchaVerifier CaptchaVerifier = nil
+
+	// Weekly donations report
+	adminEmail           string        = "pawtnerhopefoundation@gmail.com"
+	weeklyReportInterval time.Duration = 7 * 24 * time.Hour
+
+	// UPI payee for generated deeplinks, and the accepted amount range (INR)
+	upiPayeeVPA  string = "adsgpt@upi"
+	upiPayeeName string = "Pawtner Hope Foundation"
+	upiNote      string = "Donation to Pawtner Hope"
+	upiMinAmount int    = 1
+	upiMaxAmount int    = 100000
+
+	// Deeplinks each client IP may request a minute, and the shared secret the
+	// payment provider signs UPI callbacks with ("" disables the callback)
+	upiLinkRateLimit  int    = 5
+	upiCallbackSecret string = ""
+
+	// Waitlist sign-ups each client IP may make a minute, and the most
+	// addresses kept on one pet's waitlist
+	waitlistRateLimit int = 10
+	maxWaitlistPerPet int = 200
+
+	// Thank-you page a donation redirects to (with ?receipt=) when the client
+	// asks for ?redirect=true or Accept: text/html ("" always answers JSON)
+	donationSuccessURL string = ""
+
+	// Page size used when ?limit= is absent, and the upper bound it's clamped to
+	defaultPageSize int = 100
+	maxPageSize     int = 100
+
+	// Timezone for dates shown to people in emails and reports (DISPLAY_TIMEZONE)
+	displayLocation *time.Location = time.Local
+
+	// bcrypt work factor for password hashes
+	bcryptCost int = bcrypt.DefaultCost
+
+	// Outgoing email pace; Gmail starts rejecting bulk bursts (0 = unlimited).
+	// The pace is shared by all emailWorkerCount workers.
+	emailRatePerMinute int = 20
+	emailWorkerCount   int = 4
+
+	// Reaper cadence, the "code expiring" reminder, and how long
+	// Idempotency-Key replays are honoured
+	reapInterval       time.Duration = 30 * time.Second
+	otpReminderEnabled bool          = true
+	otpReminderWindow  time.Duration = 2 * time.Minute
+	idempotencyKeyTTL  time.Duration = 24 * time.Hour
+
+	// Pending (deeplink) donations unconfirmed for this long are marked Expired
+	pendingDonationTTL time.Duration = 24 * time.Hour
+
+	// A donor may ask for their receipt again only after this long
+	receiptRequestInterval time.Duration = time.Minute
+
+	// How long a pet stays pet of the week before it's unfeatured automatically
+	featuredDuration time.Duration = 7 * 24 * time.Hour
+
+	// Available pets listed longer than staleListingAge with no inquiries are
+	// flagged NeedsReview, checked every staleListingInterval (0 age disables);
+	// staleListingEmail also emails the newly flagged pets to adminEmail
+	staleListingAge      time.Duration = 90 * 24 * time.Hour
+	staleListingInterval time.Duration = 24 * time.Hour
+	staleListingEmail    bool          = false
+
+	// Minimum age (years) for each pet age category; younger pets are "Baby"
+	youngMinAge  int = 1
+	adultMinAge  int = 3
+	seniorMinAge int = 8
+
+	// Tokens unused for longer than this are rejected (0 disables the idle check)
+	tokenIdleTimeout time.Duration = 2 * time.Hour
+
+	// How often dead tokens are swept out of tokenStore
+	tokenCleanupInterval time.Duration = 10 * time.Minute
+
+	// How long a token is valid, and how close to expiry it must be before it
+	// can be refreshed (0 allows refreshing at any time)
+	tokenLifetime      time.Duration = 24 * time.Hour
+	tokenRefreshWindow time.Duration = 6 * time.Hour
+
+	// Size limits for Pet.Tags and Pet.Attributes
+	maxPetTags              int = 20
+	maxTagLength            int = 50
+	maxPetAttributes        int = 30
+	maxAttributeKeyLength   int = 50
+	maxAttributeValueLength int = 200
+
+	// Statuses hidden from public search unless an admin asks for them
+	unlistedStatuses = []string{"Adopted", "Archived"}
+
+	// Discount applied to the summed service prices when suggesting a package price
+	packageDiscount float64 = 0.10
+
+	// Hidden form field that people leave empty and bots fill in ("" disables)
+	honeypotField string = "website"
+
+	// How long shutdown waits for in-flight requests and queued jobs
+	shutdownTimeout time.Duration = 30 * time.Second
+
+	// How long (seconds) browsers may cache a CORS preflight response, and the
+	// status preflights answer with (200 or 204)
+	corsMaxAge          int = 600
+	corsPreflightStatus int = http.StatusOK
+
+	// Background HEAD check of pet photo URLs after create/update
+	photoCheckEnabled bool          = false
+	photoCheckTimeout time.Duration = 5 * time.Second
+
+	// Required adoption-form questions by pet species; species without an
+	// entry get defaultAdoptionQuestions
+	adoptionQuestions = map[string][]AdoptionQuestion{
+		"Dog": {
+			{Key: "yard", Prompt: "Do you have a yard? Roughly how big is it, and is it fenced?"},
+			{Key: "hoursAlone", Prompt: "How many hours a day would the dog be left alone?"},
+		},
+		"Cat": {
+			{Key: "indoorOnly", Prompt: "Will the cat be kept indoors only?"},
+		},
+	}
+	defaultAdoptionQuestions = []AdoptionQuestion{
+		{Key: "experience", Prompt: "Have you cared for this kind of pet before?"},
+	}
+
+	// Origins allowed to call the API cross-origin, with credentials
+	allowedOrigins = []string{"http://localhost:8080", "http://127.0.0.1:8080"}
+
 	// 3. ARRAY AND SLICE
 	pets            []Pet
 	services        []Service
@@ -209,6 +806,23 @@ var (
 	users           []User
 	donations       []Donation
 	inquiries       []AdoptionInquiry
+	packages        []ServicePackage
+	packageSeq      int
+	testimonials    []Testimonial
+	volunteers      []Volunteer
+	fosterApps      []FosterApplication
+	events          []Event
+	eventSeq        int
+
+	// Last service number handed out, so deleted IDs aren't reused
+	serviceSeq int
+
+	// Last pet number handed out. It never goes down, so it doubles as the
+	// count of every animal ever rescued for the impact block.
+	petSeq int
+
+	// Domain events for observers; see EventBus
+	eventBus *EventBus
 
 	// 4. MAP AND STRUCTS
 	petsByID     map[string]*Pet
@@ -220,20 +834,77 @@ var (
 	serviceStats map[string]map[string]interface{}
 	petsByBreed  map[string][]string
 
+	// Emails waiting for an Adopted / Under Care pet to become available again
+	waitlistByPet map[string][]string
+
+	// Idempotency-Key header -> pet created for it (POST /api/pets)
+	idempotencyKeys map[string]idempotencyEntry
+
+	// Administrative actions, oldest first
+	auditLog []AuditEntry
+
+	// Rejected logins, oldest first, capped at maxFailedLogins. Each client
+	// IP gets at most failedLoginRecordLimit records a minute; attempts past
+	// that are counted on its latest record instead of stored.
+	failedLogins           []FailedLogin
+	failedLoginSeq         int
+	maxFailedLogins        int = 10000
+	failedLoginRecordLimit int = 20
+	failedLoginLimiter     *rateLimiter
+
+	// Donation ID -> when a donor last requested its receipt
+	receiptRequests map[string]time.Time
+	upiLinkLimiter  *rateLimiter
+	waitlistLimiter *rateLimiter
+
+	// Honoree emails per donor and per honoree address, per day
+	dedicationLimiter     *rateLimiter
+	dedicationNoticeLimit int = 3
+
 	// 10. CONCURRENCY
 	notificationCh   chan NotificationJob
 	paymentCh        chan Donation
 	paymentConfirmCh chan PaymentConfirmation
-	mu               sync.Mutex
+	mu               sync.RWMutex
 
-	emailShouldFail bool = false
+	// Simulates an SMTP outage; read by every email worker
+	emailShouldFail atomic.Bool
 
-	// MongoDB
-	mongoClient *mongo.Client
-	mongoDB     *mongo.Database
+	// MongoDB; mongoConfigured is set when MONGODB_URI was given, even if
+	// the connection then failed
+	mongoClient     *mongo.Client
+	mongoDB         *mongo.Database
+	mongoConfigured bool
 
 	// Pending email verifications
 	pendingRegs map[string]*PendingRegistration
+
+	// Per-collection sync timeout and write concern. Donations are financial
+	// records so they wait for a journaled majority; pets favour fast failure.
+	mongoWritePolicies = map[string]mongoWritePolicy{
+		"pets":         {Timeout: 3 * time.Second, WriteConcern: writeconcern.W1()},
+		"users":        {Timeout: 5 * time.Second, WriteConcern: writeconcern.Majority()},
+		"donations":    {Timeout: 10 * time.Second, WriteConcern: &writeconcern.WriteConcern{W: "majority", Journal: &journaledWrites}},
+		"inquiries":    {Timeout: 5 * time.Second, WriteConcern: writeconcern.W1()},
+		"testimonials": {Timeout: 5 * time.Second, WriteConcern: writeconcern.W1()},
+		"volunteers":   {Timeout: 5 * time.Second, WriteConcern: writeconcern.W1()},
+		"foster":       {Timeout: 5 * time.Second, WriteConcern: writeconcern.W1()},
+		"failedLogins": {Timeout: 3 * time.Second, WriteConcern: writeconcern.W1()},
+		"events":       {Timeout: 5 * time.Second, WriteConcern: writeconcern.W1()},
+		"packages":     {Timeout: 5 * time.Second, WriteConcern: writeconcern.W1()},
+		"counters":     {Timeout: 5 * time.Second, WriteConcern: writeconcern.Majority()},
+	}
+	journaledWrites = true
+
+	// Failed Mongo writes are retried by mongoRetryWorker before landing in failedSyncs
+	mongoRetryCh      chan mongoSyncJob
+	failedSyncs       []mongoSyncJob
+	mongoMaxRetries   int           = 5
+	mongoRetryBackoff time.Duration = 2 * time.Second
+
+	// At most mongoMaxConcurrent writes run at once; each holds a slot in mongoSlots
+	mongoMaxConcurrent int = 10
+	mongoSlots         chan struct{}
 )
 
 func initializeData() {
@@ -245,6 +916,16 @@ func initializeData() {
 	statusCounts = make(map[string]int)
 	serviceStats = make(map[string]map[string]interface{})
 	petsByBreed = make(map[string][]string)
+	waitlistByPet = make(map[string][]string)
+	idempotencyKeys = make(map[string]idempotencyEntry)
+	auditLog = make([]AuditEntry, 0)
+	failedLogins = make([]FailedLogin, 0)
+	failedLoginLimiter = newRateLimiter(failedLoginRecordLimit, time.Minute)
+	failedLoginSeq = 0
+	receiptRequests = make(map[string]time.Time)
+	upiLinkLimiter = newRateLimiter(upiLinkRateLimit, time.Minute)
+	waitlistLimiter = newRateLimiter(waitlistRateLimit, time.Minute)
+	dedicationLimiter = newRateLimiter(dedicationNoticeLimit, 24*time.Hour)
 
 	// 3. ARRAY AND SLICE
 	pets = make([]Pet, 0, maxPets)
@@ -254,11 +935,23 @@ func initializeData() {
 	users = make([]User, 0)
 	donations = make([]Donation, 0)
 	inquiries = make([]AdoptionInquiry, 0)
+	packages = make([]ServicePackage, 0)
+	packageSeq = 0
+	testimonials = make([]Testimonial, 0)
+	volunteers = make([]Volunteer, 0)
+	fosterApps = make([]FosterApplication, 0)
+	events = make([]Event, 0)
+	eventSeq = 0
+	eventBus = newEventBus()
+	resetImpactCache()
 
 	notificationCh = make(chan NotificationJob, 100)
 	paymentCh = make(chan Donation, 50)
 	paymentConfirmCh = make(chan PaymentConfirmation, 50)
 	pendingRegs = make(map[string]*PendingRegistration)
+	mongoRetryCh = make(chan mongoSyncJob, 100)
+	mongoSlots = make(chan struct{}, max(mongoMaxConcurrent, 1))
+	failedSyncs = make([]mongoSyncJob, 0)
 
 	samplePets := []Pet{
 		{
@@ -298,6 +991,7 @@ func initializeData() {
 			Gender:       "Male",
 			Description:  "Playful puppy with lots of energy",
 			Status:       "Under Care",
+			CareReason:   "Behavioral",
 			IsVaccinated: false,
 			CreatedAt:    time.Now().AddDate(0, 0, -10),
 			Tags:         []string{"Playful", "Young", "Needs Training"},
@@ -306,6 +1000,7 @@ func initializeData() {
 	}
 
 	// 2. LOOPING STRUCTURES
+	petSeq = len(samplePets)
 	for i, pet := range samplePets {
 		pets = append(pets, pet)
 		petsByID[pet.ID] = &pets[i]
@@ -357,14 +1052,16 @@ func initializeData() {
 	}
 
 	// 2. LOOPING STRUCTURES
+	serviceSeq = len(sampleServices)
 	for i := 0; i < len(sampleServices); i++ {
 		services = append(services, sampleServices[i])
 		servicesByID[sampleServices[i].ID] = &services[i]
 		serviceStats[sampleServices[i].ID] = map[string]interface{}{
-			"bookings":  0,
-			"revenue":   0.0,
-			"rating":    4.5,
-			"available": sampleServices[i].Available,
+			"bookings":    0,
+			"revenue":     0.0,
+			"rating":      0.0,
+			"ratingCount": 0,
+			"available":   sampleServices[i].Available,
 		}
 	}
 
@@ -373,7 +1070,7 @@ func initializeData() {
 		ID:        "usr-admin",
 		Email:     "admin@pawtner.com",
 		Username:  "admin",
-		Password:  hashPassword("admin123"),
+		Password:  mustHashPassword("admin123"),
 		Role:      "admin",
 		IsAdmin:   true,
 		CreatedAt: time.Now(),
@@ -399,18 +1096,151 @@ func validatePet(pet Pet) (bool, []string) {
 		errs = append(errs, "Age must be between 0 and 30")
 	}
 
+	if pet.AdoptionFee < 0 {
+		errs = append(errs, "Adoption fee cannot be negative")
+	}
+
 	switch pet.Status {
-	case "Available", "Adopted", "Under Care":
+	case "Available", "Adopted", "Under Care", "In Foster", "Archived":
 	default:
 		errs = append(errs, "Invalid status")
 	}
 
+	if err := validateCareReason(pet.Status, pet.CareReason); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	errs = append(errs, validatePetCollections(pet)...)
+
 	return len(errs) == 0, errs
 }
 
+// validateCareReason requires a known reason for "Under Care" pets and no
+// reason for any other status.
+func validateCareReason(status, reason string) error {
+	if status != "Under Care" {
+		if reason != "" {
+			return errors.New("Care reason is only allowed for pets Under Care")
+		}
+		return nil
+	}
+	if reason == "" {
+		return errors.New("Care reason is required for pets Under Care")
+	}
+	for _, r := range careReasons {
+		if reason == r {
+			return nil
+		}
+	}
+	return fmt.Errorf("Care reason must be one of %s", strings.Join(careReasons, ", "))
+}
+
+// validatePetCollections caps Tags and Attributes so a single payload can't
+// bloat memory or MongoDB. Shared by create and update.
+func validatePetCollections(pet Pet) []string {
+	errs := make([]string, 0)
+
+	if len(pet.Tags) > maxPetTags {
+		errs = append(errs, fmt.Sprintf("At most %d tags are allowed", maxPetTags))
+	}
+	for _, tag := range pet.Tags {
+		if len(tag) > maxTagLength {
+			errs = append(errs, fmt.Sprintf("Tags must be at most %d characters", maxTagLength))
+			break
+		}
+	}
+
+	if len(pet.Attributes) > maxPetAttributes {
+		errs = append(errs, fmt.Sprintf("At most %d attributes are allowed", maxPetAttributes))
+	}
+	for key, value := range pet.Attributes {
+		if len(key) > maxAttributeKeyLength {
+			errs = append(errs, fmt.Sprintf("Attribute names must be at most %d characters", maxAttributeKeyLength))
+			break
+		}
+		if len(value) > maxAttributeValueLength {
+			errs = append(errs, fmt.Sprintf("Attribute values must be at most %d characters", maxAttributeValueLength))
+			break
+		}
+	}
+
+	return errs
+}
+
+// pendingCounts tallies the items awaiting staff attention, for the admin nav badges.
+func pendingCounts() map[string]int {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	counts := map[string]int{
+		"inquiries":       0,
+		"bookings":        0,
+		"failedDonations": 0,
+		"messages":        0,
+		"brokenPhotos":    0,
+		"testimonials":    0,
+		"volunteers":      0,
+		"foster":          0,
+		"needsReview":     0,
+	}
+	for _, inq := range inquiries {
+		if inq.Status == "Pending" {
+			counts["inquiries"]++
+		}
+	}
+	for _, b := range bookings {
+		if b.Status == "Pending" {
+			counts["bookings"]++
+		}
+	}
+	for _, d := range donations {
+		if d.Status == "Failed" {
+			counts["failedDonations"]++
+		}
+	}
+	for _, m := range contactMessages {
+		if !m.Handled {
+			counts["messages"]++
+		}
+	}
+	for _, p := range pets {
+		if len(p.BrokenImages) > 0 {
+			counts["brokenPhotos"]++
+		}
+		if p.NeedsReview {
+			counts["needsReview"]++
+		}
+	}
+	for _, t := range testimonials {
+		if !t.Approved {
+			counts["testimonials"]++
+		}
+	}
+	for _, v := range volunteers {
+		if v.Status == "Pending" {
+			counts["volunteers"]++
+		}
+	}
+	for _, f := range fosterApps {
+		if f.Status == "Pending" {
+			counts["foster"]++
+		}
+	}
+	return counts
+}
+
+// calculateStatistics snapshots the counts under mu. Every map it returns is a
+// copy, so encoding the result can't race with handlers mutating state.
 func calculateStatistics() map[string]interface{} {
+	mu.RLock()
+	defer mu.RUnlock()
+
 	stats := make(map[string]interface{})
-	stats["petsByStatus"] = statusCounts
+	byStatus := make(map[string]int, len(statusCounts))
+	for status, n := range statusCounts {
+		byStatus[status] = n
+	}
+	stats["petsByStatus"] = byStatus
 
 	speciesCount := make(map[string]int)
 	for _, pet := range pets {
@@ -418,6 +1248,22 @@ func calculateStatistics() map[string]interface{} {
 	}
 	stats["petsBySpecies"] = speciesCount
 
+	careReasonCount := make(map[string]int)
+	for _, pet := range pets {
+		if pet.Status == "Under Care" {
+			careReasonCount[pet.CareReason]++
+		}
+	}
+	stats["petsByCareReason"] = careReasonCount
+
+	feesCollected := 0.0
+	for _, inq := range inquiries {
+		if inq.FeePaid {
+			feesCollected += inq.FeeAmount
+		}
+	}
+	stats["adoptionFeesCollected"] = feesCollected
+
 	if len(pets) > 0 {
 		totalAge := 0
 		vaccinatedCount := 0
@@ -450,32 +1296,78 @@ type SpeciesFilter struct {
 	Species string
 }
 
-func (f SpeciesFilter) Filter(petList []Pet) []Pet {
-	result := make([]Pet, 0)
-	for _, p := range petList {
-		if strings.EqualFold(p.Species, f.Species) {
-			result = append(result, p)
-		}
+func (f SpeciesFilter) Match(p Pet) bool { return strings.EqualFold(p.Species, f.Species) }
+
+func (f SpeciesFilter) Filter(petList []Pet) []Pet { return filterPets(petList, f.Match) }
+
+func (f SpeciesFilter) Name() string { return "SpeciesFilter" }
+
+// BreedFilter matches breed case-insensitively; an empty Breed matches everything.
+type BreedFilter struct {
+	Breed string
+}
+
+func (f BreedFilter) Match(p Pet) bool {
+	return f.Breed == "" || strings.EqualFold(p.Breed, f.Breed)
+}
+
+func (f BreedFilter) Filter(petList []Pet) []Pet {
+	if f.Breed == "" {
+		return petList
 	}
-	return result
+	return filterPets(petList, f.Match)
 }
 
-func (f SpeciesFilter) Name() string { return "SpeciesFilter" }
+func (f BreedFilter) Name() string { return "BreedFilter" }
 
-type StatusFilter struct {
-	Status string
+// VaccinationFilter keeps pets whose vaccination status matches Vaccinated.
+type VaccinationFilter struct {
+	Vaccinated bool
 }
 
-func (f StatusFilter) Filter(petList []Pet) []Pet {
-	result := make([]Pet, 0)
-	for _, p := range petList {
-		if p.Status == f.Status {
-			result = append(result, p)
+func (f VaccinationFilter) Match(p Pet) bool { return p.IsVaccinated == f.Vaccinated }
+
+func (f VaccinationFilter) Filter(petList []Pet) []Pet { return filterPets(petList, f.Match) }
+
+func (f VaccinationFilter) Name() string { return "VaccinationFilter" }
+
+// TagFilter keeps pets carrying at least one of Tags, case-insensitively.
+// An empty Tags list matches everything.
+type TagFilter struct {
+	Tags []string
+}
+
+func (f TagFilter) Match(p Pet) bool {
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, tag := range p.Tags {
+		for _, want := range f.Tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
 		}
 	}
-	return result
+	return false
+}
+
+func (f TagFilter) Filter(petList []Pet) []Pet {
+	if len(f.Tags) == 0 {
+		return petList
+	}
+	return filterPets(petList, f.Match)
+}
+
+func (f TagFilter) Name() string { return "TagFilter" }
+
+type StatusFilter struct {
+	Status string
 }
 
+func (f StatusFilter) Match(p Pet) bool { return p.Status == f.Status }
+
+func (f StatusFilter) Filter(petList []Pet) []Pet { return filterPets(petList, f.Match) }
+
 func (f StatusFilter) Name() string { return "StatusFilter" }
 
 type AgeRangeFilter struct {
@@ -483,39 +1375,129 @@ type AgeRangeFilter struct {
 	Max int
 }
 
-func (f AgeRangeFilter) Filter(petList []Pet) []Pet {
-	result := make([]Pet, 0)
-	for _, p := range petList {
-		if (f.Min == 0 || p.Age >= f.Min) && (f.Max == 0 || p.Age <= f.Max) {
-			result = append(result, p)
-		}
-	}
-	return result
+func (f AgeRangeFilter) Match(p Pet) bool {
+	return (f.Min == 0 || p.Age >= f.Min) && (f.Max == 0 || p.Age <= f.Max)
 }
 
+func (f AgeRangeFilter) Filter(petList []Pet) []Pet { return filterPets(petList, f.Match) }
+
 func (f AgeRangeFilter) Name() string { return "AgeRangeFilter" }
 
-func ApplyFilters(petList []Pet, filters []Filterable) []Pet {
-	result := petList
-	for _, filter := range filters {
-		result = filter.Filter(result)
+// ageCategory buckets a pet into Baby, Young, Adult or Senior by age.
+func ageCategory(pet Pet) string {
+	switch {
+	case pet.Age < youngMinAge:
+		return "Baby"
+	case pet.Age < adultMinAge:
+		return "Young"
+	case pet.Age < seniorMinAge:
+		return "Adult"
+	default:
+		return "Senior"
 	}
-	return result
 }
 
-// 5. FUNCTIONS AND ERROR HANDLING
-func hashPassword(password string) string {
-	return fmt.Sprintf("hashed_%s_pawtnersalt", password)
+type AgeCategoryFilter struct {
+	Category string
 }
 
-func generateToken(userID string) string {
+func (f AgeCategoryFilter) Match(p Pet) bool { return strings.EqualFold(ageCategory(p), f.Category) }
+
+func (f AgeCategoryFilter) Filter(petList []Pet) []Pet { return filterPets(petList, f.Match) }
+
+func (f AgeCategoryFilter) Name() string { return "AgeCategoryFilter" }
+
+// ExcludeStatusFilter drops pets in any of the given statuses.
+type ExcludeStatusFilter struct {
+	Statuses []string
+}
+
+func (f ExcludeStatusFilter) Match(p Pet) bool {
+	for _, status := range f.Statuses {
+		if p.Status == status {
+			return false
+		}
+	}
+	return true
+}
+
+func (f ExcludeStatusFilter) Filter(petList []Pet) []Pet { return filterPets(petList, f.Match) }
+
+func (f ExcludeStatusFilter) Name() string { return "ExcludeStatusFilter" }
+
+func ApplyFilters(petList []Pet, filters []Filterable) []Pet {
+	result := petList
+	for _, filter := range filters {
+		result = filter.Filter(result)
+	}
+	return result
+}
+
+// matchesAll reports whether pet passes every filter.
+func matchesAll(pet Pet, filters []Filterable) bool {
+	for _, f := range filters {
+		if !f.Match(pet) {
+			return false
+		}
+	}
+	return true
+}
+
+// 5. FUNCTIONS AND ERROR HANDLING
+
+// legacyHashPrefix marks passwords stored by the old salted-format scheme.
+// They still verify, and are re-hashed with bcrypt on the next login.
+const legacyHashPrefix = "hashed_"
+
+// bcrypt only looks at the first 72 bytes, so longer passwords are rejected.
+const maxPasswordBytes = 72
+
+// hashPassword returns a bcrypt hash of password. It fails for passwords
+// longer than maxPasswordBytes.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// mustHashPassword is hashPassword for the built-in seed accounts.
+func mustHashPassword(password string) string {
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+	return hash
+}
+
+// checkPassword reports whether plain matches the stored hash, accepting both
+// bcrypt and legacy hashes.
+func checkPassword(hash, plain string) bool {
+	if strings.HasPrefix(hash, legacyHashPrefix) {
+		legacy := fmt.Sprintf("hashed_%s_pawtnersalt", plain)
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(legacy)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
+}
+
+func generateToken(userID string) string {
 	return fmt.Sprintf("tok_%s_%d", userID, time.Now().UnixNano())
 }
 
 func Register(email, username, password string) (*User, error) {
+	email = strings.TrimSpace(email)
 	if email == "" || username == "" || password == "" {
 		return nil, errors.New("email, username and password are required")
 	}
+	if !isValidEmail(email) {
+		return nil, ErrInvalidEmail
+	}
+	email = strings.ToLower(email)
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
 
 	mu.Lock()
 	defer mu.Unlock()
@@ -528,7 +1510,7 @@ func Register(email, username, password string) (*User, error) {
 		ID:        fmt.Sprintf("usr-%03d", len(users)+1),
 		Email:     email,
 		Username:  username,
-		Password:  hashPassword(password),
+		Password:  hash,
 		Role:      "user",
 		CreatedAt: time.Now(),
 		IsActive:  true,
@@ -538,27 +1520,175 @@ func Register(email, username, password string) (*User, error) {
 	return &users[len(users)-1], nil
 }
 
+func isValidRole(role string) bool {
+	for _, r := range validRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// setRole validates role and keeps IsAdmin in step with it.
+func setRole(user *User, role string) error {
+	if !isValidRole(role) {
+		return ErrInvalidRole
+	}
+	user.Role = role
+	user.IsAdmin = role == "admin"
+	return nil
+}
+
+func SetUserRole(userID, role string) (*User, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range users {
+		if users[i].ID == userID {
+			if err := setRole(&users[i], role); err != nil {
+				return nil, err
+			}
+			return &users[i], nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// AnonymizeUser handles an account-deletion request. The user record is kept
+// (so donations and inquiries still resolve by ID) but every personal detail
+// is scrubbed, including from bookings, contact messages and waitlists; the
+// account is deactivated and all its tokens are revoked. It returns the
+// donations and inquiries that were scrubbed so the caller can sync them.
+func AnonymizeUser(user *User, password string) ([]Donation, []AdoptionInquiry, error) {
+	// bcrypt is deliberately slow, so compare outside the write lock.
+	mu.RLock()
+	stored := user.Password
+	mu.RUnlock()
+	if !checkPassword(stored, password) {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if user.Password != stored {
+		return nil, nil, ErrInvalidCredentials // changed while we were comparing
+	}
+
+	oldEmail := user.Email
+	anonEmail := fmt.Sprintf("deleted-%s@anonymized.invalid", user.ID)
+	anonName := "Deleted User"
+
+	var scrubbedDonations []Donation
+	for i := range donations {
+		d := &donations[i]
+		if d.UserID == user.ID || strings.EqualFold(d.DonorEmail, oldEmail) {
+			d.UserID = user.ID
+			d.DonorName = anonName
+			d.DonorEmail = anonEmail
+			d.DonorPhone = ""
+			scrubbedDonations = append(scrubbedDonations, *d)
+		}
+	}
+	var scrubbedInquiries []AdoptionInquiry
+	for i := range inquiries {
+		inq := &inquiries[i]
+		if strings.EqualFold(inq.Email, oldEmail) {
+			inq.AdopterName = anonName
+			inq.Email = anonEmail
+			inq.Phone = ""
+			scrubbedInquiries = append(scrubbedInquiries, *inq)
+		}
+	}
+	for i := range bookings {
+		b := &bookings[i]
+		if strings.EqualFold(b.Email, oldEmail) {
+			b.OwnerName = anonName
+			b.Email = anonEmail
+			b.Phone = ""
+		}
+	}
+	for i := range contactMessages {
+		msg := &contactMessages[i]
+		if strings.EqualFold(msg.Email, oldEmail) {
+			msg.Name = anonName
+			msg.Email = anonEmail
+		}
+	}
+	for petID, emails := range waitlistByPet {
+		emails = slices.DeleteFunc(emails, func(e string) bool { return strings.EqualFold(e, oldEmail) })
+		if len(emails) == 0 {
+			delete(waitlistByPet, petID)
+		} else {
+			waitlistByPet[petID] = emails
+		}
+	}
+
+	for tok, t := range tokenStore {
+		if t.UserID == user.ID {
+			delete(tokenStore, tok)
+		}
+	}
+
+	delete(usersByEmail, oldEmail)
+	user.Email = anonEmail
+	user.Username = anonName
+	user.Password = ""
+	user.IsActive = false
+	user.Favorites = nil
+	usersByEmail[anonEmail] = user
+
+	return scrubbedDonations, scrubbedInquiries, nil
+}
+
 func Login(email, password string) (*AuthToken, error) {
 	if email == "" || password == "" {
 		return nil, ErrInvalidCredentials
 	}
 
+	// bcrypt is deliberately slow, so compare outside the write lock.
+	mu.RLock()
+	user, exists := usersByEmail[email]
+	var stored string
+	if exists {
+		stored = user.Password
+	}
+	mu.RUnlock()
+	if !exists || !checkPassword(stored, password) {
+		return nil, ErrInvalidCredentials
+	}
+	var rehashed string
+	if strings.HasPrefix(stored, legacyHashPrefix) {
+		// A failed upgrade shouldn't block the login; it's retried next time.
+		var err error
+		if rehashed, err = hashPassword(password); err != nil {
+			log.Printf("[ERROR] Could not upgrade legacy password hash: %v", err)
+		}
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
 
-	user, exists := usersByEmail[email]
-	if !exists || user.Password != hashPassword(password) {
-		return nil, ErrInvalidCredentials
+	user, exists = usersByEmail[email]
+	if !exists || user.Password != stored {
+		return nil, ErrInvalidCredentials // changed while we were comparing
+	}
+	if rehashed != "" {
+		user.Password = rehashed
+		syncUserToDB(*user)
+		log.Printf("[INFO] Migrated password hash to bcrypt for %s", user.ID)
 	}
 
+	now := time.Now()
 	token := AuthToken{
-		Token:     generateToken(user.ID),
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-		Role:      user.Role,
-		IsAdmin:   user.IsAdmin,
-		Username:  user.Username,
-		Email:     user.Email,
+		Token:      generateToken(user.ID),
+		UserID:     user.ID,
+		ExpiresAt:  now.Add(tokenLifetime),
+		LastUsedAt: now,
+		Role:       user.Role,
+		IsAdmin:    user.IsAdmin,
+		Username:   user.Username,
+		Email:      user.Email,
 	}
 	tokenStore[token.Token] = &token
 	return &token, nil
@@ -577,10 +1707,16 @@ func ValidateToken(tokenStr string) (*User, error) {
 		return nil, ErrInvalidCredentials
 	}
 
-	if time.Now().After(token.ExpiresAt) {
+	now := time.Now()
+	if now.After(token.ExpiresAt) {
 		delete(tokenStore, tokenStr)
 		return nil, ErrTokenExpired
 	}
+	if tokenIdleTimeout > 0 && now.Sub(token.LastUsedAt) > tokenIdleTimeout {
+		delete(tokenStore, tokenStr)
+		return nil, ErrSessionIdle
+	}
+	token.LastUsedAt = now
 
 	for i := range users {
 		if users[i].ID == token.UserID {
@@ -590,7 +1726,98 @@ func ValidateToken(tokenStr string) (*User, error) {
 	return nil, ErrInvalidCredentials
 }
 
-func UpdatePet(id string, update Pet) (*Pet, error) {
+// RefreshToken swaps a still-valid token for a new one with a fresh
+// lifetime. The old token stops working immediately.
+func RefreshToken(tokenStr string) (*AuthToken, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	old, exists := tokenStore[tokenStr]
+	if !exists {
+		return nil, ErrInvalidCredentials
+	}
+	now := time.Now()
+	if now.After(old.ExpiresAt) {
+		delete(tokenStore, tokenStr)
+		return nil, ErrTokenExpired
+	}
+	if tokenIdleTimeout > 0 && now.Sub(old.LastUsedAt) > tokenIdleTimeout {
+		delete(tokenStore, tokenStr)
+		return nil, ErrSessionIdle
+	}
+	if tokenRefreshWindow > 0 && old.ExpiresAt.Sub(now) > tokenRefreshWindow {
+		return nil, ErrRefreshTooEarly
+	}
+
+	token := AuthToken{
+		Token:      generateToken(old.UserID),
+		UserID:     old.UserID,
+		ExpiresAt:  now.Add(tokenLifetime),
+		LastUsedAt: now,
+		Role:       old.Role,
+		IsAdmin:    old.IsAdmin,
+		Username:   old.Username,
+		Email:      old.Email,
+	}
+	delete(tokenStore, tokenStr)
+	tokenStore[token.Token] = &token
+	return &token, nil
+}
+
+// cleanupExpiredTokens removes tokens that can no longer validate, either
+// expired or idle too long, and returns how many were removed.
+func cleanupExpiredTokens() int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for tok, t := range tokenStore {
+		if now.After(t.ExpiresAt) || (tokenIdleTimeout > 0 && now.Sub(t.LastUsedAt) > tokenIdleTimeout) {
+			delete(tokenStore, tok)
+			removed++
+		}
+	}
+	return removed
+}
+
+// tokenCleanupWorker sweeps tokenStore every interval until ctx is cancelled.
+func tokenCleanupWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := cleanupExpiredTokens(); n > 0 {
+				log.Printf("[INFO] Removed %d expired tokens", n)
+			}
+		}
+	}
+}
+
+// clearablePetFields are the JSON names UpdatePet can reset to empty. Name,
+// species and status are always required, and careReason follows status.
+var clearablePetFields = []string{"breed", "age", "gender", "description", "adoptionFee", "isVaccinated", "tags", "attributes", "images", "medical"}
+
+// UpdatePet overwrites the fields that are set in update; zero values are
+// ignored. Fields named in clearFields are reset to empty afterwards, which is the
+// only way to blank a description or set isVaccinated back to false.
+func UpdatePet(id string, update Pet, clearFields ...string) (*Pet, error) {
+	for _, field := range clearFields {
+		if !slices.Contains(clearablePetFields, field) {
+			return nil, fmt.Errorf("%q can't be cleared; clearable fields are %s", field, strings.Join(clearablePetFields, ", "))
+		}
+	}
+
+	// Deferred ahead of the unlock so it runs after it.
+	var adoptedPet *Pet
+	defer func() {
+		if adoptedPet != nil {
+			publishEvent(EventPetAdopted, adoptedPet.ID, *adoptedPet)
+		}
+	}()
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -598,6 +1825,28 @@ func UpdatePet(id string, update Pet) (*Pet, error) {
 	if !exists {
 		return nil, ErrPetNotFound
 	}
+	if update.AdoptionFee < 0 {
+		return nil, errors.New("adoption fee cannot be negative")
+	}
+	adopted := false
+
+	// Leaving "Under Care" clears the reason; entering or staying needs one.
+	if update.Status != "" || update.CareReason != "" {
+		newStatus, newReason := pet.Status, pet.CareReason
+		if update.Status != "" {
+			newStatus = update.Status
+			if newStatus != "Under Care" {
+				newReason = ""
+			}
+		}
+		if update.CareReason != "" {
+			newReason = update.CareReason
+		}
+		if err := validateCareReason(newStatus, newReason); err != nil {
+			return nil, err
+		}
+		pet.CareReason = newReason
+	}
 
 	if update.Name != "" {
 		pet.Name = update.Name
@@ -611,1141 +1860,6355 @@ func UpdatePet(id string, update Pet) (*Pet, error) {
 	if update.Age > 0 {
 		pet.Age = update.Age
 	}
+	if update.Gender != "" {
+		pet.Gender = update.Gender
+	}
+	if update.IsVaccinated {
+		pet.IsVaccinated = true
+	}
+	if update.Tags != nil {
+		pet.Tags = update.Tags
+	}
+	if update.Attributes != nil {
+		pet.Attributes = update.Attributes
+	}
+	if update.Medical != nil {
+		medical := *update.Medical
+		pet.Medical = &medical
+	}
 	if update.Status != "" {
 		oldStatus := pet.Status
 		pet.Status = update.Status
 		statusCounts[oldStatus]--
 		statusCounts[update.Status]++
+
+		// Pet is back up for adoption — let everyone on the waitlist know.
+		if oldStatus != "Available" && update.Status == "Available" {
+			notifyWaitlist(*pet, waitlistByPet[id])
+			delete(waitlistByPet, id)
+		}
+		adopted = oldStatus != "Adopted" && update.Status == "Adopted"
 	}
 	if update.Description != "" {
 		pet.Description = update.Description
 	}
+	if update.Images != nil {
+		pet.Images = update.Images
+		pet.BrokenImages = nil // rechecked by schedulePhotoCheck
+	}
+	if update.AdoptionFee > 0 {
+		pet.AdoptionFee = update.AdoptionFee
+	}
+	for _, field := range clearFields {
+		switch field {
+		case "breed":
+			pet.Breed = ""
+		case "age":
+			pet.Age = 0
+		case "gender":
+			pet.Gender = ""
+		case "description":
+			pet.Description = ""
+		case "adoptionFee":
+			pet.AdoptionFee = 0
+		case "isVaccinated":
+			pet.IsVaccinated = false
+		case "tags":
+			pet.Tags = nil
+		case "attributes":
+			pet.Attributes = nil
+		case "images":
+			pet.Images = nil
+			pet.BrokenImages = nil
+		case "medical":
+			pet.Medical = nil
+		}
+	}
+	if adopted {
+		p := *pet
+		adoptedPet = &p
+	}
 	return pet, nil
 }
 
-func DeletePet(id string) error {
+// normalizeServiceCategory returns the canonical spelling of category, or
+// false if it isn't one of serviceCategories.
+func normalizeServiceCategory(category string) (string, bool) {
+	category = strings.TrimSpace(category)
+	for _, c := range serviceCategories {
+		if strings.EqualFold(c, category) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// validateService checks a new service the way validatePet checks a pet,
+// collecting every problem rather than stopping at the first.
+func validateService(service Service) (bool, []string) {
+	errs := make([]string, 0)
+
+	if strings.TrimSpace(service.Name) == "" {
+		errs = append(errs, "Service name is required")
+	}
+
+	if strings.TrimSpace(service.Category) == "" {
+		errs = append(errs, "Category is required")
+	} else if _, ok := normalizeServiceCategory(service.Category); !ok {
+		errs = append(errs, fmt.Sprintf("Category must be one of: %s", strings.Join(serviceCategories, ", ")))
+	}
+
+	if service.Price < 0 {
+		errs = append(errs, "Price cannot be negative")
+	}
+
+	if service.Duration < 0 {
+		errs = append(errs, "Duration cannot be negative")
+	}
+
+	return len(errs) == 0, errs
+}
+
+func AddService(service Service) (*Service, error) {
+	if valid, errs := validateService(service); !valid {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	service.Category, _ = normalizeServiceCategory(service.Category)
+
 	mu.Lock()
 	defer mu.Unlock()
 
-	pet, exists := petsByID[id]
+	serviceSeq++
+	service.ID = fmt.Sprintf("svc-%03d", serviceSeq)
+	services = append(services, service)
+	reindexServices()
+	serviceStats[service.ID] = map[string]interface{}{
+		"bookings":    0,
+		"revenue":     0.0,
+		"rating":      0.0,
+		"ratingCount": 0,
+		"available":   service.Available,
+	}
+	return servicesByID[service.ID], nil
+}
+
+func UpdateService(id string, update Service) (*Service, error) {
+	if update.Category != "" {
+		category, ok := normalizeServiceCategory(update.Category)
+		if !ok {
+			return nil, ErrInvalidCategory
+		}
+		update.Category = category
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	service, exists := servicesByID[id]
 	if !exists {
-		return ErrPetNotFound
+		return nil, ErrServiceNotFound
 	}
 
-	statusCounts[pet.Status]--
-	delete(petsByID, id)
+	if update.Name != "" {
+		service.Name = update.Name
+	}
+	if update.Category != "" {
+		service.Category = update.Category
+	}
+	if update.Description != "" {
+		service.Description = update.Description
+	}
+	if update.Price > 0 {
+		service.Price = update.Price
+	}
+	if update.Duration > 0 {
+		service.Duration = update.Duration
+	}
+	if update.Features != nil {
+		service.Features = update.Features
+	}
+	return service, nil
+}
 
-	for i, p := range pets {
-		if p.ID == id {
-			pets = append(pets[:i], pets[i+1:]...)
-			break
+// DeleteService removes a service and its stats. Past bookings keep their
+// ServiceID; a service still in an active package can't be deleted.
+func DeleteService(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := servicesByID[id]; !exists {
+		return ErrServiceNotFound
+	}
+	for _, pkg := range packages {
+		if pkg.Active && slices.Contains(pkg.ServiceIDs, id) {
+			return fmt.Errorf("%w: %s", ErrServiceInUse, pkg.ID)
 		}
 	}
+
+	services = slices.DeleteFunc(services, func(s Service) bool { return s.ID == id })
+	delete(servicesByID, id)
+	delete(serviceStats, id)
+	reindexServices()
 	return nil
 }
 
-func ProcessDonation(donation *Donation) (*Receipt, error) {
-	if donation.Amount <= 0 {
-		return nil, ErrInvalidPayment
+// reindexServices re-points servicesByID at the current services slice.
+// Callers must hold mu.
+func reindexServices() {
+	for i := range services {
+		servicesByID[services[i].ID] = &services[i]
 	}
-	if donation.DonorName == "" || donation.DonorEmail == "" {
-		return nil, errors.New("donor name and email are required")
+}
+
+// RateService folds a 1-5 rating into the service's running average.
+// bookingID is optional; when given it must be a completed booking of this
+// service that hasn't been rated yet, so each booking counts once.
+func RateService(serviceID string, rating int, bookingID string) (*ServiceDetail, error) {
+	if rating < 1 || rating > 5 {
+		return nil, ErrInvalidRating
 	}
-	if donation.PaymentMethod == "" {
-		return nil, errors.New("payment method is required")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	service, exists := servicesByID[serviceID]
+	if !exists {
+		return nil, ErrServiceNotFound
+	}
+	if bookingID != "" {
+		booking, exists := bookingsByID[bookingID]
+		switch {
+		case !exists || booking.ServiceID != serviceID:
+			return nil, ErrBookingNotFound
+		case booking.Status != "Completed":
+			return nil, ErrBookingNotCompleted
+		case booking.Rated:
+			return nil, ErrBookingRated
+		}
+		booking.Rated = true
 	}
 
-	donation.ID = fmt.Sprintf("don-%03d", len(donations)+1)
-	donation.TransactionID = fmt.Sprintf("txn-%d", time.Now().UnixNano())
-	donation.Status = "Completed"
-	donation.CreatedAt = time.Now()
+	stats := serviceStats[serviceID]
+	count, _ := stats["ratingCount"].(int)
+	avg, _ := stats["rating"].(float64)
+	avg = (avg*float64(count) + float64(rating)) / float64(count+1)
+	stats["rating"] = avg
+	stats["ratingCount"] = count + 1
 
-	mu.Lock()
-	donations = append(donations, *donation)
-	mu.Unlock()
+	return &ServiceDetail{Service: *service, Rating: avg, RatingCount: count + 1}, nil
+}
 
-	syncDonationToDB(*donation)
-	receipt := GenerateReceipt(*donation)
-	return &receipt, nil
+// serviceDetail returns the service with its current rating.
+func serviceDetail(id string) (*ServiceDetail, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	service, exists := servicesByID[id]
+	if !exists {
+		return nil, ErrServiceNotFound
+	}
+	detail := &ServiceDetail{Service: *service}
+	detail.Rating, _ = serviceStats[id]["rating"].(float64)
+	detail.RatingCount, _ = serviceStats[id]["ratingCount"].(int)
+	return detail, nil
 }
 
-func GenerateReceipt(donation Donation) Receipt {
-	return Receipt{
-		ReceiptID:  fmt.Sprintf("rcpt-%d", time.Now().UnixNano()),
-		DonationID: donation.ID,
-		DonorName:  donation.DonorName,
-		Amount:     donation.Amount,
-		IssuedAt:   time.Now(),
-		Message:    fmt.Sprintf("Thank you %s for your generous donation of ₹%.2f to Pawtner Hope Foundation!", donation.DonorName, donation.Amount),
+// AddPackage validates that every member service exists and stores the
+// package. When no price is given the suggested price is used.
+func AddPackage(pkg ServicePackage) (*ServicePackage, error) {
+	if pkg.Name == "" {
+		return nil, errors.New("package name is required")
+	}
+	if len(pkg.ServiceIDs) < 2 {
+		return nil, errors.New("a package must contain at least two services")
+	}
+	if pkg.Price < 0 {
+		return nil, errors.New("package price cannot be negative")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	total := 0.0
+	for _, id := range pkg.ServiceIDs {
+		service, exists := servicesByID[id]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, id)
+		}
+		total += service.Price
+	}
+	pkg.SuggestedPrice = math.Round(total*(1-packageDiscount)*100) / 100
+	if pkg.Price == 0 {
+		pkg.Price = pkg.SuggestedPrice
 	}
+
+	packageSeq++
+	pkg.ID = fmt.Sprintf("pkg-%03d", packageSeq)
+	packages = append(packages, pkg)
+	return &pkg, nil
 }
 
-// ── Email templates ───────────────────────────────────────────────────────────
+// BookPackage creates one child booking per service in the package, each
+// sharing the owner and schedule details from booking.
+func BookPackage(packageID string, booking ServiceBooking) ([]ServiceBooking, error) {
+	mu.Lock()
+	defer mu.Unlock()
 
-const welcomeEmailTpl = `<!DOCTYPE html>
-<html lang="en">
-<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Welcome</title></head>
-<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
-  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
-    <tr><td align="center">
-      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
-        <!-- Header -->
-        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:40px 48px;text-align:center;">
-          <div style="font-size:36px;margin-bottom:8px;">🐾</div>
-          <h1 style="margin:0;color:#fff;font-size:26px;font-weight:700;letter-spacing:-0.5px;">Pawtner Hope Foundation</h1>
-          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Spreading love, one paw at a time</p>
-        </td></tr>
-        <!-- Body -->
-        <tr><td style="padding:40px 48px;">
-          <h2 style="margin:0 0 16px;color:#2c2416;font-size:22px;">Welcome, {{.Username}}! 👋</h2>
-          <p style="margin:0 0 16px;color:#555;font-size:15px;line-height:1.7;">Your account has been created successfully. We're so glad to have you as part of our community of animal lovers.</p>
-          <p style="margin:0 0 24px;color:#555;font-size:15px;line-height:1.7;">Here's what you can do now:</p>
-          <table width="100%" cellpadding="0" cellspacing="0" style="margin-bottom:28px;">
-            <tr><td style="padding:12px 16px;background:#fdf6ef;border-left:3px solid #d4a574;border-radius:4px;margin-bottom:10px;">
-              <span style="color:#b8844f;font-weight:600;">🐶 Adopt a Pet</span>
-              <span style="color:#666;font-size:14px;"> — Browse our animals and submit an adoption inquiry.</span>
-            </td></tr>
-            <tr><td style="height:8px;"></td></tr>
-            <tr><td style="padding:12px 16px;background:#fdf6ef;border-left:3px solid #d4a574;border-radius:4px;">
-              <span style="color:#b8844f;font-weight:600;">💛 Donate</span>
-              <span style="color:#666;font-size:14px;"> — Help us rescue and care for more animals.</span>
-            </td></tr>
-          </table>
-          <p style="margin:0 0 4px;color:#888;font-size:13px;">Account details</p>
-          <table width="100%" cellpadding="0" cellspacing="0" style="border:1px solid #eee;border-radius:8px;overflow:hidden;">
-            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;width:120px;">Email</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Email}}</td></tr>
-            <tr><td style="padding:10px 16px;color:#888;font-size:13px;">Username</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Username}}</td></tr>
-            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;">Member since</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Date}}</td></tr>
-          </table>
-        </td></tr>
-        <!-- CTA -->
-        <tr><td style="padding:0 48px 40px;text-align:center;">
-          <a href="http://localhost:8080/adoption.html" style="display:inline-block;background:#d4a574;color:#fff;text-decoration:none;padding:14px 36px;border-radius:50px;font-size:15px;font-weight:600;">Browse Pets for Adoption →</a>
-        </td></tr>
-        <!-- Footer -->
-        <tr><td style="background:#f5f0eb;padding:24px 48px;text-align:center;">
-          <p style="margin:0;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation. If you didn't create this account, please ignore this email.</p>
-        </td></tr>
-      </table>
-    </td></tr>
-  </table>
-</body></html>`
-
-const receiptEmailTpl = `<!DOCTYPE html>
-<html lang="en">
-<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Donation Receipt</title></head>
-<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
-  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
-    <tr><td align="center">
-      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
-        <!-- Header -->
-        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:40px 48px;text-align:center;">
-          <div style="font-size:36px;margin-bottom:8px;">🐾</div>
-          <h1 style="margin:0;color:#fff;font-size:26px;font-weight:700;">Donation Receipt</h1>
-          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Pawtner Hope Foundation</p>
-        </td></tr>
-        <!-- Amount hero -->
-        <tr><td style="padding:36px 48px 24px;text-align:center;border-bottom:1px solid #f0ebe4;">
-          <p style="margin:0 0 4px;color:#999;font-size:13px;text-transform:uppercase;letter-spacing:.8px;">Amount Received</p>
-          <p style="margin:0;color:#b8844f;font-size:48px;font-weight:700;">₹{{.Amount}}</p>
-        </td></tr>
-        <!-- Details -->
-        <tr><td style="padding:28px 48px;">
-          <p style="margin:0 0 16px;color:#2c2416;font-size:16px;font-weight:600;">Thank you, {{.DonorName}}! 💛</p>
-          <p style="margin:0 0 24px;color:#555;font-size:15px;line-height:1.7;">Your generous donation helps us rescue, care for, and re-home abandoned pets. Every rupee makes a real difference in an animal's life.</p>
-          <table width="100%" cellpadding="0" cellspacing="0" style="border:1px solid #eee;border-radius:10px;overflow:hidden;margin-bottom:24px;">
-            <tr style="background:#f9f9f9;"><td style="padding:12px 16px;color:#888;font-size:13px;width:150px;">Receipt No.</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.ReceiptID}}</td></tr>
-            <tr><td style="padding:12px 16px;color:#888;font-size:13px;">Donation ID</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.DonationID}}</td></tr>
-            <tr style="background:#f9f9f9;"><td style="padding:12px 16px;color:#888;font-size:13px;">UPI Txn / UTR</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.TransactionID}}</td></tr>
-            <tr><td style="padding:12px 16px;color:#888;font-size:13px;">Date</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;">{{.Date}}</td></tr>
-            <tr style="background:#f9f9f9;"><td style="padding:12px 16px;color:#888;font-size:13px;">Donor Email</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;">{{.DonorEmail}}</td></tr>
-          </table>
-          <div style="background:#fdf6ef;border-radius:10px;padding:16px 20px;">
-            <p style="margin:0;color:#b8844f;font-size:13px;">🔒 This is an official receipt for your tax records. Please save this email.</p>
-          </div>
-        </td></tr>
-        <!-- CTA -->
-        <tr><td style="padding:0 48px 40px;text-align:center;">
-          <a href="http://localhost:8080/donate.html" style="display:inline-block;background:#d4a574;color:#fff;text-decoration:none;padding:14px 36px;border-radius:50px;font-size:15px;font-weight:600;">Donate Again →</a>
-        </td></tr>
-        <!-- Footer -->
-        <tr><td style="background:#f5f0eb;padding:24px 48px;text-align:center;">
-          <p style="margin:0 0 6px;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation</p>
-          <p style="margin:0;color:#bbb;font-size:12px;">Questions? Email us at pawtnerhopefoundation@gmail.com</p>
-        </td></tr>
-      </table>
-    </td></tr>
-  </table>
-</body></html>`
-
-// renderTemplate renders an HTML template string with the given data.
-func renderTemplate(tplStr string, data interface{}) (string, error) {
-	tpl, err := template.New("").Parse(tplStr)
-	if err != nil {
-		return "", err
+	var pkg *ServicePackage
+	for i := range packages {
+		if packages[i].ID == packageID {
+			pkg = &packages[i]
+			break
+		}
 	}
-	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, data); err != nil {
-		return "", err
+	if pkg == nil || !pkg.Active {
+		return nil, ErrPackageNotFound
+	}
+	for _, serviceID := range pkg.ServiceIDs {
+		if hasBookingConflict(serviceID, booking.Date, booking.Time) {
+			return nil, ErrBookingConflict
+		}
 	}
-	return buf.String(), nil
-}
 
-// SendEmail sends an HTML email via Gmail SMTP.
-func SendEmail(to, subject, htmlBody string) error {
-	if to == "" || subject == "" {
-		return ErrEmailFailed
+	created := make([]ServiceBooking, 0, len(pkg.ServiceIDs))
+	for _, serviceID := range pkg.ServiceIDs {
+		child := booking
+		child.ID = fmt.Sprintf("book-%03d", len(bookings)+1)
+		child.ServiceID = serviceID
+		child.PackageID = pkg.ID
+		child.Status = "Pending"
+		child.BookedAt = time.Now()
+
+		bookings = append(bookings, child)
+		reindexBookings()
+		if stats, exists := serviceStats[serviceID]; exists {
+			stats["bookings"] = stats["bookings"].(int) + 1
+		}
+		created = append(created, child)
 	}
-	if emailShouldFail {
-		return ErrEmailFailed
+	return created, nil
+}
+
+// hasBookingConflict reports whether a live (not cancelled) booking already
+// holds the service at exactly this date and time. Bookings without a date
+// and time never clash. Callers must hold mu.
+func hasBookingConflict(serviceID, date, slot string) bool {
+	if date == "" || slot == "" {
+		return false
 	}
-	if smtpUser == "" || smtpPass == "" {
-		log.Printf("[EMAIL-SKIP] SMTP not configured. To: %s | Subject: %s", to, subject)
-		return nil
+	for _, b := range bookings {
+		if b.ServiceID == serviceID && b.Date == date && b.Time == slot && b.Status != "Cancelled" {
+			return true
+		}
 	}
+	return false
+}
 
-	header := fmt.Sprintf(
-		"From: Pawtner Hope Foundation <%s>\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n",
-		smtpUser, to, subject,
-	)
-	message := []byte(header + htmlBody)
+func DeletePet(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
 
-	auth := smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
-	addr := smtpHost + ":" + smtpPort
-	if err := smtp.SendMail(addr, auth, smtpUser, []string{to}, message); err != nil {
-		log.Printf("[EMAIL-ERROR] To: %s | %v", to, err)
-		return fmt.Errorf("%w: %v", ErrEmailFailed, err)
+	if !removePetLocked(id) {
+		return ErrPetNotFound
 	}
-	log.Printf("[EMAIL-SENT] To: %s | Subject: %s", to, subject)
+	reindexPets()
 	return nil
 }
 
-func SendEmailWithRetry(to, subject, body string, maxRetries int) error {
-	var lastErr error
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if err := SendEmail(to, subject, body); err != nil {
-			lastErr = err
-			log.Printf("[EMAIL] Attempt %d/%d failed for %s: %v", attempt, maxRetries, to, err)
-			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
-			continue
+// removePetLocked drops a pet from pets and every index keyed on it. Removing
+// shifts later elements, so callers must reindexPets once they're done.
+// Callers must hold mu.
+func removePetLocked(id string) bool {
+	pet, exists := petsByID[id]
+	if !exists {
+		return false
+	}
+
+	statusCounts[pet.Status]--
+	ids := petsByBreed[pet.Breed]
+	for i, petID := range ids {
+		if petID == id {
+			petsByBreed[pet.Breed] = append(ids[:i], ids[i+1:]...)
+			break
 		}
-		return nil
 	}
-	return fmt.Errorf("email failed after %d attempts: %w", maxRetries, lastErr)
+	delete(petsByID, id)
+	delete(waitlistByPet, id)
+
+	for i, p := range pets {
+		if p.ID == id {
+			pets = append(pets[:i], pets[i+1:]...)
+			break
+		}
+	}
+	return true
 }
 
-// sendWelcomeEmail renders and dispatches the welcome email.
-func sendWelcomeEmail(user *User) {
-	html, err := renderTemplate(welcomeEmailTpl, map[string]string{
-		"Username": user.Username,
-		"Email":    user.Email,
-		"Date":     user.CreatedAt.Format("2 Jan 2006"),
-	})
-	if err != nil {
-		log.Printf("[EMAIL] Failed to render welcome template: %v", err)
-		return
+// addPetLocked assigns a validated pet its ID and stores it. Callers must hold mu.
+func addPetLocked(pet Pet) Pet {
+	petSeq++
+	syncPetSeqToDB(petSeq)
+	pet.ID = fmt.Sprintf("pet-%03d", petSeq)
+	pet.CreatedAt = time.Now()
+	pet.BrokenImages = nil
+	pets = append(pets, pet)
+	reindexPets()
+	statusCounts[pet.Status]++
+	petsByBreed[pet.Breed] = append(petsByBreed[pet.Breed], pet.ID)
+	return pet
+}
+
+// reindexPets re-points petsByID at the current pets slice. Callers must hold mu.
+func reindexPets() {
+	for i := range pets {
+		petsByID[pets[i].ID] = &pets[i]
 	}
-	go SendEmailWithRetry(user.Email, "Welcome to Pawtner Hope Foundation 🐾", html, 3)
 }
 
-// sendDonationReceipt renders and dispatches the donation receipt email.
-func sendDonationReceipt(donation Donation, receipt Receipt) {
-	html, err := renderTemplate(receiptEmailTpl, map[string]string{
-		"DonorName":     donation.DonorName,
-		"DonorEmail":    donation.DonorEmail,
-		"Amount":        fmt.Sprintf("%.2f", donation.Amount),
-		"ReceiptID":     receipt.ReceiptID,
-		"DonationID":    donation.ID,
-		"TransactionID": donation.TransactionID,
-		"Date":          donation.CreatedAt.Format("2 Jan 2006, 3:04 PM"),
-	})
-	if err != nil {
-		log.Printf("[EMAIL] Failed to render receipt template: %v", err)
-		return
+// reindexBookings re-points bookingsByID at the current bookings slice, which
+// append may have moved. Callers must hold mu.
+func reindexBookings() {
+	for i := range bookings {
+		bookingsByID[bookings[i].ID] = &bookings[i]
 	}
-	go SendEmailWithRetry(donation.DonorEmail, "Donation Receipt — Pawtner Hope Foundation 🐾", html, 3)
 }
 
-// ── MongoDB helpers ───────────────────────────────────────────────────────────
+// GetBooking returns a booking joined with its service.
+func GetBooking(id string) (*BookingDetail, error) {
+	mu.RLock()
+	defer mu.RUnlock()
 
-func petsColl() *mongo.Collection {
-	if mongoDB == nil {
-		return nil
+	booking, exists := bookingsByID[id]
+	if !exists {
+		return nil, ErrBookingNotFound
 	}
-	return mongoDB.Collection("pets")
-}
-func usersColl() *mongo.Collection {
-	if mongoDB == nil {
-		return nil
+	detail := &BookingDetail{ServiceBooking: *booking}
+	if service, exists := servicesByID[booking.ServiceID]; exists {
+		svc := *service
+		detail.Service = &svc
 	}
-	return mongoDB.Collection("users")
+	return detail, nil
 }
-func donationsColl() *mongo.Collection {
-	if mongoDB == nil {
-		return nil
+
+// CompleteBooking marks a booking as carried out, which makes it
+// rateable. Cancelled bookings can't be completed.
+func CompleteBooking(id string) (*ServiceBooking, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	booking, exists := bookingsByID[id]
+	if !exists {
+		return nil, ErrBookingNotFound
 	}
-	return mongoDB.Collection("donations")
-}
-func inquiriesColl() *mongo.Collection {
-	if mongoDB == nil {
-		return nil
+	switch booking.Status {
+	case "Cancelled":
+		return nil, ErrBookingCancelled
+	case "Completed":
+		return nil, ErrBookingCompleted
 	}
-	return mongoDB.Collection("inquiries")
+
+	booking.Status = "Completed"
+	completed := *booking
+	return &completed, nil
 }
 
-func syncPetToDB(pet Pet) {
-	if petsColl() == nil {
-		return
+// CancelBooking soft-deletes a booking by marking it Cancelled, and takes it
+// off its service's booking count. Completed bookings can't be cancelled.
+func CancelBooking(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	booking, exists := bookingsByID[id]
+	if !exists {
+		return ErrBookingNotFound
+	}
+	switch booking.Status {
+	case "Cancelled":
+		return ErrBookingCancelled
+	case "Completed":
+		return ErrBookingCompleted
 	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		opts := options.Replace().SetUpsert(true)
-		if _, err := petsColl().ReplaceOne(ctx, bson.M{"id": pet.ID}, pet, opts); err != nil {
-			log.Printf("[MONGO] syncPetToDB error: %v", err)
-		}
-	}()
-}
 
-func deletePetFromDB(petID string) {
-	if petsColl() == nil {
-		return
+	booking.Status = "Cancelled"
+	if stats, exists := serviceStats[booking.ServiceID]; exists {
+		stats["bookings"] = stats["bookings"].(int) - 1
 	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if _, err := petsColl().DeleteOne(ctx, bson.M{"id": petID}); err != nil {
-			log.Printf("[MONGO] deletePetFromDB error: %v", err)
-		}
-	}()
+	return nil
 }
 
-func syncUserToDB(user User) {
-	if usersColl() == nil {
-		return
-	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		opts := options.Replace().SetUpsert(true)
-		if _, err := usersColl().ReplaceOne(ctx, bson.M{"id": user.ID}, user, opts); err != nil {
-			log.Printf("[MONGO] syncUserToDB error: %v", err)
+// Limits for public testimonial submissions, in characters.
+const (
+	maxTestimonialAuthor = 100
+	maxTestimonialText   = 1000
+)
+
+// sanitizeText strips control characters from user-supplied text, trims it
+// and cuts it to at most maxLen characters. Markup is left alone here and
+// escaped on output instead; see Testimonial.MarshalJSON.
+func sanitizeText(text string, maxLen int) string {
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
 		}
-	}()
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > maxLen {
+		text = strings.TrimSpace(string(runes[:maxLen]))
+	}
+	return text
 }
 
-func syncDonationToDB(donation Donation) {
-	if donationsColl() == nil {
-		return
+// SubmitTestimonial stores a sanitized, unapproved testimonial.
+func SubmitTestimonial(t Testimonial) (*Testimonial, error) {
+	t.Author = sanitizeText(t.Author, maxTestimonialAuthor)
+	t.Text = sanitizeText(t.Text, maxTestimonialText)
+	t.PetName = sanitizeText(t.PetName, maxTestimonialAuthor)
+	if t.Author == "" || t.Text == "" {
+		return nil, errors.New("author and text are required")
 	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		opts := options.Replace().SetUpsert(true)
-		if _, err := donationsColl().ReplaceOne(ctx, bson.M{"id": donation.ID}, donation, opts); err != nil {
-			log.Printf("[MONGO] syncDonationToDB error: %v", err)
-		}
-	}()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	t.ID = fmt.Sprintf("tst-%03d", len(testimonials)+1)
+	t.CreatedAt = time.Now()
+	t.Approved = false
+	t.ApprovedAt = nil
+	testimonials = append(testimonials, t)
+	syncTestimonialToDB(t)
+	return &t, nil
 }
 
-func syncInquiryToDB(inquiry AdoptionInquiry) {
-	if inquiriesColl() == nil {
-		return
-	}
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		opts := options.Replace().SetUpsert(true)
-		if _, err := inquiriesColl().ReplaceOne(ctx, bson.M{"id": inquiry.ID}, inquiry, opts); err != nil {
-			log.Printf("[MONGO] syncInquiryToDB error: %v", err)
+// ApproveTestimonial publishes a testimonial. Approving twice is harmless.
+func ApproveTestimonial(id string) (*Testimonial, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range testimonials {
+		if testimonials[i].ID != id {
+			continue
 		}
-	}()
+		if !testimonials[i].Approved {
+			testimonials[i].Approved = true
+			now := time.Now()
+			testimonials[i].ApprovedAt = &now
+			syncTestimonialToDB(testimonials[i])
+		}
+		t := testimonials[i]
+		return &t, nil
+	}
+	return nil, ErrTestimonialNotFound
 }
 
-// loadFromMongoDB seeds in-memory data from MongoDB collections on startup.
-// If a collection is empty it falls back to whatever initializeData() put there.
-func loadFromMongoDB() {
-	if mongoDB == nil {
-		return
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+// listTestimonials returns testimonials newest first; only approved ones
+// unless includePending is set.
+func listTestimonials(includePending bool) []Testimonial {
+	mu.RLock()
+	defer mu.RUnlock()
 
-	// Pets
-	if cur, err := petsColl().Find(ctx, bson.D{}); err == nil {
-		var dbPets []Pet
-		if err := cur.All(ctx, &dbPets); err == nil && len(dbPets) > 0 {
-			mu.Lock()
-			pets = dbPets
-			petsByID = make(map[string]*Pet)
-			statusCounts = make(map[string]int)
-			petsByBreed = make(map[string][]string)
-			for i := range pets {
-				petsByID[pets[i].ID] = &pets[i]
-				statusCounts[pets[i].Status]++
-				petsByBreed[pets[i].Breed] = append(petsByBreed[pets[i].Breed], pets[i].ID)
-			}
-			mu.Unlock()
-			log.Printf("[MONGO] Loaded %d pets", len(pets))
-		} else if err == nil {
-			// Collection is empty — push sample data to MongoDB so it persists
-			log.Println("[MONGO] No pets in DB, seeding sample data")
-			for _, p := range pets {
-				syncPetToDB(p)
-			}
+	result := make([]Testimonial, 0, len(testimonials))
+	for i := len(testimonials) - 1; i >= 0; i-- {
+		if testimonials[i].Approved || includePending {
+			result = append(result, testimonials[i])
 		}
 	}
+	return result
+}
 
-	// Users
-	if cur, err := usersColl().Find(ctx, bson.D{}); err == nil {
-		var dbUsers []User
-		if err := cur.All(ctx, &dbUsers); err == nil && len(dbUsers) > 0 {
-			mu.Lock()
-			users = dbUsers
-			usersByEmail = make(map[string]*User)
-			hasAdmin := false
-			for i := range users {
-				usersByEmail[users[i].Email] = &users[i]
-				if users[i].IsAdmin {
-					hasAdmin = true
-				}
-			}
-			// Always ensure a default admin account exists
-			if !hasAdmin {
-				adminUser := User{
-					ID:        "usr-admin",
-					Email:     "admin@pawtner.com",
-					Username:  "admin",
-					Password:  hashPassword("admin123"),
-					Role:      "admin",
-					IsAdmin:   true,
-					CreatedAt: time.Now(),
-					IsActive:  true,
-				}
-				users = append(users, adminUser)
-				usersByEmail[adminUser.Email] = &users[len(users)-1]
-				syncUserToDB(adminUser)
-			}
-			mu.Unlock()
-			log.Printf("[MONGO] Loaded %d users", len(users))
+// Limits for volunteer sign-ups, in characters unless noted.
+const (
+	maxVolunteerName      = 100
+	maxVolunteerPhone     = 20
+	maxVolunteerField     = 200
+	maxVolunteerInterests = 10 // entries
+)
+
+// SignUpVolunteer validates and stores a volunteer sign-up as Pending.
+func SignUpVolunteer(v Volunteer) (*Volunteer, error) {
+	v.Name = sanitizeText(v.Name, maxVolunteerName)
+	v.Email = strings.TrimSpace(strings.ToLower(v.Email))
+	v.Phone = sanitizeText(v.Phone, maxVolunteerPhone)
+	v.Availability = sanitizeText(v.Availability, maxVolunteerField)
+	if v.Name == "" || v.Availability == "" || !isValidEmail(v.Email) {
+		return nil, ErrInvalidVolunteer
+	}
+	interests := make([]string, 0, len(v.Interests))
+	for _, interest := range v.Interests {
+		if interest = sanitizeText(interest, maxTagLength); interest != "" && !slices.Contains(interests, interest) {
+			interests = append(interests, interest)
 		}
 	}
+	if len(interests) > maxVolunteerInterests {
+		return nil, fmt.Errorf("at most %d interests allowed", maxVolunteerInterests)
+	}
+	v.Interests = interests
 
-	// Donations
-	if cur, err := donationsColl().Find(ctx, bson.D{}); err == nil {
-		var dbDonations []Donation
-		if err := cur.All(ctx, &dbDonations); err == nil && len(dbDonations) > 0 {
-			mu.Lock()
-			donations = dbDonations
-			mu.Unlock()
-			log.Printf("[MONGO] Loaded %d donations", len(donations))
+	mu.Lock()
+	defer mu.Unlock()
+
+	v.ID = fmt.Sprintf("vol-%03d", len(volunteers)+1)
+	v.CreatedAt = time.Now()
+	v.Status = "Pending"
+	v.CaptchaToken = ""
+	volunteers = append(volunteers, v)
+	syncVolunteerToDB(v)
+	return &v, nil
+}
+
+// listVolunteers returns sign-ups oldest first, optionally only those with
+// the given status.
+func listVolunteers(status string) []Volunteer {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Volunteer, 0, len(volunteers))
+	for _, v := range volunteers {
+		if status == "" || strings.EqualFold(v.Status, status) {
+			result = append(result, v)
 		}
 	}
+	return result
+}
 
-	// Inquiries
-	if cur, err := inquiriesColl().Find(ctx, bson.D{}); err == nil {
-		var dbInquiries []AdoptionInquiry
-		if err := cur.All(ctx, &dbInquiries); err == nil && len(dbInquiries) > 0 {
-			mu.Lock()
-			inquiries = dbInquiries
-			mu.Unlock()
-			log.Printf("[MONGO] Loaded %d inquiries", len(inquiries))
+// Limits for event text fields, in characters.
+const (
+	maxEventTitle       = 200
+	maxEventDescription = 2000
+	maxEventLocation    = 200
+)
+
+// validateEvent sanitizes an event's text and checks that it has a title and
+// ends after it starts.
+func validateEvent(e *Event) error {
+	e.Title = sanitizeText(e.Title, maxEventTitle)
+	e.Description = sanitizeText(e.Description, maxEventDescription)
+	e.Location = sanitizeText(e.Location, maxEventLocation)
+	if e.Title == "" {
+		return errors.New("event title is required")
+	}
+	if e.StartsAt.IsZero() {
+		return errors.New("startsAt is required")
+	}
+	if !e.EndsAt.After(e.StartsAt) {
+		return ErrInvalidEventTime
+	}
+	return nil
+}
+
+// CreateEvent validates and stores a new event.
+func CreateEvent(e Event) (*Event, error) {
+	if err := validateEvent(&e); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	eventSeq++
+	e.ID = fmt.Sprintf("evt-%03d", eventSeq)
+	events = append(events, e)
+	syncEventToDB(e)
+	return &e, nil
+}
+
+// UpdateEvent replaces an event's details; the update must carry every field,
+// including Published.
+func UpdateEvent(id string, update Event) (*Event, error) {
+	if err := validateEvent(&update); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range events {
+		if events[i].ID != id {
+			continue
 		}
+		update.ID = id
+		events[i] = update
+		syncEventToDB(update)
+		return &update, nil
 	}
+	return nil, ErrEventNotFound
 }
 
-// generateOTP returns a zero-padded 6-digit numeric code.
-func generateOTP() string {
-	return fmt.Sprintf("%06d", rand.Intn(10000000))
+func DeleteEvent(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range events {
+		if events[i].ID == id {
+			events = slices.Delete(events, i, i+1)
+			removeDoc("events", id)
+			return nil
+		}
+	}
+	return ErrEventNotFound
 }
 
-// ── OTP email template ────────────────────────────────────────────────────────
+// listEvents returns events soonest first. Unless includePast is set, events
+// that ended before now are left out; drafts only appear with includeDrafts.
+func listEvents(now time.Time, includePast, includeDrafts bool) []Event {
+	mu.RLock()
+	result := make([]Event, 0, len(events))
+	for _, e := range events {
+		if (e.Published || includeDrafts) && (includePast || e.EndsAt.After(now)) {
+			result = append(result, e)
+		}
+	}
+	mu.RUnlock()
 
-const otpEmailTpl = `<!DOCTYPE html>
-<html lang="en">
-<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Verify Email</title></head>
-<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
-  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
-    <tr><td align="center">
-      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
-        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:36px 48px;text-align:center;">
-          <div style="font-size:36px;margin-bottom:8px;">🐾</div>
-          <h1 style="margin:0;color:#fff;font-size:24px;font-weight:700;">Email Verification</h1>
-          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Pawtner Hope Foundation</p>
-        </td></tr>
-        <tr><td style="padding:40px 48px;text-align:center;">
-          <p style="margin:0 0 8px;color:#555;font-size:15px;line-height:1.7;">Hi <strong>{{.Username}}</strong>! Use the code below to verify your email address.</p>
-          <p style="margin:0 0 28px;color:#888;font-size:13px;">This code expires in <strong>5 minutes</strong>.</p>
-          <div style="display:inline-block;background:#fdf6ef;border:2px dashed #d4a574;border-radius:16px;padding:24px 48px;margin-bottom:28px;">
-            <p style="margin:0;font-size:42px;font-weight:800;letter-spacing:10px;color:#b8844f;font-family:monospace;">{{.Code}}</p>
-          </div>
-          <p style="margin:0;color:#aaa;font-size:12px;">If you didn't request this, you can safely ignore this email.</p>
-        </td></tr>
-        <tr><td style="background:#f5f0eb;padding:20px 48px;text-align:center;">
-          <p style="margin:0;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation</p>
-        </td></tr>
-      </table>
-    </td></tr>
-  </table>
-</body></html>`
+	sort.SliceStable(result, func(i, j int) bool { return result[i].StartsAt.Before(result[j].StartsAt) })
+	return result
+}
 
-// 5. FUNCTIONS AND ERROR HANDLING
-func SearchPets(query string, filters []Filterable) ([]Pet, error) {
-	if query == "" && len(filters) == 0 {
-		return nil, errors.New("search query or filters required")
+// petCSVColumns are the spreadsheet headers ImportPetsCSV understands,
+// matched case-insensitively. Other columns are ignored.
+var petCSVColumns = []string{"name", "species", "breed", "age", "gender", "status", "vaccinated"}
+
+// maxPetCSVBytes caps the size of a pet CSV upload.
+const maxPetCSVBytes = 1 << 20
+
+// CSVRowResult reports the outcome of importing one CSV row. Line counts the
+// header as line 1, as a spreadsheet does.
+type CSVRowResult struct {
+	Line    int      `json:"line"`
+	ID      string   `json:"id,omitempty"`
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// petFromCSVRow builds a pet from one CSV record and validates it. A blank
+// status means Available.
+func petFromCSVRow(columns map[string]int, record []string) (Pet, []string) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	pet := Pet{
+		Name:    cell("name"),
+		Species: cell("species"),
+		Breed:   cell("breed"),
+		Gender:  cell("gender"),
+		Status:  cell("status"),
+	}
+	if pet.Status == "" {
+		pet.Status = "Available"
+	}
+
+	errs := make([]string, 0)
+	if age := cell("age"); age != "" {
+		n, err := strconv.Atoi(age)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Age %q is not a whole number", age))
+		}
+		pet.Age = n
+	}
+	switch vaccinated := strings.ToLower(cell("vaccinated")); vaccinated {
+	case "yes", "y", "true", "1":
+		pet.IsVaccinated = true
+	case "", "no", "n", "false", "0":
+	default:
+		errs = append(errs, fmt.Sprintf("Vaccinated %q must be yes or no", cell("vaccinated")))
+	}
+
+	if valid, petErrs := validatePet(pet); !valid {
+		errs = append(errs, petErrs...)
+	}
+	return pet, errs
+}
+
+// ImportPetsCSV adds a pet for each valid row of a CSV with a header row.
+// Invalid rows are reported and skipped. It returns the per-row results and
+// the added pets so they can be synced.
+func ImportPetsCSV(src io.Reader) ([]CSVRowResult, []Pet, error) {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if slices.Contains(petCSVColumns, name) {
+			columns[name] = i
+		}
+	}
+	for _, required := range []string{"name", "species"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("CSV header must include a %q column", required)
+		}
+	}
+
+	results := make([]CSVRowResult, 0)
+	valid := make([]Pet, 0)
+	validRows := make([]int, 0) // index into results for each valid pet
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		pet, errs := petFromCSVRow(columns, record)
+		if len(errs) > 0 {
+			results = append(results, CSVRowResult{Line: line, Errors: errs})
+			continue
+		}
+		validRows = append(validRows, len(results))
+		results = append(results, CSVRowResult{Line: line, Success: true})
+		valid = append(valid, pet)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	added := make([]Pet, 0, len(valid))
+	for i, pet := range valid {
+		pet = addPetLocked(pet)
+		results[validRows[i]].ID = pet.ID
+		added = append(added, pet)
+	}
+	return results, added, nil
+}
+
+// BulkPetResult reports the outcome of a bulk operation for one pet ID.
+type BulkPetResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// BulkDeletePets deletes each pet under a single lock, skipping unknown IDs.
+func BulkDeletePets(ids []string) []BulkPetResult {
+	mu.Lock()
+	defer mu.Unlock()
+
+	results := make([]BulkPetResult, 0, len(ids))
+	for _, id := range ids {
+		if removePetLocked(id) {
+			results = append(results, BulkPetResult{ID: id, Success: true})
+		} else {
+			results = append(results, BulkPetResult{ID: id, Reason: "not found"})
+		}
+	}
+	reindexPets()
+	return results
+}
+
+// BulkArchivePets moves each pet to the Archived status, hiding it from public
+// search without losing its record. It returns the per-ID results and the
+// archived pets so they can be synced.
+func BulkArchivePets(ids []string) ([]BulkPetResult, []Pet) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	results := make([]BulkPetResult, 0, len(ids))
+	archived := make([]Pet, 0, len(ids))
+	for _, id := range ids {
+		pet, exists := petsByID[id]
+		switch {
+		case !exists:
+			results = append(results, BulkPetResult{ID: id, Reason: "not found"})
+		case pet.Status == "Archived":
+			results = append(results, BulkPetResult{ID: id, Reason: "already archived"})
+		default:
+			statusCounts[pet.Status]--
+			statusCounts["Archived"]++
+			pet.Status = "Archived"
+			pet.CareReason = ""
+			unfeaturePet(pet)
+			results = append(results, BulkPetResult{ID: id, Success: true})
+			archived = append(archived, *pet)
+		}
+	}
+	return results, archived
+}
+
+// FeaturePet marks a pet as the pet of the week, unfeaturing whichever pet
+// held the spot before. It returns the newly featured pet and the previously
+// featured one (nil if there was none) so both can be synced.
+func FeaturePet(id string) (*Pet, *Pet, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	pet, exists := petsByID[id]
+	if !exists {
+		return nil, nil, ErrPetNotFound
+	}
+
+	var previous *Pet
+	for _, p := range petsByID {
+		if p.Featured && p.ID != id {
+			unfeaturePet(p)
+			previous = p
+		}
+	}
+
+	now := time.Now()
+	until := now.Add(featuredDuration)
+	pet.Featured = true
+	pet.FeaturedAt = &now
+	pet.FeaturedUntil = &until
+	return pet, previous, nil
+}
+
+func unfeaturePet(p *Pet) {
+	p.Featured = false
+	p.FeaturedAt = nil
+	p.FeaturedUntil = nil
+}
+
+// expireFeaturedPetLocked unfeatures the pet of the week once its time is up,
+// returning it so the change can be synced. Caller must hold mu.
+func expireFeaturedPetLocked(now time.Time) *Pet {
+	for _, p := range petsByID {
+		if p.Featured && p.FeaturedUntil != nil && !now.Before(*p.FeaturedUntil) {
+			unfeaturePet(p)
+			log.Printf("[INFO] Featured pet expired: ID=%s", p.ID)
+			return p
+		}
+	}
+	return nil
+}
+
+// reapFeaturedPet clears an expired pet of the week.
+func reapFeaturedPet(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if expired := expireFeaturedPetLocked(now); expired != nil {
+		syncPetToDB(*expired)
+	}
+}
+
+// staleListings returns the IDs of pets that have been Available for longer
+// than maxAge, measured from when they were listed, without a single inquiry.
+func staleListings(petList []Pet, inquiryList []AdoptionInquiry, now time.Time, maxAge time.Duration) []string {
+	asked := make(map[string]bool, len(inquiryList))
+	for _, inq := range inquiryList {
+		asked[inq.PetID] = true
+	}
+
+	ids := make([]string, 0)
+	for _, p := range petList {
+		if p.Status == "Available" && !asked[p.ID] && now.Sub(p.CreatedAt) > maxAge {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// flagStaleListings sets NeedsReview on stale listings and clears it from pets
+// that have since been adopted or asked about. It returns the newly flagged pets.
+func flagStaleListings(now time.Time) []Pet {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stale := make(map[string]bool)
+	if staleListingAge > 0 {
+		for _, id := range staleListings(pets, inquiries, now, staleListingAge) {
+			stale[id] = true
+		}
+	}
+
+	flagged := make([]Pet, 0)
+	for i := range pets {
+		if pets[i].NeedsReview == stale[pets[i].ID] {
+			continue
+		}
+		pets[i].NeedsReview = stale[pets[i].ID]
+		syncPetToDB(pets[i])
+		if pets[i].NeedsReview {
+			flagged = append(flagged, pets[i])
+		}
+	}
+	return flagged
+}
+
+// reviewStaleListings flags stale listings and, if staleListingEmail is set,
+// tells the admins about the ones flagged this time round.
+func reviewStaleListings(now time.Time) {
+	flagged := flagStaleListings(now)
+	if len(flagged) == 0 {
+		return
+	}
+	log.Printf("[INFO] %d pet(s) flagged for review after %s without inquiries", len(flagged), staleListingAge)
+	if !staleListingEmail {
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "These pets have been listed for over %d days without a single adoption inquiry:\n\n", int(staleListingAge.Hours()/24))
+	for _, p := range flagged {
+		fmt.Fprintf(&body, "- %s (%s, %s), listed %s\n", p.Name, p.Species, p.ID, displayTime(p.CreatedAt, displayDateLayout))
 	}
+	body.WriteString("\nConsider refreshing their photos and description, or featuring them.\n")
+
+	enqueueNotification(NotificationJob{
+		To:        adminEmail,
+		Subject:   "Pets Needing Review - Pawtner Hope",
+		Body:      body.String(),
+		JobType:   "stale-listings",
+		PlainText: true,
+	})
+}
+
+func staleListingWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reviewStaleListings(now)
+		}
+	}
+}
+
+// featuredPet returns a copy of the current pet of the week, if any. An
+// expired selection is cleared here too, so readers never see it between
+// reaps; only that case takes the write lock.
+func featuredPet() (Pet, bool) {
+	now := time.Now()
+	mu.RLock()
+	for _, p := range petsByID {
+		if p.Featured {
+			pet := *p
+			mu.RUnlock()
+			if pet.FeaturedUntil != nil && !now.Before(*pet.FeaturedUntil) {
+				reapFeaturedPet(now)
+				return Pet{}, false
+			}
+			return pet, true
+		}
+	}
+	mu.RUnlock()
+	return Pet{}, false
+}
+
+// JoinWaitlist records interest in a pet that is currently Adopted or Under Care.
+// It reports false when the email was already on the waitlist. Each pet keeps
+// at most maxWaitlistPerPet addresses.
+func JoinWaitlist(petID, email string) (bool, error) {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if !isValidEmail(email) {
+		return false, ErrInvalidEmail
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	pet, exists := petsByID[petID]
+	if !exists {
+		return false, ErrPetNotFound
+	}
+	if pet.Status == "Available" {
+		return false, errors.New("pet is available for adoption, please submit an inquiry instead")
+	}
+
+	for _, e := range waitlistByPet[petID] {
+		if e == email {
+			return false, nil
+		}
+	}
+	if len(waitlistByPet[petID]) >= maxWaitlistPerPet {
+		return false, ErrWaitlistFull
+	}
+	waitlistByPet[petID] = append(waitlistByPet[petID], email)
+	return true, nil
+}
+
+// notifyWaitlist queues an email to everyone waiting on a pet that is available again.
+func notifyWaitlist(pet Pet, emails []string) {
+	for _, email := range emails {
+		job := NotificationJob{
+			To:      email,
+			Subject: fmt.Sprintf("%s is available for adoption again - Pawtner Hope", pet.Name),
+			Body:    fmt.Sprintf("Good news! %s, whom you were waiting for, is available for adoption again. Visit Pawtner Hope to submit an inquiry.", pet.Name),
+			JobType: "waitlist",
+		}
+		enqueueNotification(job)
+	}
+	if len(emails) > 0 {
+		log.Printf("[INFO] Notified %d waitlisted adopters for pet %s", len(emails), pet.ID)
+	}
+}
+
+// AddFavorite shortlists a pet for the user. It reports false if the pet was
+// already a favorite.
+func AddFavorite(user *User, petID string) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := petsByID[petID]; !exists {
+		return false, ErrPetNotFound
+	}
+	for _, id := range user.Favorites {
+		if id == petID {
+			return false, nil
+		}
+	}
+	user.Favorites = append(user.Favorites, petID)
+	return true, nil
+}
+
+// RemoveFavorite drops a pet from the user's shortlist. It reports false if
+// the pet wasn't on it.
+func RemoveFavorite(user *User, petID string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, id := range user.Favorites {
+		if id == petID {
+			user.Favorites = append(user.Favorites[:i], user.Favorites[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// favoritePets returns the full records of the user's favorites, skipping
+// pets that have since been deleted.
+func favoritePets(user *User) []Pet {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Pet, 0, len(user.Favorites))
+	for _, id := range user.Favorites {
+		if pet, exists := petsByID[id]; exists {
+			result = append(result, *pet)
+		}
+	}
+	return result
+}
+
+// petsMissingPhotos returns available pets that have no images yet.
+func petsMissingPhotos() []Pet {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Pet, 0)
+	for _, p := range pets {
+		if p.Status == "Available" && len(p.Images) == 0 {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// checkupDateLayouts are the LastCheckup formats staff have used.
+var checkupDateLayouts = []string{"2006-01-02", time.RFC3339, "02/01/2006", "2 Jan 2006", "January 2, 2006"}
+
+// parseCheckupDate reads a Medical.LastCheckup value in any of checkupDateLayouts.
+func parseCheckupDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range checkupDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// checkupDue returns the shelter's pets whose last checkup is more than months
+// old or was never recorded. Adopted pets are left to their new vets. Pets
+// whose date can't be parsed aren't guessed at; their IDs come back in
+// unparseable so staff can fix the record.
+func checkupDue(petList []Pet, now time.Time, months int) (due []Pet, unparseable []string) {
+	cutoff := now.AddDate(0, -months, 0)
+	due, unparseable = make([]Pet, 0), make([]string, 0)
+	for _, p := range petList {
+		if p.Status == "Adopted" {
+			continue
+		}
+		if p.Medical == nil || strings.TrimSpace(p.Medical.LastCheckup) == "" {
+			due = append(due, p)
+			continue
+		}
+		last, ok := parseCheckupDate(p.Medical.LastCheckup)
+		if !ok {
+			unparseable = append(unparseable, p.ID)
+			continue
+		}
+		if last.Before(cutoff) {
+			due = append(due, p)
+		}
+	}
+	return due, unparseable
+}
+
+// petFacets counts, for each requested attribute, how many pets matching every
+// filter carry each distinct value. Pets without the attribute aren't counted.
+func petFacets(attrs []string, filters []Filterable) map[string]map[string]int {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	facets := make(map[string]map[string]int, len(attrs))
+	for _, attr := range attrs {
+		facets[attr] = make(map[string]int)
+	}
+	for _, p := range pets {
+		if !matchesAll(p, filters) {
+			continue
+		}
+		for _, attr := range attrs {
+			if v, ok := p.Attributes[attr]; ok && v != "" {
+				facets[attr][v]++
+			}
+		}
+	}
+	return facets
+}
+
+// similarPets suggests up to limit other listed pets of the target's species.
+// Same-breed pets come first, then pets sharing more tags; ties go by ID.
+func similarPets(target Pet, all []Pet, limit int) []Pet {
+	type candidate struct {
+		pet       Pet
+		sameBreed bool
+		overlap   int
+	}
+	var candidates []candidate
+	for _, p := range all {
+		if p.ID == target.ID || !strings.EqualFold(p.Species, target.Species) ||
+			slices.Contains(unlistedStatuses, p.Status) {
+			continue
+		}
+		c := candidate{pet: p, sameBreed: strings.EqualFold(p.Breed, target.Breed)}
+		for _, tag := range p.Tags {
+			if slices.ContainsFunc(target.Tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+				c.overlap++
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.sameBreed != b.sameBreed {
+			return a.sameBreed
+		}
+		if a.overlap != b.overlap {
+			return a.overlap > b.overlap
+		}
+		return a.pet.ID < b.pet.ID
+	})
+
+	result := make([]Pet, 0, limit)
+	for _, c := range candidates {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, c.pet)
+	}
+	return result
+}
+
+// petsWithBrokenPhotos lists pets with at least one photo URL that failed the
+// reachability check.
+func petsWithBrokenPhotos() []Pet {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Pet, 0)
+	for _, p := range pets {
+		if len(p.BrokenImages) > 0 {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// schedulePhotoCheck verifies the pet's photo URLs in the background, so a
+// slow image host never holds up the request.
+func schedulePhotoCheck(pet Pet) {
+	if !photoCheckEnabled || len(pet.Images) == 0 {
+		return
+	}
+	go checkPetPhotos(pet.ID, pet.Images)
+}
+
+// checkPetPhotos HEAD-requests each URL and records the unreachable ones on
+// the pet. URLs replaced while the check ran are ignored.
+func checkPetPhotos(petID string, urls []string) {
+	client := &http.Client{Timeout: photoCheckTimeout}
+	var broken []string
+	for _, u := range urls {
+		if !photoReachable(client, u) {
+			broken = append(broken, u)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	pet, exists := petsByID[petID]
+	if !exists {
+		return
+	}
+	var current []string
+	for _, u := range broken {
+		if slices.Contains(pet.Images, u) {
+			current = append(current, u)
+		}
+	}
+	pet.BrokenImages = current
+	if len(current) > 0 {
+		log.Printf("[WARN] Pet %s has %d unreachable photo(s): %v", petID, len(current), current)
+	}
+	syncPetToDB(*pet)
+}
+
+// photoReachable reports whether a HEAD request to url succeeds. Hosts that
+// don't allow HEAD get a GET instead.
+func photoReachable(client *http.Client, url string) bool {
+	resp, err := client.Head(url)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = client.Get(url)
+	}
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 400
+}
+
+func ProcessDonation(donation *Donation) (*Receipt, error) {
+	if donation.Amount <= 0 {
+		return nil, ErrInvalidPayment
+	}
+	if donation.DonorName == "" || donation.DonorEmail == "" {
+		return nil, errors.New("donor name and email are required")
+	}
+	if donation.PaymentMethod == "" {
+		return nil, errors.New("payment method is required")
+	}
+
+	if err := validateDedication(*donation); err != nil {
+		return nil, err
+	}
+	donation.DedicationName = singleLine(donation.DedicationName, maxDedicationName)
+	donation.HonoreeEmail = strings.ToLower(strings.TrimSpace(donation.HonoreeEmail))
+
+	donation.ID = fmt.Sprintf("don-%03d", len(donations)+1)
+	donation.TransactionID = fmt.Sprintf("txn-%d", time.Now().UnixNano())
+	donation.Status = "Completed"
+	donation.CreatedAt = time.Now()
+
+	mu.Lock()
+	donations = append(donations, *donation)
+	mu.Unlock()
+	publishEvent(EventDonationCompleted, donation.ID, *donation)
+
+	syncDonationToDB(*donation)
+	if smsEnabled && donation.DonorPhone != "" {
+		go sendDonationSMS(*donation)
+	}
+	sendDedicationNotice(*donation)
+	receipt := GenerateReceipt(*donation)
+	return &receipt, nil
+}
+
+// validateDedication requires the dedication type and name to be given together.
+func validateDedication(donation Donation) error {
+	if donation.DedicationType == "" && donation.DedicationName == "" {
+		return nil
+	}
+	if donation.DedicationType == "" || strings.TrimSpace(donation.DedicationName) == "" {
+		return errors.New("dedication type and name must be provided together")
+	}
+	if donation.DedicationType != "memory" && donation.DedicationType != "honor" {
+		return errors.New("dedication type must be 'memory' or 'honor'")
+	}
+	if donation.HonoreeEmail != "" && !isValidEmail(strings.TrimSpace(donation.HonoreeEmail)) {
+		return fmt.Errorf("honoree %w", ErrInvalidEmail)
+	}
+	return nil
+}
+
+// maxDedicationName caps the honoree's name, which is echoed into emails.
+const maxDedicationName = 100
+
+// singleLine sanitizes text for an email subject or line: control
+// characters and line breaks are dropped, runs of whitespace collapse to one
+// space, and the result is cut to maxLen characters.
+func singleLine(text string, maxLen int) string {
+	return sanitizeText(strings.Join(strings.Fields(text), " "), maxLen)
+}
+
+// dedicationText renders a dedication as "in memory of X" / "in honor of X",
+// or "" when the donation has none.
+func dedicationText(donation Donation) string {
+	if donation.DedicationType == "" {
+		return ""
+	}
+	return fmt.Sprintf("in %s of %s", donation.DedicationType, donation.DedicationName)
+}
+
+// ReceiptGenerator builds the receipt issued for a completed donation.
+type ReceiptGenerator interface {
+	Generate(donation Donation) Receipt
+}
+
+// defaultReceiptGenerator produces the standard thank-you receipt.
+type defaultReceiptGenerator struct{}
+
+// GenerateReceipt builds a donation's receipt with the configured receiptGenerator.
+func GenerateReceipt(donation Donation) Receipt {
+	return receiptGenerator.Generate(donation)
+}
+
+func (defaultReceiptGenerator) Generate(donation Donation) Receipt {
+	message := fmt.Sprintf("Thank you %s for your generous donation of ₹%.2f to Pawtner Hope Foundation!", donation.DonorName, donation.Amount)
+	if dedication := dedicationText(donation); dedication != "" {
+		message += " This gift was made " + dedication + "."
+	}
+	return Receipt{
+		ReceiptID:  fmt.Sprintf("rcpt-%d", time.Now().UnixNano()),
+		DonationID: donation.ID,
+		DonorName:  donation.DonorName,
+		Amount:     donation.Amount,
+		IssuedAt:   time.Now(),
+		Message:    message,
+	}
+}
+
+// recordAudit appends an entry to the audit log.
+func recordAudit(actor, action, target, detail string) {
+	mu.Lock()
+	auditLog = append(auditLog, AuditEntry{Time: time.Now(), Actor: actor, Action: action, Target: target, Detail: detail})
+	mu.Unlock()
+	log.Printf("[AUDIT] %s %s %s %s", actor, action, target, detail)
+}
+
+// maxFailedLoginEmail caps the email stored with a failed login; 254 bytes
+// is the longest valid address.
+const maxFailedLoginEmail = 254
+
+// loginFailureReason explains why Login rejected email/password, for the
+// failed-login record only; the client always just sees invalid credentials.
+// Caller must hold mu.
+func loginFailureReason(email, password string) string {
+	if email == "" || password == "" {
+		return "missing_credentials"
+	}
+	if _, exists := usersByEmail[email]; !exists {
+		return "unknown_email"
+	}
+	return "wrong_password"
+}
+
+// recordFailedLogin stores a rejected login, dropping the oldest record once
+// maxFailedLogins is reached. Past failedLoginRecordLimit a minute for ip,
+// the attempt only bumps Repeats on that IP's latest record and nothing is
+// written to the database; it reports false in that case.
+func recordFailedLogin(email, password, ip string) (FailedLogin, bool) {
+	if len(email) > maxFailedLoginEmail {
+		email = strings.ToValidUTF8(email[:maxFailedLoginEmail], "")
+	}
+	allowed, _ := failedLoginLimiter.allow(ip, time.Now())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !allowed {
+		for i := len(failedLogins) - 1; i >= 0; i-- {
+			if failedLogins[i].IP == ip {
+				failedLogins[i].Repeats++
+				return failedLogins[i], false
+			}
+		}
+		return FailedLogin{Email: email, IP: ip}, false
+	}
+
+	failedLoginSeq++
+	entry := FailedLogin{
+		ID:     fmt.Sprintf("fl-%06d", failedLoginSeq),
+		Email:  email,
+		IP:     ip,
+		Time:   time.Now(),
+		Reason: loginFailureReason(email, password),
+	}
+	if maxFailedLogins > 0 && len(failedLogins) >= maxFailedLogins {
+		failedLogins = slices.Delete(failedLogins, 0, len(failedLogins)-maxFailedLogins+1)
+	}
+	failedLogins = append(failedLogins, entry)
+	syncFailedLoginToDB(entry)
+	return entry, true
+}
+
+// isValidEmail accepts a bare address like "name@example.com".
+func isValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
+
+// maskEmail hides all but the first letter of the local part, so
+// "priya@example.com" becomes "p***@example.com".
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "***"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// findInquiry returns a pointer into inquiries. Callers must hold mu.
+func findInquiry(id string) *AdoptionInquiry {
+	for i := range inquiries {
+		if inquiries[i].ID == id {
+			return &inquiries[i]
+		}
+	}
+	return nil
+}
+
+// findDonation returns a pointer into donations. Callers must hold mu.
+func findDonation(id string) *Donation {
+	for i := range donations {
+		if donations[i].ID == id {
+			return &donations[i]
+		}
+	}
+	return nil
+}
+
+// inquiryStatuses are the states an adoption inquiry can be moved between.
+var inquiryStatuses = []string{"Pending", "Approved", "Rejected"}
+
+// UpdateInquiryStatus moves an inquiry to status. Approving it marks the pet
+// Adopted, and is refused if the pet was already adopted; withdrawing the
+// approval puts the pet back the way it was, telling its waitlist if that
+// makes it Available again. The pet is returned (nil if it
+// didn't change) so it can be synced. A Pending inquiry that becomes
+// Approved also emails the adopter.
+func UpdateInquiryStatus(id, status string) (*AdoptionInquiry, *Pet, error) {
+	if !slices.Contains(inquiryStatuses, status) {
+		return nil, nil, ErrInvalidInquiryStatus
+	}
+
+	// Deferred ahead of the unlock so it runs after it.
+	var adoptedPet *Pet
+	defer func() {
+		if adoptedPet != nil {
+			publishEvent(EventPetAdopted, adoptedPet.ID, *adoptedPet)
+		}
+	}()
+	mu.Lock()
+	defer mu.Unlock()
+
+	inquiry := findInquiry(id)
+	if inquiry == nil {
+		return nil, nil, ErrInquiryNotFound
+	}
+	previous := inquiry.Status
+	pet, petExists := petsByID[inquiry.PetID]
+	approving := status == "Approved" && previous != "Approved"
+	if approving && petExists && pet.Status == "Adopted" {
+		return nil, nil, ErrPetAlreadyAdopted
+	}
+
+	var changed *Pet
+	switch {
+	case approving && petExists:
+		inquiry.PetStatusBefore = pet.Status
+		statusCounts[pet.Status]--
+		statusCounts["Adopted"]++
+		pet.Status = "Adopted"
+		pet.CareReason = ""
+		unfeaturePet(pet)
+		p := *pet
+		changed = &p
+		adoptedPet = &p
+	case previous == "Approved" && status != "Approved":
+		if petExists && pet.Status == "Adopted" && inquiry.PetStatusBefore != "" {
+			statusCounts["Adopted"]--
+			statusCounts[inquiry.PetStatusBefore]++
+			pet.Status = inquiry.PetStatusBefore
+			p := *pet
+			changed = &p
+
+			// Back up for adoption, as in UpdatePet.
+			if pet.Status == "Available" {
+				notifyWaitlist(p, waitlistByPet[pet.ID])
+				delete(waitlistByPet, pet.ID)
+			}
+		}
+		inquiry.PetStatusBefore = ""
+	}
+	inquiry.Status = status
+	updated := *inquiry
+
+	// Only the first approval is news to the adopter; re-saving an approved
+	// inquiry must not send another email.
+	if previous == "Pending" && status == "Approved" {
+		pet := Pet{ID: inquiry.PetID}
+		if p, exists := petsByID[inquiry.PetID]; exists {
+			pet = *p
+		}
+		sendAdoptionApprovalEmail(updated, pet)
+	}
+	return &updated, changed, nil
+}
+
+// SubmitFosterApplication stores a Pending application from user. A pet, if
+// given, must exist and still be with the shelter.
+func SubmitFosterApplication(user *User, app FosterApplication) (*FosterApplication, error) {
+	app.PetID = strings.TrimSpace(app.PetID)
+	app.Phone = sanitizeText(app.Phone, maxVolunteerPhone)
+	app.Message = sanitizeText(app.Message, maxTestimonialText)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if app.PetID != "" {
+		pet, exists := petsByID[app.PetID]
+		if !exists {
+			return nil, ErrPetNotFound
+		}
+		if slices.Contains(unlistedStatuses, pet.Status) {
+			return nil, fmt.Errorf("%s is no longer in our care", pet.Name)
+		}
+	}
+
+	app.ID = fmt.Sprintf("fst-%03d", len(fosterApps)+1)
+	app.UserID = user.ID
+	app.Name = user.Username
+	app.Email = user.Email
+	app.Status = "Pending"
+	app.CreatedAt = time.Now()
+	fosterApps = append(fosterApps, app)
+	return &app, nil
+}
+
+// UpdateFosterStatus moves a foster application through the same states as
+// an adoption inquiry. Approving it moves the pet, if any, to "In Foster",
+// and withdrawing the approval puts the pet back the way it was. The pet is
+// returned (nil if it didn't change) so it can be synced, along with the
+// application's previous status.
+func UpdateFosterStatus(id, status string) (*FosterApplication, *Pet, string, error) {
+	if !slices.Contains(inquiryStatuses, status) {
+		return nil, nil, "", ErrInvalidInquiryStatus
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var app *FosterApplication
+	for i := range fosterApps {
+		if fosterApps[i].ID == id {
+			app = &fosterApps[i]
+			break
+		}
+	}
+	if app == nil {
+		return nil, nil, "", ErrFosterNotFound
+	}
+	previous := app.Status
+	pet, petExists := petsByID[app.PetID]
+
+	var changed *Pet
+	switch {
+	case status == "Approved" && previous != "Approved":
+		if petExists && pet.Status != "In Foster" && !slices.Contains(unlistedStatuses, pet.Status) {
+			app.PetStatusBefore = pet.Status
+			statusCounts[pet.Status]--
+			statusCounts["In Foster"]++
+			pet.Status = "In Foster"
+			pet.CareReason = ""
+			p := *pet
+			changed = &p
+		}
+	case previous == "Approved" && status != "Approved":
+		if petExists && pet.Status == "In Foster" && app.PetStatusBefore != "" {
+			statusCounts["In Foster"]--
+			statusCounts[app.PetStatusBefore]++
+			pet.Status = app.PetStatusBefore
+			p := *pet
+			changed = &p
+		}
+		app.PetStatusBefore = ""
+	}
+	app.Status = status
+	updated := *app
+	return &updated, changed, previous, nil
+}
+
+// listFosterApplications returns applications oldest first, optionally only
+// those with the given status.
+func listFosterApplications(status string) []FosterApplication {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]FosterApplication, 0, len(fosterApps))
+	for _, app := range fosterApps {
+		if status == "" || strings.EqualFold(app.Status, status) {
+			result = append(result, app)
+		}
+	}
+	return result
+}
+
+// LinkAdoptionFee records a completed donation as the fee payment for an approved inquiry.
+// An inquiry is paid for once, and a donation pays for at most one inquiry.
+func LinkAdoptionFee(inquiryID, donationID string) (*AdoptionInquiry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	inquiry := findInquiry(inquiryID)
+	if inquiry == nil {
+		return nil, ErrInquiryNotFound
+	}
+	if inquiry.Status != "Approved" {
+		return nil, errors.New("adoption fee can only be linked to an approved inquiry")
+	}
+
+	donation := findDonation(donationID)
+	if donation == nil {
+		return nil, ErrDonationNotFound
+	}
+	if donation.Status != "Completed" {
+		return nil, errors.New("donation has not been completed")
+	}
+	if inquiry.FeePaid {
+		return nil, ErrFeeAlreadyPaid
+	}
+	for _, other := range inquiries {
+		if other.DonationID == donation.ID {
+			return nil, ErrDonationLinked
+		}
+	}
+
+	inquiry.DonationID = donation.ID
+	inquiry.FeePaid = true
+	inquiry.FeeAmount = donation.Amount
+	linked := *inquiry
+	return &linked, nil
+}
+
+// RecordAdoptionFee marks an approved inquiry's fee as paid outside the
+// donation flow (cash, bank transfer), using the pet's listed adoption fee.
+// A fee already recorded, here or from a linked donation, is left alone.
+func RecordAdoptionFee(inquiryID string) (*AdoptionInquiry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	inquiry := findInquiry(inquiryID)
+	if inquiry == nil {
+		return nil, ErrInquiryNotFound
+	}
+	if inquiry.Status != "Approved" {
+		return nil, errors.New("adoption fee can only be recorded for an approved inquiry")
+	}
+	if inquiry.FeePaid {
+		return nil, ErrFeeAlreadyPaid
+	}
+
+	inquiry.FeePaid = true
+	if pet, exists := petsByID[inquiry.PetID]; exists {
+		inquiry.FeeAmount = pet.AdoptionFee
+	}
+	recorded := *inquiry
+	return &recorded, nil
+}
+
+// Layouts for human-facing dates. Timestamps carry the zone abbreviation so
+// donors elsewhere know which clock they're reading.
+const (
+	displayDateLayout     = "2 Jan 2006"
+	displayDateTimeLayout = "2 Jan 2006, 3:04 PM MST"
+)
+
+// displayTime formats t in the configured display timezone.
+func displayTime(t time.Time, layout string) string {
+	return t.In(displayLocation).Format(layout)
+}
+
+// summarizeDonations aggregates completed donations created in [from, to).
+func summarizeDonations(list []Donation, from, to time.Time) DonationSummary {
+	summary := DonationSummary{From: from, To: to}
+	donors := make(map[string]bool)
+	campaignTotals := make(map[string]float64)
+
+	for _, d := range list {
+		if d.Status != "Completed" || d.CreatedAt.Before(from) || !d.CreatedAt.Before(to) {
+			continue
+		}
+		summary.TotalAmount += d.Amount
+		summary.DonationCount++
+		donors[strings.ToLower(d.DonorEmail)] = true
+		if d.Campaign != "" {
+			campaignTotals[d.Campaign] += d.Amount
+		}
+	}
+	summary.DonorCount = len(donors)
+
+	for campaign, total := range campaignTotals {
+		// Ties go to the alphabetically first campaign so the result is stable.
+		if total > summary.TopCampaignTotal || (total == summary.TopCampaignTotal && campaign < summary.TopCampaign) {
+			summary.TopCampaign = campaign
+			summary.TopCampaignTotal = total
+		}
+	}
+	return summary
+}
+
+// weeklyDonationSummary summarizes the seven days up to now.
+func weeklyDonationSummary() DonationSummary {
+	now := time.Now()
+	mu.RLock()
+	defer mu.RUnlock()
+	return summarizeDonations(donations, now.AddDate(0, 0, -7), now)
+}
+
+// sendWeeklyReport emails the weekly donations summary to the admin address.
+func sendWeeklyReport() {
+	summary := weeklyDonationSummary()
+	topCampaign := summary.TopCampaign
+	if topCampaign == "" {
+		topCampaign = "(none)"
+	}
+	body := fmt.Sprintf("Weekly donations report: %s - %s (%s)\n\n"+
+		"Total raised: Rs. %.2f\nDonations: %d\nUnique donors: %d\nTop campaign: %s (Rs. %.2f)\n",
+		displayTime(summary.From, displayDateLayout), displayTime(summary.To, displayDateLayout), displayTime(summary.To, "MST"),
+		summary.TotalAmount, summary.DonationCount, summary.DonorCount, topCampaign, summary.TopCampaignTotal)
+
+	enqueueNotification(NotificationJob{
+		To:        adminEmail,
+		Subject:   "Weekly Donations Report - Pawtner Hope",
+		Body:      body,
+		JobType:   "report",
+		PlainText: true,
+	})
+}
+
+func weeklyReportWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sendWeeklyReport()
+		}
+	}
+}
+
+// BuildGivingStatement collects a user's completed donations for one calendar year.
+// Donations are matched by UserID, or by email for gifts made while logged out.
+func BuildGivingStatement(user User, year int) GivingStatement {
+	statement := GivingStatement{
+		Year:        year,
+		DonorName:   user.Username,
+		DonorEmail:  user.Email,
+		Donations:   make([]Donation, 0),
+		GeneratedAt: time.Now(),
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, d := range donations {
+		if d.Status != "Completed" || d.CreatedAt.Year() != year {
+			continue
+		}
+		if d.UserID == user.ID || strings.EqualFold(d.DonorEmail, user.Email) {
+			statement.Donations = append(statement.Donations, d)
+			statement.Total += d.Amount
+		}
+	}
+	return statement
+}
+
+// pdfEscape escapes the characters that are special inside a PDF string literal.
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
+
+// renderStatementPDF lays the statement out as a plain-text PDF, 50 lines per page.
+func renderStatementPDF(st GivingStatement) []byte {
+	lines := []string{
+		"Pawtner Hope Foundation - Annual Giving Statement",
+		"",
+		fmt.Sprintf("Donor: %s <%s>", st.DonorName, st.DonorEmail),
+		fmt.Sprintf("Year: %d", st.Year),
+		fmt.Sprintf("Generated: %s", displayTime(st.GeneratedAt, displayDateTimeLayout)),
+		"",
+		"Date          Donation ID    Transaction             Amount (INR)",
+	}
+	for _, d := range st.Donations {
+		lines = append(lines, fmt.Sprintf("%-13s %-14s %-23s %12.2f", displayTime(d.CreatedAt, "2006-01-02"), d.ID, d.TransactionID, d.Amount))
+	}
+	lines = append(lines, "", fmt.Sprintf("Total donated in %d: INR %.2f", st.Year, st.Total),
+		"Thank you for supporting Pawtner Hope Foundation.")
+
+	const linesPerPage = 50
+	var pageContents []string
+	for start := 0; start < len(lines); start += linesPerPage {
+		end := start + linesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		var content bytes.Buffer
+		content.WriteString("BT /F1 10 Tf 50 800 Td 14 TL\n")
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(&content, "(%s) '\n", pdfEscape(line))
+		}
+		content.WriteString("ET")
+		pageContents = append(pageContents, content.String())
+	}
+
+	// Object layout: 1 catalog, 2 page tree, 3 font, then a page + content pair per page.
+	kids := make([]string, len(pageContents))
+	for i := range pageContents {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageContents)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	}
+	for i, content := range pageContents {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// ── Email templates ───────────────────────────────────────────────────────────
+
+const welcomeEmailTpl = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Welcome</title></head>
+<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
+  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
+    <tr><td align="center">
+      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
+        <!-- Header -->
+        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:40px 48px;text-align:center;">
+          <div style="font-size:36px;margin-bottom:8px;">🐾</div>
+          <h1 style="margin:0;color:#fff;font-size:26px;font-weight:700;letter-spacing:-0.5px;">Pawtner Hope Foundation</h1>
+          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Spreading love, one paw at a time</p>
+        </td></tr>
+        <!-- Body -->
+        <tr><td style="padding:40px 48px;">
+          <h2 style="margin:0 0 16px;color:#2c2416;font-size:22px;">Welcome, {{.Username}}! 👋</h2>
+          <p style="margin:0 0 16px;color:#555;font-size:15px;line-height:1.7;">Your account has been created successfully. We're so glad to have you as part of our community of animal lovers.</p>
+          <p style="margin:0 0 24px;color:#555;font-size:15px;line-height:1.7;">Here's what you can do now:</p>
+          <table width="100%" cellpadding="0" cellspacing="0" style="margin-bottom:28px;">
+            <tr><td style="padding:12px 16px;background:#fdf6ef;border-left:3px solid #d4a574;border-radius:4px;margin-bottom:10px;">
+              <span style="color:#b8844f;font-weight:600;">🐶 Adopt a Pet</span>
+              <span style="color:#666;font-size:14px;"> — Browse our animals and submit an adoption inquiry.</span>
+            </td></tr>
+            <tr><td style="height:8px;"></td></tr>
+            <tr><td style="padding:12px 16px;background:#fdf6ef;border-left:3px solid #d4a574;border-radius:4px;">
+              <span style="color:#b8844f;font-weight:600;">💛 Donate</span>
+              <span style="color:#666;font-size:14px;"> — Help us rescue and care for more animals.</span>
+            </td></tr>
+          </table>
+          <p style="margin:0 0 4px;color:#888;font-size:13px;">Account details</p>
+          <table width="100%" cellpadding="0" cellspacing="0" style="border:1px solid #eee;border-radius:8px;overflow:hidden;">
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;width:120px;">Email</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Email}}</td></tr>
+            <tr><td style="padding:10px 16px;color:#888;font-size:13px;">Username</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Username}}</td></tr>
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;">Member since</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Date}}</td></tr>
+          </table>
+        </td></tr>
+        <!-- CTA -->
+        <tr><td style="padding:0 48px 40px;text-align:center;">
+          <a href="http://localhost:8080/adoption.html" style="display:inline-block;background:#d4a574;color:#fff;text-decoration:none;padding:14px 36px;border-radius:50px;font-size:15px;font-weight:600;">Browse Pets for Adoption →</a>
+        </td></tr>
+        <!-- Footer -->
+        <tr><td style="background:#f5f0eb;padding:24px 48px;text-align:center;">
+          <p style="margin:0;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation. If you didn't create this account, please ignore this email.</p>
+        </td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body></html>`
+
+const receiptEmailTpl = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Donation Receipt</title></head>
+<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
+  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
+    <tr><td align="center">
+      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
+        <!-- Header -->
+        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:40px 48px;text-align:center;">
+          <div style="font-size:36px;margin-bottom:8px;">🐾</div>
+          <h1 style="margin:0;color:#fff;font-size:26px;font-weight:700;">Donation Receipt</h1>
+          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Pawtner Hope Foundation</p>
+        </td></tr>
+        <!-- Amount hero -->
+        <tr><td style="padding:36px 48px 24px;text-align:center;border-bottom:1px solid #f0ebe4;">
+          <p style="margin:0 0 4px;color:#999;font-size:13px;text-transform:uppercase;letter-spacing:.8px;">Amount Received</p>
+          <p style="margin:0;color:#b8844f;font-size:48px;font-weight:700;">₹{{.Amount}}</p>
+        </td></tr>
+        <!-- Details -->
+        <tr><td style="padding:28px 48px;">
+          <p style="margin:0 0 16px;color:#2c2416;font-size:16px;font-weight:600;">Thank you, {{.DonorName}}! 💛</p>
+          <p style="margin:0 0 24px;color:#555;font-size:15px;line-height:1.7;">Your generous donation helps us rescue, care for, and re-home abandoned pets. Every rupee makes a real difference in an animal's life.</p>
+          <table width="100%" cellpadding="0" cellspacing="0" style="border:1px solid #eee;border-radius:10px;overflow:hidden;margin-bottom:24px;">
+            <tr style="background:#f9f9f9;"><td style="padding:12px 16px;color:#888;font-size:13px;width:150px;">Receipt No.</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.ReceiptID}}</td></tr>
+            <tr><td style="padding:12px 16px;color:#888;font-size:13px;">Donation ID</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.DonationID}}</td></tr>
+            <tr style="background:#f9f9f9;"><td style="padding:12px 16px;color:#888;font-size:13px;">UPI Txn / UTR</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.TransactionID}}</td></tr>
+            <tr><td style="padding:12px 16px;color:#888;font-size:13px;">Date</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;">{{.Date}}</td></tr>
+            <tr style="background:#f9f9f9;"><td style="padding:12px 16px;color:#888;font-size:13px;">Donor Email</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;">{{.DonorEmail}}</td></tr>
+            {{if .Dedication}}<tr><td style="padding:12px 16px;color:#888;font-size:13px;">Dedication</td><td style="padding:12px 16px;color:#2c2416;font-size:13px;">Given {{.Dedication}}</td></tr>{{end}}
+          </table>
+          <div style="background:#fdf6ef;border-radius:10px;padding:16px 20px;">
+            <p style="margin:0;color:#b8844f;font-size:13px;">🔒 This is an official receipt for your tax records. Please save this email.</p>
+          </div>
+        </td></tr>
+        <!-- CTA -->
+        <tr><td style="padding:0 48px 40px;text-align:center;">
+          <a href="http://localhost:8080/donate.html" style="display:inline-block;background:#d4a574;color:#fff;text-decoration:none;padding:14px 36px;border-radius:50px;font-size:15px;font-weight:600;">Donate Again →</a>
+        </td></tr>
+        <!-- Footer -->
+        <tr><td style="background:#f5f0eb;padding:24px 48px;text-align:center;">
+          <p style="margin:0 0 6px;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation</p>
+          <p style="margin:0;color:#bbb;font-size:12px;">Questions? Email us at pawtnerhopefoundation@gmail.com</p>
+        </td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body></html>`
+
+const adoptionApprovedTpl = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Adoption Approved</title></head>
+<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
+  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
+    <tr><td align="center">
+      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
+        <!-- Header -->
+        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:40px 48px;text-align:center;">
+          <div style="font-size:36px;margin-bottom:8px;">🏡</div>
+          <h1 style="margin:0;color:#fff;font-size:26px;font-weight:700;">Adoption Approved</h1>
+          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Pawtner Hope Foundation</p>
+        </td></tr>
+        <!-- Body -->
+        <tr><td style="padding:40px 48px;">
+          <h2 style="margin:0 0 16px;color:#2c2416;font-size:22px;">Congratulations, {{.AdopterName}}! 🎉</h2>
+          <p style="margin:0 0 16px;color:#555;font-size:15px;line-height:1.7;">We're delighted to let you know that your adoption inquiry for <strong style="color:#b8844f;">{{.PetName}}</strong> has been approved.</p>
+          <p style="margin:0 0 24px;color:#555;font-size:15px;line-height:1.7;">Our team will be in touch shortly to arrange the adoption fee and a time for you to bring {{.PetName}} home.</p>
+          <table width="100%" cellpadding="0" cellspacing="0" style="border:1px solid #eee;border-radius:8px;overflow:hidden;">
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;width:120px;">Inquiry ID</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.InquiryID}}</td></tr>
+            <tr><td style="padding:10px 16px;color:#888;font-size:13px;">Pet</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.PetName}}</td></tr>
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;">Approved on</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Date}}</td></tr>
+          </table>
+        </td></tr>
+        <!-- Footer -->
+        <tr><td style="background:#f5f0eb;padding:24px 48px;text-align:center;">
+          <p style="margin:0 0 6px;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation</p>
+          <p style="margin:0;color:#bbb;font-size:12px;">Questions? Email us at pawtnerhopefoundation@gmail.com</p>
+        </td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body></html>`
+
+const bookingConfirmationTpl = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Booking Confirmed</title></head>
+<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
+  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
+    <tr><td align="center">
+      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
+        <!-- Header -->
+        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:40px 48px;text-align:center;">
+          <div style="font-size:36px;margin-bottom:8px;">📅</div>
+          <h1 style="margin:0;color:#fff;font-size:26px;font-weight:700;">Booking Received</h1>
+          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Pawtner Hope Foundation</p>
+        </td></tr>
+        <!-- Body -->
+        <tr><td style="padding:40px 48px;">
+          <h2 style="margin:0 0 16px;color:#2c2416;font-size:22px;">Thanks, {{.OwnerName}}!</h2>
+          <p style="margin:0 0 24px;color:#555;font-size:15px;line-height:1.7;">We've received your booking for <strong style="color:#b8844f;">{{.ServiceName}}</strong>. Here are the details for your records.</p>
+          <table width="100%" cellpadding="0" cellspacing="0" style="border:1px solid #eee;border-radius:8px;overflow:hidden;">
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;width:120px;">Booking ID</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;font-family:monospace;">{{.BookingID}}</td></tr>
+            <tr><td style="padding:10px 16px;color:#888;font-size:13px;">Service</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.ServiceName}}</td></tr>
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;">Date</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Date}}</td></tr>
+            <tr><td style="padding:10px 16px;color:#888;font-size:13px;">Time</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;">{{.Time}}</td></tr>
+            <tr style="background:#f9f9f9;"><td style="padding:10px 16px;color:#888;font-size:13px;">Price</td><td style="padding:10px 16px;color:#2c2416;font-size:13px;font-weight:600;">₹{{.Price}}</td></tr>
+          </table>
+        </td></tr>
+        <!-- Footer -->
+        <tr><td style="background:#f5f0eb;padding:24px 48px;text-align:center;">
+          <p style="margin:0 0 6px;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation</p>
+          <p style="margin:0;color:#bbb;font-size:12px;">Need to change your booking? Email us at pawtnerhopefoundation@gmail.com</p>
+        </td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body></html>`
+
+// Email templates are parsed once at startup so a syntax error fails the boot
+// rather than silently dropping emails at send time.
+var (
+	welcomeEmailTmpl = template.Must(template.New("welcome").Parse(welcomeEmailTpl))
+	receiptEmailTmpl = template.Must(template.New("receipt").Parse(receiptEmailTpl))
+	otpEmailTmpl     = template.Must(template.New("otp").Parse(otpEmailTpl))
+
+	adoptionApprovedTmpl    = template.Must(template.New("adoptionApproved").Parse(adoptionApprovedTpl))
+	bookingConfirmationTmpl = template.Must(template.New("bookingConfirmation").Parse(bookingConfirmationTpl))
+)
+
+// Plain-text fallbacks used when an HTML template fails to render.
+
+func welcomeEmailText(data map[string]string) string {
+	return fmt.Sprintf("Welcome to Pawtner Hope Foundation, %s!\n\n"+
+		"Your account (%s) was created on %s. You can now browse pets for adoption and support our work with a donation.\n\n"+
+		"If you didn't create this account, please ignore this email.",
+		data["Username"], data["Email"], data["Date"])
+}
+
+func receiptEmailText(data map[string]string) string {
+	dedication := ""
+	if data["Dedication"] != "" {
+		dedication = "Dedication: Given " + data["Dedication"] + "\n"
+	}
+	return fmt.Sprintf("Thank you, %s!\n\n"+
+		"Amount received: Rs. %s\nReceipt No.: %s\nDonation ID: %s\nUPI Txn / UTR: %s\nDate: %s\n%s\n"+
+		"This is an official receipt for your tax records. Please save this email.\n"+
+		"Questions? Email us at pawtnerhopefoundation@gmail.com",
+		data["DonorName"], data["Amount"], data["ReceiptID"], data["DonationID"], data["TransactionID"], data["Date"], dedication)
+}
+
+func otpEmailText(data map[string]string) string {
+	return fmt.Sprintf("Hi %s,\n\nYour Pawtner Hope verification code is: %s\n\n"+
+		"This code expires in 5 minutes. If you didn't request this, you can safely ignore this email.",
+		data["Username"], data["Code"])
+}
+
+func adoptionApprovedText(data map[string]string) string {
+	return fmt.Sprintf("Congratulations, %s!\n\n"+
+		"Your adoption inquiry (%s) for %s was approved on %s. Our team will be in touch shortly "+
+		"to arrange the adoption fee and a time for you to bring %s home.\n\n"+
+		"Questions? Email us at pawtnerhopefoundation@gmail.com",
+		data["AdopterName"], data["InquiryID"], data["PetName"], data["Date"], data["PetName"])
+}
+
+func bookingConfirmationText(data map[string]string) string {
+	return fmt.Sprintf("Thanks, %s!\n\n"+
+		"We've received your booking %s for %s on %s at %s. Price: ₹%s.\n\n"+
+		"Need to change your booking? Email us at pawtnerhopefoundation@gmail.com",
+		data["OwnerName"], data["BookingID"], data["ServiceName"], data["Date"], data["Time"], data["Price"])
+}
+
+// composeEmail renders an HTML email into a notification job, falling back to
+// the plain-text body if the template fails so the recipient still hears from us.
+func composeEmail(to, subject, jobType string, tpl *template.Template, data map[string]string, fallback func(map[string]string) string) NotificationJob {
+	job := NotificationJob{To: to, Subject: subject, JobType: jobType}
+	html, err := renderTemplate(tpl, data)
+	if err != nil {
+		log.Printf("[EMAIL] Failed to render %s template, sending plain text instead: %v", tpl.Name(), err)
+		job.Body = fallback(data)
+		job.PlainText = true
+		return job
+	}
+	job.Body = html
+	return job
+}
+
+// renderTemplate executes a parsed HTML template with the given data.
+func renderTemplate(tpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SendEmail sends an HTML email via Gmail SMTP.
+func SendEmail(to, subject, htmlBody string) error {
+	return sendMail(to, subject, "text/html", htmlBody)
+}
+
+// SendTextEmail sends a plain-text email via Gmail SMTP.
+func SendTextEmail(to, subject, textBody string) error {
+	return sendMail(to, subject, "text/plain", textBody)
+}
+
+func sendMail(to, subject, contentType, body string) error {
+	if to == "" || subject == "" {
+		return ErrEmailFailed
+	}
+	if emailShouldFail.Load() {
+		return ErrEmailFailed
+	}
+	if smtpUser == "" || smtpPass == "" {
+		log.Printf("[EMAIL-SKIP] SMTP not configured. To: %s | Subject: %s", to, subject)
+		return nil
+	}
+
+	header := fmt.Sprintf(
+		"From: Pawtner Hope Foundation <%s>\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s; charset=UTF-8\r\n\r\n",
+		smtpUser, to, subject, contentType,
+	)
+	message := []byte(header + body)
+
+	auth := smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
+	addr := smtpHost + ":" + smtpPort
+	var err error
+	if smtpRequireTLS {
+		tlsConfig := &tls.Config{ServerName: smtpHost, MinVersion: smtpTLSMinVersion}
+		err = sendMailRequireTLS(addr, tlsConfig, auth, smtpUser, []string{to}, message)
+	} else {
+		err = smtp.SendMail(addr, auth, smtpUser, []string{to}, message)
+	}
+	if err != nil {
+		log.Printf("[EMAIL-ERROR] To: %s | %v", to, err)
+		return fmt.Errorf("%w: %v", ErrEmailFailed, err)
+	}
+	log.Printf("[EMAIL-SENT] To: %s | Subject: %s", to, subject)
+	return nil
+}
+
+// sendMailRequireTLS is smtp.SendMail without the plaintext fallback: it fails
+// if the server doesn't offer STARTTLS or its certificate doesn't verify, so
+// credentials never cross the wire unencrypted.
+func sendMailRequireTLS(addr string, tlsConfig *tls.Config, auth smtp.Auth, from string, to []string, msg []byte) error {
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return errors.New("smtp server does not support STARTTLS")
+	}
+	if err := c.StartTLS(tlsConfig); err != nil {
+		return fmt.Errorf("starttls: %w", err)
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func SendEmailWithRetry(to, subject, body string, maxRetries int) error {
+	return retryEmail(func() error { return SendEmail(to, subject, body) }, to, maxRetries)
+}
+
+// deliverNotification sends a queued job in the format it was composed in.
+func deliverNotification(job NotificationJob, maxRetries int) error {
+	if job.PlainText {
+		return retryEmail(func() error { return SendTextEmail(job.To, job.Subject, job.Body) }, job.To, maxRetries)
+	}
+	return SendEmailWithRetry(job.To, job.Subject, job.Body, maxRetries)
+}
+
+func retryEmail(send func() error, to string, maxRetries int) error {
+	var lastErr error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if err := send(); err != nil {
+			lastErr = err
+			log.Printf("[EMAIL] Attempt %d/%d failed for %s: %v", attempt, maxRetries, to, err)
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("email failed after %d attempts: %w", maxRetries, lastErr)
+}
+
+// sendWelcomeEmail renders and queues the welcome email.
+func sendWelcomeEmail(user *User) {
+	job := composeEmail(user.Email, "Welcome to Pawtner Hope Foundation 🐾", "welcome", welcomeEmailTmpl, map[string]string{
+		"Username": user.Username,
+		"Email":    user.Email,
+		"Date":     displayTime(user.CreatedAt, displayDateLayout),
+	}, welcomeEmailText)
+	enqueueNotification(job)
+}
+
+// sendDonationReceipt renders and queues the donation receipt email.
+func sendDonationReceipt(donation Donation, receipt Receipt) {
+	job := composeEmail(donation.DonorEmail, "Donation Receipt — Pawtner Hope Foundation 🐾", "receipt", receiptEmailTmpl, map[string]string{
+		"DonorName":     donation.DonorName,
+		"DonorEmail":    donation.DonorEmail,
+		"Amount":        fmt.Sprintf("%.2f", donation.Amount),
+		"ReceiptID":     receipt.ReceiptID,
+		"DonationID":    donation.ID,
+		"TransactionID": donation.TransactionID,
+		"Date":          displayTime(donation.CreatedAt, displayDateTimeLayout),
+		"Dedication":    dedicationText(donation),
+	}, receiptEmailText)
+	enqueueNotification(job)
+}
+
+// sendAdoptionApprovalEmail congratulates the adopter once their inquiry is approved.
+func sendAdoptionApprovalEmail(inquiry AdoptionInquiry, pet Pet) {
+	petName := pet.Name
+	if petName == "" {
+		petName = "your new companion"
+	}
+	job := composeEmail(inquiry.Email, "Your Adoption Is Approved — Pawtner Hope Foundation 🐾", "adoption-approved", adoptionApprovedTmpl, map[string]string{
+		"AdopterName": inquiry.AdopterName,
+		"PetName":     petName,
+		"InquiryID":   inquiry.ID,
+		"Date":        displayTime(time.Now(), displayDateLayout),
+	}, adoptionApprovedText)
+	enqueueNotification(job)
+}
+
+// sendBookingConfirmation sends the owner the details of a booking they just made.
+func sendBookingConfirmation(b ServiceBooking, svc Service) {
+	date, slot := b.Date, b.Time
+	if date == "" {
+		date = "To be confirmed"
+	}
+	if slot == "" {
+		slot = "To be confirmed"
+	}
+	job := composeEmail(b.Email, "Booking Received: "+svc.Name+" — Pawtner Hope Foundation 🐾", "booking-confirmation", bookingConfirmationTmpl, map[string]string{
+		"OwnerName":   b.OwnerName,
+		"BookingID":   b.ID,
+		"ServiceName": svc.Name,
+		"Date":        date,
+		"Time":        slot,
+		"Price":       fmt.Sprintf("%.2f", svc.Price),
+	}, bookingConfirmationText)
+	enqueueNotification(job)
+}
+
+// sendDedicationNotice lets the honoree know a gift was made in their name.
+// It is only sent once the payment has been confirmed, and at most
+// dedicationNoticeLimit times a day per donor and per honoree, since the
+// donor picks both the recipient and part of the text.
+func sendDedicationNotice(donation Donation) {
+	if donation.Status != "Completed" || donation.HonoreeEmail == "" || donation.DedicationType == "" {
+		return
+	}
+	now := time.Now()
+	if ok, _ := dedicationLimiter.allow("donor:"+strings.ToLower(donation.DonorEmail), now); !ok {
+		log.Printf("[WARN] Dedication notice for %s skipped: donor %s over the daily limit", donation.ID, donation.DonorEmail)
+		return
+	}
+	if ok, _ := dedicationLimiter.allow("honoree:"+donation.HonoreeEmail, now); !ok {
+		log.Printf("[WARN] Dedication notice for %s skipped: honoree over the daily limit", donation.ID)
+		return
+	}
+
+	dedication := dedicationText(donation)
+	job := NotificationJob{
+		To:      donation.HonoreeEmail,
+		Subject: "A gift was made " + dedication + " — Pawtner Hope Foundation 🐾",
+		Body: fmt.Sprintf("Hello,\n\n%s has made a donation to Pawtner Hope Foundation %s.\n\n"+
+			"Their gift helps us rescue, care for, and re-home abandoned pets.\n\nWith gratitude,\nPawtner Hope Foundation",
+			singleLine(donation.DonorName, maxDedicationName), dedication),
+		JobType:   "dedication",
+		PlainText: true,
+	}
+	enqueueNotification(job)
+}
+
+// ── SMS ───────────────────────────────────────────────────────────────────────
+
+// SMSSender delivers a text message to a phone number.
+type SMSSender interface {
+	Send(to, message string) error
+}
+
+// logSMSSender is the default sender: it only logs, so nothing leaves the box
+// until a real provider is configured.
+type logSMSSender struct{}
+
+func (logSMSSender) Send(to, message string) error {
+	log.Printf("[SMS-SKIP] SMS provider not configured. To: %s | %s", to, message)
+	return nil
+}
+
+// twilioSMSSender sends messages through Twilio's REST API.
+type twilioSMSSender struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	Client     *http.Client
+}
+
+func (t twilioSMSSender) Send(to, message string) error {
+	endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", t.AccountSID)
+	form := url.Values{"To": {to}, "From": {t.From}, "Body": {message}}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("twilio returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendDonationSMS texts the donor a short thank-you.
+func sendDonationSMS(donation Donation) {
+	message := fmt.Sprintf("Thank you %s for your donation of Rs. %.2f to Pawtner Hope Foundation! Ref: %s",
+		donation.DonorName, donation.Amount, donation.ID)
+	if err := smsSender.Send(donation.DonorPhone, message); err != nil {
+		log.Printf("[SMS-ERROR] To: %s | %v", donation.DonorPhone, err)
+		return
+	}
+	log.Printf("[SMS-SENT] Donation thank-you to %s", donation.DonorPhone)
+}
+
+// ── CAPTCHA ───────────────────────────────────────────────────────────────────
+
+// CaptchaVerifier checks a client-supplied CAPTCHA token with the provider.
+type CaptchaVerifier interface {
+	Verify(token, remoteIP string) (bool, error)
+}
+
+// Site-verify endpoints. reCAPTCHA and hCaptcha share the same request and
+// response shape, so one verifier covers both.
+const (
+	recaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+	hcaptchaVerifyURL  = "https://hcaptcha.com/siteverify"
+)
+
+// siteVerifyCaptcha posts the token to a reCAPTCHA/hCaptcha siteverify endpoint.
+type siteVerifyCaptcha struct {
+	Secret    string
+	VerifyURL string
+	Client    *http.Client
+}
+
+func (c siteVerifyCaptcha) Verify(token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {c.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	resp, err := c.Client.PostForm(c.VerifyURL, form)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("captcha provider returned %s", resp.Status)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Success, nil
+}
+
+// checkCaptcha verifies token when CAPTCHA is enabled, writing a 400 and
+// returning false if it is missing or rejected.
+func checkCaptcha(w http.ResponseWriter, r *http.Request, token string) bool {
+	if !captchaEnabled {
+		return true
+	}
+	if token == "" {
+		respondError(w, http.StatusBadRequest, "CAPTCHA token is required")
+		return false
+	}
+	if captchaVerifier == nil {
+		log.Printf("[CAPTCHA-ERROR] CAPTCHA enabled but no verifier configured")
+		respondError(w, http.StatusBadRequest, "CAPTCHA verification failed")
+		return false
+	}
+	remoteIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	ok, err := captchaVerifier.Verify(token, remoteIP)
+	if err != nil {
+		log.Printf("[CAPTCHA-ERROR] %v", err)
+	}
+	if !ok {
+		respondError(w, http.StatusBadRequest, "CAPTCHA verification failed")
+		return false
+	}
+	return true
+}
+
+// decodeForm decodes a public form's JSON body into v and reports whether the
+// honeypot field was filled in.
+func decodeForm(r *http.Request, v interface{}) (trapped bool, err error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return false, err
+	}
+	if honeypotField == "" {
+		return false, nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return false, nil
+	}
+	raw, ok := fields[honeypotField]
+	if !ok || string(raw) == "null" {
+		return false, nil
+	}
+	var value string
+	if json.Unmarshal(raw, &value) == nil && strings.TrimSpace(value) == "" {
+		return false, nil
+	}
+	return true, nil
+}
+
+// respondHoneypot logs a trapped submission and answers as if it succeeded,
+// so the bot has no reason to retry.
+func respondHoneypot(w http.ResponseWriter, r *http.Request, form string) {
+	log.Printf("[SPAM] Honeypot %q filled on %s form from %s; dropped", honeypotField, form, r.RemoteAddr)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Thank you! Your submission has been received.",
+	})
+}
+
+// ── MongoDB helpers ───────────────────────────────────────────────────────────
+
+func petsColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("pets")
+}
+func usersColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("users")
+}
+func donationsColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("donations")
+}
+func inquiriesColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("inquiries")
+}
+func testimonialsColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("testimonials")
+}
+func volunteersColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("volunteers")
+}
+func fosterColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("foster")
+}
+func eventsColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("events")
+}
+func failedLoginsColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("failedLogins")
+}
+func packagesColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("packages")
+}
+func countersColl() *mongo.Collection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection("counters")
+}
+
+// seqCounter is a persisted ID sequence, so IDs freed by deletions are never
+// handed out again after a restart.
+type seqCounter struct {
+	ID  string `json:"id"`
+	Seq int    `json:"seq"`
+}
+
+// docCollection is the subset of *mongo.Collection used by the sync helpers,
+// so tests can substitute an in-memory fake.
+type docCollection interface {
+	ReplaceOne(ctx context.Context, filter any, replacement any, opts ...options.Lister[options.ReplaceOptions]) (*mongo.UpdateResult, error)
+	DeleteOne(ctx context.Context, filter any, opts ...options.Lister[options.DeleteOneOptions]) (*mongo.DeleteResult, error)
+}
+
+// mongoWritePolicy controls how long a sync may take and how durable it must be.
+type mongoWritePolicy struct {
+	Timeout      time.Duration
+	WriteConcern *writeconcern.WriteConcern
+}
+
+// defaultWritePolicy applies to any collection without an explicit entry in mongoWritePolicies.
+var defaultWritePolicy = mongoWritePolicy{Timeout: 5 * time.Second}
+
+// writeCollection opens a collection configured with the given write concern.
+// Returns nil when MongoDB is not configured.
+var writeCollection = func(name string, wc *writeconcern.WriteConcern) docCollection {
+	if mongoDB == nil {
+		return nil
+	}
+	return mongoDB.Collection(name, options.Collection().SetWriteConcern(wc))
+}
+
+func writePolicy(collection string) mongoWritePolicy {
+	if policy, ok := mongoWritePolicies[collection]; ok {
+		return policy
+	}
+	return defaultWritePolicy
+}
+
+// parseWriteConcern accepts "majority", "majority+journal", "journaled" or a node count.
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "majority":
+		return writeconcern.Majority(), nil
+	case "majority+journal":
+		journal := true
+		return &writeconcern.WriteConcern{W: "majority", Journal: &journal}, nil
+	case "journaled":
+		return writeconcern.Journaled(), nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid write concern %q", value)
+	}
+	return &writeconcern.WriteConcern{W: n}, nil
+}
+
+// loadMongoWritePolicies applies MONGO_TIMEOUT_<COLLECTION> and
+// MONGO_WRITE_CONCERN_<COLLECTION> overrides on top of the defaults.
+func loadMongoWritePolicies() {
+	for name, policy := range mongoWritePolicies {
+		key := strings.ToUpper(name)
+		if v := os.Getenv("MONGO_TIMEOUT_" + key); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				policy.Timeout = d
+			} else {
+				log.Printf("[CONFIG] Ignoring invalid MONGO_TIMEOUT_%s=%q", key, v)
+			}
+		}
+		if v := os.Getenv("MONGO_WRITE_CONCERN_" + key); v != "" {
+			if wc, err := parseWriteConcern(v); err == nil {
+				policy.WriteConcern = wc
+			} else {
+				log.Printf("[CONFIG] Ignoring MONGO_WRITE_CONCERN_%s: %v", key, err)
+			}
+		}
+		mongoWritePolicies[name] = policy
+	}
+}
+
+// mongoSyncJob is a single background write, kept around so it can be retried.
+type mongoSyncJob struct {
+	Collection string
+	ID         string
+	Doc        interface{} // nil for deletes
+	Delete     bool
+	Attempts   int
+	LastError  string
+	FailedAt   time.Time
+	Version    uint64 // order of this write among writes to the same document
+}
+
+// syncVersions remembers the newest write dispatched for each document, so
+// a retry carrying an older snapshot can tell it has been superseded.
+var syncVersions = struct {
+	sync.Mutex
+	seq    uint64
+	latest map[string]uint64
+}{latest: make(map[string]uint64)}
+
+func (j mongoSyncJob) key() string { return j.Collection + "/" + j.ID }
+
+// nextSyncVersion stamps a new write to key as the newest one.
+func nextSyncVersion(key string) uint64 {
+	syncVersions.Lock()
+	defer syncVersions.Unlock()
+	syncVersions.seq++
+	syncVersions.latest[key] = syncVersions.seq
+	return syncVersions.seq
+}
+
+// superseded reports whether a newer write to the same document has been
+// dispatched since j, which makes retrying j pointless or harmful.
+func (j mongoSyncJob) superseded() bool {
+	syncVersions.Lock()
+	defer syncVersions.Unlock()
+	return syncVersions.latest[j.key()] != j.Version
+}
+
+// run performs the write once, honouring the collection's write policy. It
+// waits for a free slot first so bursts of syncs can't exhaust the pool.
+func (j mongoSyncJob) run() error {
+	policy := writePolicy(j.Collection)
+	coll := writeCollection(j.Collection, policy.WriteConcern)
+	if coll == nil {
+		return nil
+	}
+	slots := mongoSlots
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), policy.Timeout)
+	defer cancel()
+	if j.Delete {
+		_, err := coll.DeleteOne(ctx, bson.M{"id": j.ID})
+		return err
+	}
+	opts := options.Replace().SetUpsert(true)
+	_, err := coll.ReplaceOne(ctx, bson.M{"id": j.ID}, j.Doc, opts)
+	return err
+}
+
+// dispatchSync runs a write in the background and hands it to the retry
+// worker if the first attempt fails.
+func dispatchSync(job mongoSyncJob) {
+	if writeCollection(job.Collection, nil) == nil {
+		return // MongoDB not configured
+	}
+	job.Version = nextSyncVersion(job.key())
+	go func() {
+		job.Attempts++
+		if err := job.run(); err != nil {
+			log.Printf("[MONGO] sync %s/%s failed, queueing retry: %v", job.Collection, job.ID, err)
+			job.LastError = err.Error()
+			select {
+			case mongoRetryCh <- job:
+			default:
+				recordFailedSync(job)
+			}
+		}
+	}()
+}
+
+// recordFailedSync keeps a write that exhausted its retries for manual reconciliation.
+func recordFailedSync(job mongoSyncJob) {
+	job.FailedAt = time.Now()
+	mu.Lock()
+	failedSyncs = append(failedSyncs, job)
+	mu.Unlock()
+	log.Printf("[MONGO] giving up on %s/%s after %d attempts: %s", job.Collection, job.ID, job.Attempts, job.LastError)
+}
+
+// mongoRetryWorker retries failed syncs with linear backoff. Each job waits
+// on its own timer, so one failing write doesn't hold up the rest.
+func mongoRetryWorker(jobs <-chan mongoSyncJob) {
+	for job := range jobs {
+		scheduleRetry(job)
+	}
+}
+
+// scheduleRetry runs the next attempt at job after its backoff.
+func scheduleRetry(job mongoSyncJob) {
+	time.AfterFunc(time.Duration(job.Attempts)*mongoRetryBackoff, func() { retrySync(job) })
+}
+
+// retrySync makes one more attempt at job, unless a newer write to the same
+// document has replaced it, and reschedules or gives up on failure.
+func retrySync(job mongoSyncJob) {
+	if job.superseded() {
+		log.Printf("[MONGO] dropping retry of %s/%s: superseded by a newer write", job.Collection, job.ID)
+		return
+	}
+	job.Attempts++
+	err := job.run()
+	if err == nil {
+		log.Printf("[MONGO] sync %s/%s succeeded on attempt %d", job.Collection, job.ID, job.Attempts)
+		return
+	}
+	job.LastError = err.Error()
+	if job.Attempts > mongoMaxRetries {
+		recordFailedSync(job)
+		return
+	}
+	scheduleRetry(job)
+}
+
+// upsertDoc replaces (or inserts) the document with the given id in the background.
+func upsertDoc(collection, id string, doc interface{}) {
+	dispatchSync(mongoSyncJob{Collection: collection, ID: id, Doc: doc})
+}
+
+// removeDoc deletes the document with the given id in the background.
+func removeDoc(collection, id string) {
+	dispatchSync(mongoSyncJob{Collection: collection, ID: id, Delete: true})
+}
+
+func syncPetToDB(pet Pet) {
+	upsertDoc("pets", pet.ID, pet)
+}
+
+func deletePetFromDB(petID string) {
+	removeDoc("pets", petID)
+}
+
+func syncPetSeqToDB(seq int) {
+	upsertDoc("counters", "pets", seqCounter{ID: "pets", Seq: seq})
+}
+
+func syncUserToDB(user User) {
+	upsertDoc("users", user.ID, user)
+}
+
+func syncDonationToDB(donation Donation) {
+	upsertDoc("donations", donation.ID, donation)
+}
+
+func syncInquiryToDB(inquiry AdoptionInquiry) {
+	upsertDoc("inquiries", inquiry.ID, inquiry)
+}
+
+func syncTestimonialToDB(testimonial Testimonial) {
+	upsertDoc("testimonials", testimonial.ID, testimonial)
+}
+
+func syncVolunteerToDB(volunteer Volunteer) {
+	upsertDoc("volunteers", volunteer.ID, volunteer)
+}
+
+func syncFosterToDB(app FosterApplication) {
+	upsertDoc("foster", app.ID, app)
+}
+
+func syncEventToDB(event Event) {
+	upsertDoc("events", event.ID, event)
+}
+
+func syncFailedLoginToDB(entry FailedLogin) {
+	upsertDoc("failedLogins", entry.ID, entry)
+}
+
+func syncPackageToDB(pkg ServicePackage) {
+	upsertDoc("packages", pkg.ID, pkg)
+}
+
+// loadFromMongoDB seeds in-memory data from MongoDB collections on startup.
+// If a collection is empty it falls back to whatever initializeData() put there.
+func loadFromMongoDB() {
+	if mongoDB == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Pets
+	if cur, err := petsColl().Find(ctx, bson.D{}); err == nil {
+		var dbPets []Pet
+		if err := cur.All(ctx, &dbPets); err == nil && len(dbPets) > 0 {
+			mu.Lock()
+			pets = dbPets
+			petsByID = make(map[string]*Pet)
+			statusCounts = make(map[string]int)
+			petsByBreed = make(map[string][]string)
+			for i := range pets {
+				petsByID[pets[i].ID] = &pets[i]
+				statusCounts[pets[i].Status]++
+				petsByBreed[pets[i].Breed] = append(petsByBreed[pets[i].Breed], pets[i].ID)
+				var n int
+				if _, err := fmt.Sscanf(pets[i].ID, "pet-%d", &n); err == nil && n > petSeq {
+					petSeq = n
+				}
+			}
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d pets", len(pets))
+		} else if err == nil {
+			// Collection is empty — push sample data to MongoDB so it persists
+			log.Println("[MONGO] No pets in DB, seeding sample data")
+			for _, p := range pets {
+				syncPetToDB(p)
+			}
+		}
+	}
+
+	// The pet sequence outlives the pets it numbered; the surviving IDs
+	// above only cover a counter that was never written.
+	var petCounter seqCounter
+	err := countersColl().FindOne(ctx, bson.M{"id": "pets"}).Decode(&petCounter)
+	mu.Lock()
+	if err == nil && petCounter.Seq > petSeq {
+		petSeq = petCounter.Seq
+	}
+	seq := petSeq
+	mu.Unlock()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		syncPetSeqToDB(seq)
+	}
+
+	// Users
+	if cur, err := usersColl().Find(ctx, bson.D{}); err == nil {
+		var dbUsers []User
+		if err := cur.All(ctx, &dbUsers); err == nil && len(dbUsers) > 0 {
+			mu.Lock()
+			users = dbUsers
+			usersByEmail = make(map[string]*User)
+			hasAdmin := false
+			for i := range users {
+				usersByEmail[users[i].Email] = &users[i]
+				// Roles edited directly in Mongo may be junk; fall back to "user".
+				if err := setRole(&users[i], users[i].Role); err != nil {
+					log.Printf("[MONGO] User %s has invalid role %q, treating as user", users[i].ID, users[i].Role)
+					setRole(&users[i], "user")
+				}
+				if users[i].IsAdmin {
+					hasAdmin = true
+				}
+			}
+			// Always ensure a default admin account exists
+			if !hasAdmin {
+				adminUser := User{
+					ID:        "usr-admin",
+					Email:     "admin@pawtner.com",
+					Username:  "admin",
+					Password:  mustHashPassword("admin123"),
+					Role:      "admin",
+					IsAdmin:   true,
+					CreatedAt: time.Now(),
+					IsActive:  true,
+				}
+				users = append(users, adminUser)
+				usersByEmail[adminUser.Email] = &users[len(users)-1]
+				syncUserToDB(adminUser)
+			}
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d users", len(users))
+		}
+	}
+
+	// Donations
+	if cur, err := donationsColl().Find(ctx, bson.D{}); err == nil {
+		var dbDonations []Donation
+		if err := cur.All(ctx, &dbDonations); err == nil && len(dbDonations) > 0 {
+			mu.Lock()
+			donations = dbDonations
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d donations", len(donations))
+		}
+	}
+
+	// Inquiries
+	if cur, err := inquiriesColl().Find(ctx, bson.D{}); err == nil {
+		var dbInquiries []AdoptionInquiry
+		if err := cur.All(ctx, &dbInquiries); err == nil && len(dbInquiries) > 0 {
+			mu.Lock()
+			inquiries = dbInquiries
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d inquiries", len(inquiries))
+		}
+	}
+
+	// Testimonials
+	if cur, err := testimonialsColl().Find(ctx, bson.D{}); err == nil {
+		var dbTestimonials []Testimonial
+		if err := cur.All(ctx, &dbTestimonials); err == nil && len(dbTestimonials) > 0 {
+			mu.Lock()
+			testimonials = dbTestimonials
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d testimonials", len(testimonials))
+		}
+	}
+
+	// Volunteers
+	if cur, err := volunteersColl().Find(ctx, bson.D{}); err == nil {
+		var dbVolunteers []Volunteer
+		if err := cur.All(ctx, &dbVolunteers); err == nil && len(dbVolunteers) > 0 {
+			mu.Lock()
+			volunteers = dbVolunteers
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d volunteers", len(volunteers))
+		}
+	}
+
+	// Foster applications
+	if cur, err := fosterColl().Find(ctx, bson.D{}); err == nil {
+		var dbFoster []FosterApplication
+		if err := cur.All(ctx, &dbFoster); err == nil && len(dbFoster) > 0 {
+			mu.Lock()
+			fosterApps = dbFoster
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d foster applications", len(fosterApps))
+		}
+	}
+
+	// Events
+	if cur, err := eventsColl().Find(ctx, bson.D{}); err == nil {
+		var dbEvents []Event
+		if err := cur.All(ctx, &dbEvents); err == nil && len(dbEvents) > 0 {
+			mu.Lock()
+			events = dbEvents
+			for _, e := range dbEvents {
+				var n int
+				if _, err := fmt.Sscanf(e.ID, "evt-%d", &n); err == nil && n > eventSeq {
+					eventSeq = n
+				}
+			}
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d events", len(events))
+		}
+	}
+
+	// Service packages
+	if cur, err := packagesColl().Find(ctx, bson.D{}); err == nil {
+		var dbPackages []ServicePackage
+		if err := cur.All(ctx, &dbPackages); err == nil && len(dbPackages) > 0 {
+			mu.Lock()
+			packages = dbPackages
+			for _, p := range dbPackages {
+				var n int
+				if _, err := fmt.Sscanf(p.ID, "pkg-%d", &n); err == nil && n > packageSeq {
+					packageSeq = n
+				}
+			}
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d service packages", len(packages))
+		}
+	}
+
+	// Failed logins
+	if cur, err := failedLoginsColl().Find(ctx, bson.D{}); err == nil {
+		var dbFailed []FailedLogin
+		if err := cur.All(ctx, &dbFailed); err == nil && len(dbFailed) > 0 {
+			sort.Slice(dbFailed, func(i, j int) bool { return dbFailed[i].Time.Before(dbFailed[j].Time) })
+			mu.Lock()
+			failedLogins = dbFailed
+			for _, entry := range dbFailed {
+				var n int
+				if _, err := fmt.Sscanf(entry.ID, "fl-%d", &n); err == nil && n > failedLoginSeq {
+					failedLoginSeq = n
+				}
+			}
+			mu.Unlock()
+			log.Printf("[MONGO] Loaded %d failed logins", len(failedLogins))
+		}
+	}
+}
+
+// reapPendingRegistrations drops expired pending registrations. When reminders
+// are enabled, a registration nearing expiry gets one reminder email first.
+func reapPendingRegistrations(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for email, pending := range pendingRegs {
+		if now.After(pending.ExpiresAt) {
+			delete(pendingRegs, email)
+			log.Printf("[INFO] Pending registration for %s expired", email)
+			continue
+		}
+		if !otpReminderEnabled || pending.Reminded || pending.ExpiresAt.Sub(now) > otpReminderWindow {
+			continue
+		}
+		pending.Reminded = true
+		job := NotificationJob{
+			To:      email,
+			Subject: "Your Pawtner Hope verification code is about to expire",
+			Body: fmt.Sprintf("Hi %s,\n\nYour verification code %s expires at %s. "+
+				"Enter it soon to finish creating your account.\n\nPawtner Hope Foundation",
+				pending.Username, pending.Code, displayTime(pending.ExpiresAt, "3:04 PM MST")),
+			JobType:   "otp-reminder",
+			PlainText: true,
+		}
+		enqueueNotification(job)
+	}
+}
+
+type idempotencyEntry struct {
+	PetID     string
+	CreatedAt time.Time
+}
+
+// reapIdempotencyKeys forgets Idempotency-Keys older than idempotencyKeyTTL.
+func reapIdempotencyKeys(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for key, entry := range idempotencyKeys {
+		if now.Sub(entry.CreatedAt) > idempotencyKeyTTL {
+			delete(idempotencyKeys, key)
+		}
+	}
+}
+
+// staleDonations returns the indexes of Pending donations created before cutoff.
+func staleDonations(list []Donation, cutoff time.Time) []int {
+	var stale []int
+	for i, d := range list {
+		if d.Status == "Pending" && d.CreatedAt.Before(cutoff) {
+			stale = append(stale, i)
+		}
+	}
+	return stale
+}
+
+// expireStaleDonations marks abandoned Pending donations as Expired so they
+// stop waiting for a confirmation that isn't coming.
+func expireStaleDonations(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, i := range staleDonations(donations, now.Add(-pendingDonationTTL)) {
+		donations[i].Status = "Expired"
+		log.Printf("[PAYMENT] Pending donation %s expired unconfirmed", donations[i].ID)
+		syncDonationToDB(donations[i])
+	}
+}
+
+func reaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reapPendingRegistrations(now)
+			reapIdempotencyKeys(now)
+			reapFeaturedPet(now)
+			expireStaleDonations(now)
+		}
+	}
+}
+
+// generateOTP returns a zero-padded 6-digit numeric code.
+func generateOTP() string {
+	return fmt.Sprintf("%06d", rand.Intn(10000000))
+}
+
+// ── OTP email template ────────────────────────────────────────────────────────
+
+const otpEmailTpl = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width,initial-scale=1"><title>Verify Email</title></head>
+<body style="margin:0;padding:0;background:#faf8f5;font-family:'Segoe UI',Arial,sans-serif;">
+  <table width="100%" cellpadding="0" cellspacing="0" style="background:#faf8f5;padding:40px 20px;">
+    <tr><td align="center">
+      <table width="600" cellpadding="0" cellspacing="0" style="background:#ffffff;border-radius:16px;overflow:hidden;box-shadow:0 4px 24px rgba(44,36,22,.08);">
+        <tr><td style="background:linear-gradient(135deg,#d4a574,#b8844f);padding:36px 48px;text-align:center;">
+          <div style="font-size:36px;margin-bottom:8px;">🐾</div>
+          <h1 style="margin:0;color:#fff;font-size:24px;font-weight:700;">Email Verification</h1>
+          <p style="margin:8px 0 0;color:rgba(255,255,255,.8);font-size:14px;">Pawtner Hope Foundation</p>
+        </td></tr>
+        <tr><td style="padding:40px 48px;text-align:center;">
+          <p style="margin:0 0 8px;color:#555;font-size:15px;line-height:1.7;">Hi <strong>{{.Username}}</strong>! Use the code below to verify your email address.</p>
+          <p style="margin:0 0 28px;color:#888;font-size:13px;">This code expires in <strong>5 minutes</strong>.</p>
+          <div style="display:inline-block;background:#fdf6ef;border:2px dashed #d4a574;border-radius:16px;padding:24px 48px;margin-bottom:28px;">
+            <p style="margin:0;font-size:42px;font-weight:800;letter-spacing:10px;color:#b8844f;font-family:monospace;">{{.Code}}</p>
+          </div>
+          <p style="margin:0;color:#aaa;font-size:12px;">If you didn't request this, you can safely ignore this email.</p>
+        </td></tr>
+        <tr><td style="background:#f5f0eb;padding:20px 48px;text-align:center;">
+          <p style="margin:0;color:#aaa;font-size:12px;">© 2024 Pawtner Hope Foundation</p>
+        </td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body></html>`
+
+// 5. FUNCTIONS AND ERROR HANDLING
+// SearchPets returns the requested 1-based page of pets matching query and
+// every filter, plus the total number of matches. Only the page is copied out;
+// a limit <= 0 returns all matches.
+func SearchPets(query string, filters []Filterable, page, limit int) ([]Pet, int, error) {
+	if query == "" && len(filters) == 0 {
+		return nil, 0, errors.New("search query or filters required")
+	}
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * limit
+
+	qLower := strings.ToLower(query)
+	result := make([]Pet, 0)
+	total := 0
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, p := range pets {
+		if query != "" &&
+			!strings.Contains(strings.ToLower(p.Name), qLower) &&
+			!strings.Contains(strings.ToLower(p.Species), qLower) &&
+			!strings.Contains(strings.ToLower(p.Breed), qLower) {
+			continue
+		}
+		if !matchesAll(p, filters) {
+			continue
+		}
+
+		if limit <= 0 || (total >= start && total < start+limit) {
+			result = append(result, p)
+		}
+		total++
+	}
+
+	return result, total, nil
+}
+
+// emailPacer spaces calls to wait so they happen at most perMinute times a
+// minute, across however many workers share it. now and sleep are swappable
+// so tests can run on a fake clock.
+type emailPacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+func newEmailPacer(perMinute int) *emailPacer {
+	p := &emailPacer{now: time.Now, sleep: time.Sleep}
+	if perMinute > 0 {
+		p.interval = time.Minute / time.Duration(perMinute)
+	}
+	return p
+}
+
+// wait blocks until the next send slot is free. The slot is claimed under the
+// lock and slept for outside it, so workers queue up one interval apart.
+func (p *emailPacer) wait() {
+	if p.interval == 0 {
+		return
+	}
+	p.mu.Lock()
+	now, delay := p.now(), time.Duration(0)
+	if now.Before(p.next) {
+		delay = p.next.Sub(now)
+		now = p.next
+	}
+	p.next = now.Add(p.interval)
+	p.mu.Unlock()
+
+	if delay > 0 {
+		p.sleep(delay)
+	}
+}
+
+// startEmailWorkers runs n email workers sharing jobs and pacer, counted in wg.
+func startEmailWorkers(n int, jobs <-chan NotificationJob, pacer *emailPacer, wg *sync.WaitGroup, deliver func(NotificationJob)) {
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			runEmailWorker(jobs, pacer, deliver)
+		}()
+	}
+}
+
+func runEmailWorker(jobs <-chan NotificationJob, pacer *emailPacer, deliver func(NotificationJob)) {
+	for job := range jobs {
+		pacer.wait()
+		deliver(job)
+	}
+}
+
+func paymentProcessor(donationQueue <-chan Donation, confirmations chan<- PaymentConfirmation) {
+	for donation := range donationQueue {
+		time.Sleep(50 * time.Millisecond)
+		confirmation := PaymentConfirmation{
+			DonationID:    donation.ID,
+			Success:       true,
+			TransactionID: fmt.Sprintf("txn-%d", time.Now().UnixNano()),
+		}
+		confirmations <- confirmation
+
+		// Only auto-send receipt for mobile UPI deeplink payments.
+		// Desktop donors request one via POST /api/donations/{id}/receipt.
+		if donation.PaymentViaDeeplink {
+			pendingNotifications.Add(1)
+			go func(d Donation) {
+				defer pendingNotifications.Done()
+				receipt := GenerateReceipt(d)
+				sendDonationReceipt(d, receipt)
+			}(donation)
+		} else {
+			log.Printf("[INFO] Desktop donation from %s — receipt not auto-sent (request required)", donation.DonorEmail)
+		}
+	}
+}
+
+func confirmationListener(confirmations <-chan PaymentConfirmation) {
+	for confirmation := range confirmations {
+		var completed *Donation
+		mu.Lock()
+		for i := range donations {
+			if donations[i].ID == confirmation.DonationID {
+				if confirmation.Success {
+					donations[i].Status = "Completed"
+					donations[i].TransactionID = confirmation.TransactionID
+					d := donations[i]
+					completed = &d
+				} else {
+					donations[i].Status = "Failed"
+				}
+				break
+			}
+		}
+		mu.Unlock()
+		log.Printf("[PAYMENT] Processed: %s - Success: %v", confirmation.DonationID, confirmation.Success)
+		if completed != nil {
+			publishEvent(EventDonationCompleted, completed.ID, *completed)
+			sendDedicationNotice(*completed)
+		}
+	}
+}
+
+// Domain event types published on the event bus.
+const (
+	EventPetAdopted        = "pet.adopted"
+	EventDonationCompleted = "donation.completed"
+)
+
+// eventBusBuffer is how many events a subscriber may fall behind before it
+// starts missing them.
+const eventBusBuffer = 64
+
+// DomainEvent is a change to shelter data that observers (live feeds,
+// webhooks, metrics) may want to react to. Data holds a copy of the entity.
+type DomainEvent struct {
+	Type     string      `json:"type"`
+	EntityID string      `json:"entityId"`
+	Data     interface{} `json:"data,omitempty"`
+	Time     time.Time   `json:"time"`
+}
+
+// EventBus fans each published event out to every subscriber. Publishing
+// never blocks: a subscriber whose buffer is full misses the event.
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers []chan DomainEvent
+}
+
+func newEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe returns a channel receiving every event published from now on.
+func (b *EventBus) Subscribe() <-chan DomainEvent {
+	ch := make(chan DomainEvent, eventBusBuffer)
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe stops delivery to ch and closes it.
+func (b *EventBus) Unsubscribe(ch <-chan DomainEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subscribers {
+		if sub == ch {
+			b.subscribers = slices.Delete(b.subscribers, i, i+1)
+			close(sub)
+			return
+		}
+	}
+}
+
+func (b *EventBus) Publish(event DomainEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subscribers {
+		select {
+		case sub <- event:
+		default:
+			log.Printf("[EVENTS] Subscriber is %d events behind, dropping %s %s", eventBusBuffer, event.Type, event.EntityID)
+		}
+	}
+}
+
+// publishEvent stamps and broadcasts a domain event. EventBus locks itself,
+// so callers publish after releasing mu rather than logging dropped events
+// from inside it.
+func publishEvent(eventType, entityID string, data interface{}) {
+	eventBus.Publish(DomainEvent{Type: eventType, EntityID: entityID, Data: data, Time: time.Now()})
+}
+
+// Sends still in flight onto notificationCh and paymentCh, so a channel is
+// closed only once nothing else will write to it.
+var (
+	pendingNotifications sync.WaitGroup
+	pendingPayments      sync.WaitGroup
+)
+
+// enqueueNotification hands job to the email worker without blocking the
+// caller. The channel is captured now, not when the goroutine runs.
+func enqueueNotification(job NotificationJob) {
+	ch := notificationCh
+	pendingNotifications.Add(1)
+	go func() {
+		defer pendingNotifications.Done()
+		ch <- job
+	}()
+}
+
+// enqueuePayment hands a donation to the payment processor without blocking.
+func enqueuePayment(donation Donation) {
+	ch := paymentCh
+	pendingPayments.Add(1)
+	go func() {
+		defer pendingPayments.Done()
+		ch <- donation
+	}()
+}
+
+// workerSet is one run of the background workers and the channels they read.
+type workerSet struct {
+	cancel        context.CancelFunc
+	notifications chan NotificationJob
+	payments      chan Donation
+	confirmations chan PaymentConfirmation
+
+	tickers, paymentWorkers, confirmationWorkers, emailWorkers sync.WaitGroup
+}
+
+// startWorkers runs the background workers on the current channels. The
+// ticker-driven ones return when ctx is cancelled; the channel readers return
+// once stop closes their channel.
+func startWorkers(ctx context.Context) *workerSet {
+	ctx, cancel := context.WithCancel(ctx)
+	ws := &workerSet{
+		cancel:        cancel,
+		notifications: notificationCh,
+		payments:      paymentCh,
+		confirmations: paymentConfirmCh,
+	}
+
+	// 11. GOROUTINES AND CHANNELS
+	pacer := newEmailPacer(emailRatePerMinute)
+	startEmailWorkers(max(emailWorkerCount, 1), ws.notifications, pacer, &ws.emailWorkers, func(job NotificationJob) {
+		deliverNotification(job, 3)
+	})
+	ws.paymentWorkers.Add(1)
+	go func() {
+		defer ws.paymentWorkers.Done()
+		paymentProcessor(ws.payments, ws.confirmations)
+	}()
+	ws.confirmationWorkers.Add(1)
+	go func() {
+		defer ws.confirmationWorkers.Done()
+		confirmationListener(ws.confirmations)
+	}()
+	go mongoRetryWorker(mongoRetryCh)
+
+	ws.tickers.Add(4)
+	go func() {
+		defer ws.tickers.Done()
+		weeklyReportWorker(ctx, weeklyReportInterval)
+	}()
+	go func() {
+		defer ws.tickers.Done()
+		reaper(ctx, reapInterval)
+	}()
+	go func() {
+		defer ws.tickers.Done()
+		tokenCleanupWorker(ctx, tokenCleanupInterval)
+	}()
+	go func() {
+		defer ws.tickers.Done()
+		staleListingWorker(ctx, staleListingInterval)
+	}()
+	return ws
+}
+
+// stop shuts the workers down in dependency order once the HTTP server has
+// stopped taking requests: the ticker workers first, then payments (whose
+// receipts become emails), then emails. Each channel is closed after its
+// senders finish and drained before moving on. It gives up when ctx expires
+// and reports how many queued jobs were drained. It must not be called while
+// handlers may still enqueue work, since sending on a closed channel panics.
+func (ws *workerSet) stop(ctx context.Context) (payments, emails int, err error) {
+	ws.cancel()
+	if err := waitContext(ctx, &ws.tickers); err != nil {
+		return 0, 0, err
+	}
+
+	if err := waitContext(ctx, &pendingPayments); err != nil {
+		return 0, 0, err
+	}
+	payments = len(ws.payments)
+	close(ws.payments)
+	if err := waitContext(ctx, &ws.paymentWorkers); err != nil {
+		return payments, 0, err
+	}
+	close(ws.confirmations)
+	if err := waitContext(ctx, &ws.confirmationWorkers); err != nil {
+		return payments, 0, err
+	}
+
+	if err := waitContext(ctx, &pendingNotifications); err != nil {
+		return payments, 0, err
+	}
+	emails = len(ws.notifications)
+	close(ws.notifications)
+	return payments, emails, waitContext(ctx, &ws.emailWorkers)
+}
+
+// waitContext waits for wg, giving up when ctx is done.
+func waitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HTTP Handlers
+
+// Panic recovery middleware
+func recoverPanic(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("[PANIC RECOVERED] %v for request %s %s", err, r.Method, r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": false,
+					"code":    "INTERNAL_ERROR",
+					"message": "Internal server error",
+				})
+			}
+		}()
+		next(w, r)
+	}
+}
+
+// 6. INTERFACE - http.HandlerFunc implements http.Handler
+func enableCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && isAllowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
+
+		if r.Method == "OPTIONS" {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(corsPreflightStatus)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func isAllowedOrigin(origin string) bool {
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// authenticate resolves the user behind the request's bearer token.
+func authenticate(r *http.Request) (*User, error) {
+	return ValidateToken(bearerToken(r))
+}
+
+// isAdminRequest reports whether the request carries a valid admin token.
+func isAdminRequest(r *http.Request) bool {
+	user, err := authenticate(r)
+	return err == nil && user.IsAdmin
+}
+
+// requireAdmin only lets requests through when the bearer token belongs to an admin.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := authenticate(r)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		if !user.IsAdmin {
+			respondError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Safe file serving with error handling
+func serveHTMLFile(filename string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			log.Printf("[ERROR] File not found: %s", filename)
+			http.Error(w, "Page not found", http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, filename)
+	}
+}
+
+// Safe JSON response with error handling
+func respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("[ERROR] Failed to encode JSON response: %v", err)
+	}
+}
+
+// parsePagination reads ?page= and ?limit=. Oversized limits are clamped to
+// maxPageSize rather than rejected; missing or invalid values use the defaults.
+// A negative page is an error.
+func parsePagination(r *http.Request) (page, limit int, err error) {
+	page, limit = 1, defaultPageSize
+	query := r.URL.Query()
+	if v, convErr := strconv.Atoi(query.Get("page")); convErr == nil {
+		if v < 0 {
+			return 0, 0, errors.New("page must not be negative")
+		}
+		if v > 0 {
+			page = v
+		}
+	}
+	if v, convErr := strconv.Atoi(query.Get("limit")); convErr == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return page, limit, nil
+}
+
+// paginate returns the items on the given 1-based page.
+func paginate[T any](items []T, page, limit int) []T {
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return make([]T, 0) // [] rather than null, even for a nil items
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// respondList writes one page of a list along with the effective paging metadata.
+func respondList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+	respondPage(w, paginate(items, page, limit), len(items), page, limit)
+}
+
+// respondPage writes an already-paged result; total is the unpaged count.
+func respondPage[T any](w http.ResponseWriter, result []T, total, page, limit int) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"count":   len(result),
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+		"data":    result,
+	})
+}
+
+// errorCodes gives clients a stable, machine-readable code for each domain error.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrInvalidCredentials, "INVALID_CREDENTIALS"},
+	{ErrUserAlreadyExists, "USER_EXISTS"},
+	{ErrTokenExpired, "TOKEN_EXPIRED"},
+	{ErrSessionIdle, "SESSION_IDLE"},
+	{ErrPetNotFound, "PET_NOT_FOUND"},
+	{ErrInvalidPayment, "INVALID_PAYMENT"},
+	{ErrEmailFailed, "EMAIL_FAILED"},
+	{ErrInquiryNotFound, "INQUIRY_NOT_FOUND"},
+	{ErrDonationNotFound, "DONATION_NOT_FOUND"},
+	{ErrServiceNotFound, "SERVICE_NOT_FOUND"},
+	{ErrInvalidCategory, "INVALID_CATEGORY"},
+	{ErrPackageNotFound, "PACKAGE_NOT_FOUND"},
+	{ErrUserNotFound, "USER_NOT_FOUND"},
+	{ErrInvalidRole, "INVALID_ROLE"},
+	{ErrInvalidRating, "INVALID_RATING"},
+	{ErrBookingNotFound, "BOOKING_NOT_FOUND"},
+	{ErrBookingCancelled, "BOOKING_CANCELLED"},
+	{ErrBookingCompleted, "BOOKING_COMPLETED"},
+	{ErrBookingConflict, "BOOKING_CONFLICT"},
+	{ErrBookingNotCompleted, "BOOKING_NOT_COMPLETED"},
+	{ErrBookingRated, "BOOKING_RATED"},
+	{ErrTestimonialNotFound, "TESTIMONIAL_NOT_FOUND"},
+	{ErrInvalidVolunteer, "INVALID_VOLUNTEER"},
+	{ErrInvalidInquiryStatus, "INVALID_INQUIRY_STATUS"},
+	{ErrFosterNotFound, "FOSTER_NOT_FOUND"},
+	{ErrRefreshTooEarly, "REFRESH_TOO_EARLY"},
+	{ErrEventNotFound, "EVENT_NOT_FOUND"},
+	{ErrInvalidEmail, "INVALID_EMAIL"},
+	{ErrServiceInUse, "SERVICE_IN_USE"},
+	{ErrInvalidEventTime, "INVALID_EVENT_TIME"},
+	{ErrDonationNotPending, "DONATION_NOT_PENDING"},
+	{ErrFeeAlreadyPaid, "FEE_ALREADY_PAID"},
+	{ErrDonationLinked, "DONATION_LINKED"},
+	{ErrPetAlreadyAdopted, "PET_ALREADY_ADOPTED"},
+	{ErrContactNotFound, "CONTACT_NOT_FOUND"},
+	{ErrWaitlistFull, "WAITLIST_FULL"},
+}
+
+// statusErrorCode is the fallback code for errors without a sentinel,
+// e.g. 404 -> "NOT_FOUND".
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return "RATE_LIMITED"
+	case http.StatusInternalServerError:
+		return "INTERNAL_ERROR"
+	}
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_").Replace(text))
+}
+
+// errorCode maps err to its domain code, falling back to the status code.
+func errorCode(err error, statusCode int) string {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	return statusErrorCode(statusCode)
+}
+
+// Error response helper
+func respondError(w http.ResponseWriter, statusCode int, message string) {
+	respondErrorCode(w, statusCode, statusErrorCode(statusCode), message)
+}
+
+// respondErrorFor reports err with the code of the domain error it wraps.
+func respondErrorFor(w http.ResponseWriter, statusCode int, err error) {
+	respondErrorCode(w, statusCode, errorCode(err, statusCode), err.Error())
+}
+
+// respondDecodeError reports a request body that json couldn't decode.
+func respondDecodeError(w http.ResponseWriter, err error) {
+	respondErrorCode(w, http.StatusBadRequest, "INVALID_JSON", decodeErrorMessage(err))
+}
+
+// decodeErrorMessage explains a JSON decoding error in terms of the request:
+// which field had the wrong type, or where the syntax broke.
+func decodeErrorMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "Invalid JSON: request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "Invalid JSON: request body ended unexpectedly"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("Invalid JSON: %s at offset %d", syntaxErr.Error(), syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Sprintf("Invalid JSON: expected %s but got %s at offset %d",
+				jsonKind(typeErr.Type), typeErr.Value, typeErr.Offset)
+		}
+		return fmt.Sprintf("Invalid JSON: field '%s' expected %s but got %s at offset %d",
+			typeErr.Field, jsonKind(typeErr.Type), typeErr.Value, typeErr.Offset)
+	}
+	return "Invalid JSON"
+}
+
+// jsonKind names the JSON value a Go type is decoded from.
+func jsonKind(t reflect.Type) string {
+	if t == nil {
+		return "value"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Ptr:
+		return jsonKind(t.Elem())
+	}
+	return t.String()
+}
+
+func respondErrorCode(w http.ResponseWriter, statusCode int, code, message string) {
+	log.Printf("[ERROR] HTTP %d %s: %s", statusCode, code, message)
+	respondJSON(w, statusCode, map[string]interface{}{
+		"success": false,
+		"code":    code,
+		"message": message,
+	})
+}
+
+// petListFilters builds the filters requested on the pets list query string.
+// An absent vaccinated parameter means no filter, not "false".
+func petListFilters(query url.Values) ([]Filterable, error) {
+	var filters []Filterable
+	if species := query.Get("species"); species != "" {
+		filters = append(filters, SpeciesFilter{Species: species})
+	}
+	if breed := query.Get("breed"); breed != "" {
+		filters = append(filters, BreedFilter{Breed: breed})
+	}
+	if status := query.Get("status"); status != "" {
+		filters = append(filters, StatusFilter{Status: status})
+	}
+	if ageCat := query.Get("ageCategory"); ageCat != "" {
+		filters = append(filters, AgeCategoryFilter{Category: ageCat})
+	}
+	// tags may be repeated (?tags=a&tags=b) or comma-separated (?tags=a,b)
+	var tags []string
+	for _, v := range query["tags"] {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) > 0 {
+		filters = append(filters, TagFilter{Tags: tags})
+	}
+	if v := query.Get("vaccinated"); v != "" {
+		vaccinated, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("vaccinated must be true or false")
+		}
+		filters = append(filters, VaccinationFilter{Vaccinated: vaccinated})
+	}
+	return filters, nil
+}
+
+func getPetsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := query.Get("q")
+
+	filters, err := petListFilters(query)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sortKey := query.Get("sort")
+
+	var result []Pet
+
+	// 2. CONTROL FLOW
+	if search != "" {
+		// Adopters shouldn't find pets that are already gone; admins may opt in.
+		if !(query.Get("includeAdopted") == "true" && isAdminRequest(r)) {
+			filters = append(filters, ExcludeStatusFilter{Statuses: unlistedStatuses})
+		}
+		page, limit, err := parsePagination(r)
+		if err != nil {
+			respondErrorFor(w, http.StatusBadRequest, err)
+			return
+		}
+		if sortKey != "" {
+			// Sorting needs every match, so page after sorting below.
+			page, limit = 1, 0
+		}
+		// SearchPets only fails without a query, which was ruled out above.
+		found, total, _ := SearchPets(search, filters, page, limit)
+		if sortKey == "" {
+			respondPage(w, found, total, page, limit)
+			return
+		}
+		result = found
+	} else {
+		mu.RLock()
+		result = slices.Clone(ApplyFilters(pets, filters))
+		mu.RUnlock()
+	}
+
+	if sortKey != "" {
+		result = sortPets(result, sortKey)
+	}
+	respondList(w, r, result)
+}
+
+// defaultPetSort is used for unrecognised ?sort= values: newest first.
+const defaultPetSort = "createdAt_desc"
+
+// sortPets returns a sorted copy of petList, leaving the input untouched.
+// sortKey is name, age or createdAt with an _asc or _desc suffix (the older
+// "-name" form also means descending); ties are broken by ID. Unknown keys
+// fall back to defaultPetSort.
+func sortPets(petList []Pet, sortKey string) []Pet {
+	sorted := make([]Pet, len(petList))
+	copy(sorted, petList)
+
+	field, desc := parseSortKey(sortKey)
+	var compare func(a, b Pet) int
+	switch field {
+	case "name":
+		compare = func(a, b Pet) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+	case "age":
+		compare = func(a, b Pet) int { return a.Age - b.Age }
+	case "createdat":
+		compare = func(a, b Pet) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	default:
+		return sortPets(petList, defaultPetSort)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		c := compare(sorted[i], sorted[j])
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// parseSortKey splits "age_desc" or "-age" into a lower-cased field and direction.
+func parseSortKey(sortKey string) (field string, desc bool) {
+	key := strings.ToLower(strings.TrimSpace(sortKey))
+	switch {
+	case strings.HasSuffix(key, "_desc"):
+		return strings.TrimSuffix(key, "_desc"), true
+	case strings.HasSuffix(key, "_asc"):
+		return strings.TrimSuffix(key, "_asc"), false
+	case strings.HasPrefix(key, "-"):
+		return key[1:], true
+	}
+	return key, false
+}
+
+// idPattern matches the IDs this server hands out, e.g. "pet-001".
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func isValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+func getPetByIDHandler(w http.ResponseWriter, r *http.Request) {
+	petID := r.PathValue("id")
+
+	if petID == "" {
+		respondError(w, http.StatusBadRequest, "Pet ID is required")
+		return
+	}
+	if !isValidID(petID) {
+		respondError(w, http.StatusBadRequest, "Invalid pet ID")
+		return
+	}
+
+	mu.RLock()
+	found, exists := petsByID[petID]
+	var pet Pet
+	if exists {
+		pet = *found
+	}
+	mu.RUnlock()
+
+	// 2. CONTROL FLOW
+	if !exists {
+		respondErrorCode(w, http.StatusNotFound, "PET_NOT_FOUND", "Pet not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pet,
+	})
+}
+
+func addPetHandler(w http.ResponseWriter, r *http.Request) {
+	var newPet Pet
+
+	// 8. JSON MARSHAL AND UNMARSHAL
+	if err := json.NewDecoder(r.Body).Decode(&newPet); err != nil {
+		log.Printf("[ERROR] Failed to decode pet JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	valid, validationErrors := validatePet(newPet)
+	if !valid {
+		log.Printf("[ERROR] Pet validation failed: %v", validationErrors)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"code":    "VALIDATION_FAILED",
+			"message": "Validation failed",
+			"errors":  validationErrors,
+		})
+		return
+	}
+
+	// A retried request with the same Idempotency-Key gets the original pet back.
+	key := r.Header.Get("Idempotency-Key")
+	mu.Lock()
+	if entry, seen := idempotencyKeys[key]; key != "" && seen {
+		if existing, exists := petsByID[entry.PetID]; exists {
+			pet := *existing
+			mu.Unlock()
+			log.Printf("[INFO] Replayed pet creation for Idempotency-Key %s: ID=%s", key, pet.ID)
+			respondJSON(w, http.StatusOK, map[string]interface{}{
+				"success": true,
+				"message": "Pet already created",
+				"data":    pet,
+			})
+			return
+		}
+	}
+
+	newPet = addPetLocked(newPet)
+	if key != "" {
+		idempotencyKeys[key] = idempotencyEntry{PetID: newPet.ID, CreatedAt: newPet.CreatedAt}
+	}
+	mu.Unlock()
+
+	syncPetToDB(newPet)
+	schedulePhotoCheck(newPet)
+	log.Printf("[INFO] Pet added: ID=%s, Name=%s, Species=%s", newPet.ID, newPet.Name, newPet.Species)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Pet added successfully",
+		"data":    newPet,
+	})
+}
+
+// updatePetHandler applies a partial update. Fields to blank can be listed in
+// a "clear" array in the body or as ?clear=description,tags.
+func updatePetHandler(w http.ResponseWriter, r *http.Request) {
+	petID := r.PathValue("id")
+
+	var req struct {
+		Pet
+		Clear []string `json:"clear"`
+	}
+
+	// 8. JSON MARSHAL AND UNMARSHAL
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode update JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+	update := req.Pet
+
+	clearFields := req.Clear
+	for _, param := range r.URL.Query()["clear"] {
+		for _, field := range strings.Split(param, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				clearFields = append(clearFields, field)
+			}
+		}
+	}
+
+	if errs := validatePetCollections(update); len(errs) > 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"code":    "VALIDATION_FAILED",
+			"message": "Validation failed",
+			"errors":  errs,
+		})
+		return
+	}
+
+	// 5. FUNCTIONS AND ERROR HANDLING
+	pet, err := UpdatePet(petID, update, clearFields...)
+	if err != nil {
+		if errors.Is(err, ErrPetNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	log.Printf("[INFO] Pet updated: ID=%s", petID)
+	syncPetToDB(*pet)
+	if update.Images != nil {
+		schedulePhotoCheck(*pet)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Pet updated successfully",
+		"data":    pet,
+	})
+}
+
+func deletePetHandler(w http.ResponseWriter, r *http.Request) {
+	petID := r.PathValue("id")
+
+	// 5. FUNCTIONS AND ERROR HANDLING
+	if err := DeletePet(petID); err != nil {
+		if errors.Is(err, ErrPetNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	log.Printf("[INFO] Pet deleted: ID=%s", petID)
+	deletePetFromDB(petID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Pet deleted successfully",
+	})
+}
+
+// decodeBulkIDs reads {"ids": [...]} from the request, writing a 400 on failure.
+func decodeBulkIDs(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	if len(req.IDs) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one pet ID is required")
+		return nil, false
+	}
+	if len(req.IDs) > maxPageSize {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("At most %d pet IDs per request", maxPageSize))
+		return nil, false
+	}
+	return req.IDs, true
+}
+
+func bulkDeletePetsHandler(w http.ResponseWriter, r *http.Request) {
+	ids, ok := decodeBulkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	results := BulkDeletePets(ids)
+	deleted := 0
+	for _, res := range results {
+		if res.Success {
+			deletePetFromDB(res.ID)
+			deleted++
+		}
+	}
+
+	log.Printf("[INFO] Bulk delete: %d of %d pets deleted", deleted, len(ids))
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%d of %d pets deleted", deleted, len(ids)),
+		"data":    results,
+	})
+}
+
+// importPetsCSVHandler accepts a CSV either as the "file" field of a
+// multipart upload or as the raw request body.
+func importPetsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPetCSVBytes)
+	defer r.Body.Close()
+
+	var src io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			log.Printf("[ERROR] Failed to read CSV upload: %v", err)
+			respondError(w, http.StatusBadRequest, `Upload the CSV as the "file" form field`)
+			return
+		}
+		defer file.Close()
+		src = file
+	}
+
+	results, added, err := ImportPetsCSV(src)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("CSV must be at most %d bytes", maxPetCSVBytes))
+			return
+		}
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, pet := range added {
+		syncPetToDB(pet)
+		schedulePhotoCheck(pet)
+	}
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	summary := fmt.Sprintf("%d of %d rows imported", len(added), len(results))
+	recordAudit(actor, "pet.import", "", summary)
+
+	log.Printf("[INFO] CSV import: %s", summary)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": summary,
+		"data":    results,
+	})
+}
+
+// petExportHeader is the header row of the pets CSV export.
+var petExportHeader = []string{"ID", "Name", "Species", "Breed", "Age", "Gender", "Status", "IsVaccinated", "CreatedAt"}
+
+// exportPetsCSVHandler streams the pet inventory as a CSV download. It takes
+// the same ?q=, filter and ?sort= parameters as the pets list, without paging.
+func exportPetsCSVHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filters, err := petListFilters(query)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var result []Pet
+	if search := query.Get("q"); search != "" {
+		result, _, _ = SearchPets(search, filters, 1, 0)
+	} else {
+		mu.RLock()
+		result = slices.Clone(ApplyFilters(pets, filters))
+		mu.RUnlock()
+	}
+	if sortKey := query.Get("sort"); sortKey != "" {
+		result = sortPets(result, sortKey)
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pets-%s.csv"`, displayTime(time.Now(), "2006-01-02")))
+
+	out := csv.NewWriter(w)
+	out.Write(petExportHeader)
+	for _, pet := range result {
+		out.Write([]string{
+			pet.ID,
+			pet.Name,
+			pet.Species,
+			pet.Breed,
+			strconv.Itoa(pet.Age),
+			pet.Gender,
+			pet.Status,
+			strconv.FormatBool(pet.IsVaccinated),
+			displayTime(pet.CreatedAt, "2006-01-02 15:04"),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("[ERROR] Writing pets CSV export: %v", err)
+	}
+}
+
+func bulkArchivePetsHandler(w http.ResponseWriter, r *http.Request) {
+	ids, ok := decodeBulkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	results, archived := BulkArchivePets(ids)
+	for _, pet := range archived {
+		syncPetToDB(pet)
+	}
+
+	log.Printf("[INFO] Bulk archive: %d of %d pets archived", len(archived), len(ids))
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%d of %d pets archived", len(archived), len(ids)),
+		"data":    results,
+	})
+}
+
+func featurePetHandler(w http.ResponseWriter, r *http.Request) {
+	petID := r.PathValue("id")
+
+	pet, previous, err := FeaturePet(petID)
+	if err != nil {
+		if errors.Is(err, ErrPetNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if previous != nil {
+		syncPetToDB(*previous)
+	}
+	syncPetToDB(*pet)
+	log.Printf("[INFO] Pet featured: ID=%s", petID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Pet featured successfully",
+		"data":    pet,
+	})
+}
+
+func getFeaturedPetHandler(w http.ResponseWriter, _ *http.Request) {
+	pet, ok := featuredPet()
+	if !ok {
+		respondError(w, http.StatusNotFound, "No featured pet")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pet,
+	})
+}
+
+func joinWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	petID := r.PathValue("id")
+	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+	if ok, wait := waitlistLimiter.allow(ip, time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondError(w, http.StatusTooManyRequests, "Too many waitlist requests. Please wait a minute and try again.")
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	added, err := JoinWaitlist(petID, req.Email)
+	if err != nil {
+		if errors.Is(err, ErrPetNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else if errors.Is(err, ErrWaitlistFull) {
+			respondErrorFor(w, http.StatusConflict, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	if !added {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "You are already on the waitlist for this pet",
+		})
+		return
+	}
+
+	log.Printf("[INFO] Waitlist joined: Pet=%s, Email=%s", petID, req.Email)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "You have been added to the waitlist. We'll email you if this pet becomes available.",
+	})
+}
+
+func favoritePetHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+	petID := r.PathValue("id")
+
+	added, err := AddFavorite(user, petID)
+	if err != nil {
+		respondErrorFor(w, http.StatusNotFound, err)
+		return
+	}
+	if !added {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Pet is already in your favorites",
+		})
+		return
+	}
+
+	mu.RLock()
+	syncUserToDB(*user)
+	mu.RUnlock()
+	log.Printf("[INFO] Favorite added: User=%s, Pet=%s", user.ID, petID)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Pet added to favorites",
+	})
+}
+
+func unfavoritePetHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+	petID := r.PathValue("id")
+
+	if RemoveFavorite(user, petID) {
+		mu.RLock()
+		syncUserToDB(*user)
+		mu.RUnlock()
+		log.Printf("[INFO] Favorite removed: User=%s, Pet=%s", user.ID, petID)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Pet removed from favorites",
+	})
+}
+
+func getFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	respondList(w, r, favoritePets(user))
+}
+
+func getPetFacetsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var attrs []string
+	for _, attr := range strings.Split(query.Get("attr"), ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	if len(attrs) == 0 {
+		respondError(w, http.StatusBadRequest, "attr is required, e.g. ?attr=Color,Size")
+		return
+	}
+
+	filters, err := petListFilters(query)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    petFacets(attrs, filters),
+	})
+}
+
+func getSimilarPetsHandler(w http.ResponseWriter, r *http.Request) {
+	petID := r.PathValue("id")
+
+	mu.RLock()
+	target, exists := petsByID[petID]
+	var result []Pet
+	if exists {
+		result = similarPets(*target, pets, 5)
+	}
+	mu.RUnlock()
+
+	if !exists {
+		respondErrorFor(w, http.StatusNotFound, ErrPetNotFound)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"count":   len(result),
+		"data":    result,
+	})
+}
+
+func getPetsBrokenPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	respondList(w, r, petsWithBrokenPhotos())
+}
+
+// getPetsCheckupDueHandler lists pets overdue for a vet checkup, ?months=
+// (default 12), as a worklist for the vet.
+func getPetsCheckupDueHandler(w http.ResponseWriter, r *http.Request) {
+	months := 12
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, "months must be a positive whole number")
+			return
+		}
+		months = n
+	}
+
+	mu.RLock()
+	due, unparseable := checkupDue(pets, time.Now(), months)
+	mu.RUnlock()
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"count":       len(due),
+		"data":        due,
+		"unparseable": unparseable,
+	})
+}
+
+func getPetsMissingPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	result := petsMissingPhotos()
+
+	respondList(w, r, result)
+}
+
+func getServicesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	category := query.Get("category")
+
+	result := make([]Service, 0)
+
+	// 2. CONTROL FLOW and LOOPING
+	mu.RLock()
+	if category == "" {
+		result = make([]Service, len(services))
+		copy(result, services)
+	} else {
+		for _, service := range services {
+			if strings.EqualFold(service.Category, strings.TrimSpace(category)) {
+				result = append(result, service)
+			}
+		}
+	}
+	mu.RUnlock()
+
+	respondList(w, r, result)
+}
+
+func createServiceHandler(w http.ResponseWriter, r *http.Request) {
+	var service Service
+
+	if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
+		log.Printf("[ERROR] Failed to decode service JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	valid, validationErrors := validateService(service)
+	if !valid {
+		log.Printf("[ERROR] Service validation failed: %v", validationErrors)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"code":    "VALIDATION_FAILED",
+			"message": "Validation failed",
+			"errors":  validationErrors,
+		})
+		return
+	}
+
+	created, err := AddService(service)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Printf("[INFO] Service added: ID=%s, Name=%s, Category=%s", created.ID, created.Name, created.Category)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Service added successfully",
+		"data":    created,
+	})
+}
+
+func updateServiceHandler(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.PathValue("id")
+
+	var update Service
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.Printf("[ERROR] Failed to decode service update JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	service, err := UpdateService(serviceID, update)
+	if err != nil {
+		if errors.Is(err, ErrServiceNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	log.Printf("[INFO] Service updated: ID=%s", serviceID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Service updated successfully",
+		"data":    service,
+	})
+}
+
+func deleteServiceHandler(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.PathValue("id")
+
+	if err := DeleteService(serviceID); err != nil {
+		if errors.Is(err, ErrServiceNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusConflict, err)
+		}
+		return
+	}
+
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	recordAudit(actor, "service.delete", serviceID, "")
+
+	log.Printf("[INFO] Service deleted: ID=%s", serviceID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Service deleted successfully",
+	})
+}
+
+func getServiceHandler(w http.ResponseWriter, r *http.Request) {
+	detail, err := serviceDetail(r.PathValue("id"))
+	if err != nil {
+		respondErrorFor(w, http.StatusNotFound, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    detail,
+	})
+}
+
+func rateServiceHandler(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.PathValue("id")
+
+	var req struct {
+		Rating    int    `json:"rating"`
+		BookingID string `json:"bookingId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	// A rating tied to a booking may only come from whoever made it, so
+	// the once-per-booking guard can't be used up by guessing IDs.
+	if req.BookingID != "" {
+		user, err := authenticate(r)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		mu.RLock()
+		booking, exists := bookingsByID[req.BookingID]
+		owned := exists && strings.EqualFold(booking.Email, user.Email)
+		mu.RUnlock()
+		if exists && !owned && !user.IsAdmin {
+			respondError(w, http.StatusForbidden, "You can only rate your own bookings")
+			return
+		}
+	}
+
+	detail, err := RateService(serviceID, req.Rating, req.BookingID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrServiceNotFound), errors.Is(err, ErrBookingNotFound):
+			respondErrorFor(w, http.StatusNotFound, err)
+		case errors.Is(err, ErrBookingNotCompleted), errors.Is(err, ErrBookingRated):
+			respondErrorFor(w, http.StatusConflict, err)
+		default:
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	log.Printf("[INFO] Service rated: ID=%s, Rating=%d", serviceID, req.Rating)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Thanks for your rating",
+		"data":    detail,
+	})
+}
+
+func getBookingsHandler(w http.ResponseWriter, r *http.Request) {
+	mu.RLock()
+	result := make([]ServiceBooking, len(bookings))
+	copy(result, bookings)
+	mu.RUnlock()
+
+	respondList(w, r, result)
+}
+
+func createBookingHandler(w http.ResponseWriter, r *http.Request) {
+	var booking ServiceBooking
+
+	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
+		log.Printf("[ERROR] Failed to decode booking JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	// Validate required fields
+	if booking.ServiceID == "" || booking.OwnerName == "" || booking.Email == "" {
+		respondError(w, http.StatusBadRequest, "Service ID, owner name, and email are required")
+		return
+	}
+
+	booking.BookedAt = time.Now()
+	booking.Status = "Pending"
+
+	// The ID comes from the slice length, so it must be taken under the same
+	// lock as the append or concurrent requests can share one.
+	mu.Lock()
+	if hasBookingConflict(booking.ServiceID, booking.Date, booking.Time) {
+		mu.Unlock()
+		respondErrorFor(w, http.StatusConflict, ErrBookingConflict)
+		return
+	}
+	booking.ID = fmt.Sprintf("book-%03d", len(bookings)+1)
+	bookings = append(bookings, booking)
+	reindexBookings()
+	if stats, exists := serviceStats[booking.ServiceID]; exists {
+		stats["bookings"] = stats["bookings"].(int) + 1
+	}
+	var service *Service
+	if svc, exists := servicesByID[booking.ServiceID]; exists {
+		copied := *svc
+		service = &copied
+	}
+	mu.Unlock()
+
+	log.Printf("[INFO] Booking created: ID=%s, Service=%s, Owner=%s", booking.ID, booking.ServiceID, booking.OwnerName)
+	if service != nil {
+		sendBookingConfirmation(booking, *service)
+	} else {
+		log.Printf("[WARN] Booking %s is for unknown service %s; no confirmation sent", booking.ID, booking.ServiceID)
+	}
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Booking created successfully",
+		"data":    booking,
+	})
+}
+
+func getBookingHandler(w http.ResponseWriter, r *http.Request) {
+	detail, err := GetBooking(r.PathValue("id"))
+	if err != nil {
+		respondErrorFor(w, http.StatusNotFound, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    detail,
+	})
+}
+
+// cancelBookingHandler cancels a booking for its owner (the logged-in user
+// whose email it was made with) or an admin.
+func cancelBookingHandler(w http.ResponseWriter, r *http.Request) {
+	bookingID := r.PathValue("id")
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	mu.RLock()
+	booking, exists := bookingsByID[bookingID]
+	owned := exists && strings.EqualFold(booking.Email, user.Email)
+	mu.RUnlock()
+	if exists && !owned && !user.IsAdmin {
+		respondError(w, http.StatusForbidden, "You can only cancel your own bookings")
+		return
+	}
+
+	if err := CancelBooking(bookingID); err != nil {
+		switch {
+		case errors.Is(err, ErrBookingNotFound):
+			respondErrorFor(w, http.StatusNotFound, err)
+		case errors.Is(err, ErrBookingCancelled), errors.Is(err, ErrBookingCompleted):
+			respondErrorFor(w, http.StatusConflict, err)
+		default:
+			respondErrorFor(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	log.Printf("[INFO] Booking cancelled: ID=%s by %s", bookingID, user.Email)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Booking cancelled successfully",
+	})
+}
+
+func completeBookingHandler(w http.ResponseWriter, r *http.Request) {
+	booking, err := CompleteBooking(r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrBookingNotFound):
+			respondErrorFor(w, http.StatusNotFound, err)
+		case errors.Is(err, ErrBookingCancelled), errors.Is(err, ErrBookingCompleted):
+			respondErrorFor(w, http.StatusConflict, err)
+		default:
+			respondErrorFor(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	log.Printf("[INFO] Booking completed: ID=%s", booking.ID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Booking marked completed",
+		"data":    booking,
+	})
+}
+
+func getPackagesHandler(w http.ResponseWriter, r *http.Request) {
+	mu.RLock()
+	result := make([]ServicePackage, len(packages))
+	copy(result, packages)
+	mu.RUnlock()
+
+	respondList(w, r, result)
+}
+
+func createPackageHandler(w http.ResponseWriter, r *http.Request) {
+	// Active is a pointer here so a package is bookable unless the request
+	// explicitly says otherwise.
+	var req struct {
+		ServicePackage
+		Active *bool `json:"active"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode package JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	pkg := req.ServicePackage
+	pkg.Active = req.Active == nil || *req.Active
+	created, err := AddPackage(pkg)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+	syncPackageToDB(*created)
+
+	log.Printf("[INFO] Package created: ID=%s, Name=%s, Services=%v", created.ID, created.Name, created.ServiceIDs)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Package created successfully",
+		"data":    created,
+	})
+}
+
+func bookPackageHandler(w http.ResponseWriter, r *http.Request) {
+	packageID := r.PathValue("id")
+
+	var booking ServiceBooking
+	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
+		log.Printf("[ERROR] Failed to decode package booking JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if booking.OwnerName == "" || booking.Email == "" {
+		respondError(w, http.StatusBadRequest, "Owner name and email are required")
+		return
+	}
+
+	created, err := BookPackage(packageID, booking)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, ErrBookingConflict) {
+			status = http.StatusConflict
+		}
+		respondErrorFor(w, status, err)
+		return
+	}
+
+	log.Printf("[INFO] Package booked: ID=%s, Owner=%s, Bookings=%d", packageID, booking.OwnerName, len(created))
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Package booked successfully",
+		"data":    created,
+	})
+}
+
+func submitContactHandler(w http.ResponseWriter, r *http.Request) {
+	var contact ContactForm
+
+	trapped, err := decodeForm(r, &contact)
+	if err != nil {
+		log.Printf("[ERROR] Failed to decode contact JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+	if trapped {
+		respondHoneypot(w, r, "contact")
+		return
+	}
+
+	if !checkCaptcha(w, r, contact.CaptchaToken) {
+		return
+	}
+	contact.CaptchaToken = ""
+
+	// Validate required fields
+	contact.Email = strings.TrimSpace(contact.Email)
+	if contact.Name == "" || contact.Email == "" || contact.Message == "" {
+		respondError(w, http.StatusBadRequest, "Name, email, and message are required")
+		return
+	}
+	if !isValidEmail(contact.Email) {
+		respondErrorFor(w, http.StatusBadRequest, ErrInvalidEmail)
+		return
+	}
+	contact.Email = strings.ToLower(contact.Email)
+
+	contact.SentAt = time.Now()
+	mu.Lock()
+	contact.ID = fmt.Sprintf("msg-%03d", len(contactMessages)+1)
+	contactMessages = append(contactMessages, contact)
+	mu.Unlock()
+
+	log.Printf("[INFO] Contact message received from: %s (%s)", contact.Name, contact.Email)
+
+	// 10. CONCURRENCY
+	enqueueNotification(NotificationJob{
+		To:      contact.Email,
+		Subject: "Thank you for contacting Pawtner Hope",
+		Body:    fmt.Sprintf("Dear %s, we received your message and will get back to you soon.", contact.Name),
+		JobType: "contact",
+	})
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Message sent successfully",
+	})
+}
+
+// getTestimonialsHandler lists approved testimonials. Admins can pass
+// ?pending=true to include those awaiting approval.
+func getTestimonialsHandler(w http.ResponseWriter, r *http.Request) {
+	includePending := r.URL.Query().Get("pending") == "true" && isAdminRequest(r)
+	respondList(w, r, listTestimonials(includePending))
+}
+
+func submitTestimonialHandler(w http.ResponseWriter, r *http.Request) {
+	var req Testimonial
+
+	trapped, err := decodeForm(r, &req)
+	if err != nil {
+		log.Printf("[ERROR] Failed to decode testimonial JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+	if trapped {
+		respondHoneypot(w, r, "testimonial")
+		return
+	}
+
+	testimonial, err := SubmitTestimonial(req)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Printf("[INFO] Testimonial submitted: ID=%s, Author=%s", testimonial.ID, testimonial.Author)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Thank you! Your testimonial will appear once it has been reviewed.",
+		"data":    testimonial,
+	})
+}
+
+func approveTestimonialHandler(w http.ResponseWriter, r *http.Request) {
+	testimonial, err := ApproveTestimonial(r.PathValue("id"))
+	if err != nil {
+		respondErrorFor(w, http.StatusNotFound, err)
+		return
+	}
+
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	recordAudit(actor, "testimonial.approve", testimonial.ID, "")
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Testimonial approved",
+		"data":    testimonial,
+	})
+}
+
+func submitVolunteerHandler(w http.ResponseWriter, r *http.Request) {
+	var req Volunteer
+
+	trapped, err := decodeForm(r, &req)
+	if err != nil {
+		log.Printf("[ERROR] Failed to decode volunteer JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+	if trapped {
+		respondHoneypot(w, r, "volunteer")
+		return
+	}
+
+	if !checkCaptcha(w, r, req.CaptchaToken) {
+		return
+	}
+
+	volunteer, err := SignUpVolunteer(req)
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Printf("[INFO] Volunteer sign-up: ID=%s, %s (%s)", volunteer.ID, volunteer.Name, volunteer.Email)
+	enqueueNotification(NotificationJob{
+		To:      volunteer.Email,
+		Subject: "Thank you for volunteering with Pawtner Hope 🐾",
+		Body: fmt.Sprintf("Hi %s,\n\nThank you for offering to volunteer with us! "+
+			"Our team will review your sign-up and get in touch about opportunities that match "+
+			"your availability (%s).\n\nPawtner Hope Foundation", volunteer.Name, volunteer.Availability),
+		JobType:   "volunteer",
+		PlainText: true,
+	})
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Thank you for signing up! We'll be in touch soon.",
+		"data":    volunteer,
+	})
+}
+
+// getVolunteersHandler lists volunteer sign-ups, filtered by ?status=.
+func getVolunteersHandler(w http.ResponseWriter, r *http.Request) {
+	respondList(w, r, listVolunteers(r.URL.Query().Get("status")))
+}
+
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+
+		CaptchaToken string `json:"captchaToken"`
+	}
+
+	trapped, err := decodeForm(r, &req)
+	if err != nil {
+		log.Printf("[ERROR] Failed to decode registration JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+	if trapped {
+		respondHoneypot(w, r, "registration")
+		return
+	}
+
+	if !checkCaptcha(w, r, req.CaptchaToken) {
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Email == "" || req.Username == "" || req.Password == "" {
+		respondError(w, http.StatusBadRequest, "Email, username and password are required")
+		return
+	}
+	if !isValidEmail(req.Email) {
+		respondErrorFor(w, http.StatusBadRequest, ErrInvalidEmail)
+		return
+	}
+	req.Email = strings.ToLower(req.Email)
+	if len(req.Password) > maxPasswordBytes {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Password must be at most %d bytes", maxPasswordBytes))
+		return
+	}
+	if err := validateUsername(req.Username); err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+	if isDisposableEmail(req.Email) {
+		respondError(w, http.StatusBadRequest, "Disposable email addresses are not accepted. Please use a permanent email address.")
+		return
+	}
+
+	mu.RLock()
+	_, alreadyExists := usersByEmail[req.Email]
+	mu.RUnlock()
+	if alreadyExists {
+		respondErrorFor(w, http.StatusConflict, ErrUserAlreadyExists)
+		return
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		log.Printf("[ERROR] Registration for %s: %v", req.Email, err)
+		respondError(w, http.StatusBadRequest, "Password could not be accepted")
+		return
+	}
+
+	// Registering again while a code is live never invalidates that code: the
+	// first registration's details and code are kept, and the code is only
+	// resent (with a fresh expiry) once it's within otpReminderWindow of
+	// expiring. Keeping the first details stops a second caller from swapping
+	// in their own password, and maxVerifyAttempts still bounds guesses at the
+	// code. The decision is made under one lock so concurrent registrations
+	// for the same email agree; entries are replaced, never changed in place.
+	now := time.Now()
+	mu.Lock()
+	if _, exists := usersByEmail[req.Email]; exists {
+		mu.Unlock()
+		respondErrorFor(w, http.StatusConflict, ErrUserAlreadyExists)
+		return
+	}
+	pending, reused := pendingRegs[req.Email]
+	if reused && now.After(pending.ExpiresAt) {
+		reused = false
+	}
+	send := !reused || pending.ExpiresAt.Sub(now) <= otpReminderWindow
+	if !reused {
+		pending = &PendingRegistration{
+			Email:          req.Email,
+			Username:       req.Username,
+			HashedPassword: hash,
+			Code:           generateOTP(),
+		}
+		pendingRegs[req.Email] = pending
+	}
+	if send {
+		refreshed := *pending
+		refreshed.ExpiresAt = now.Add(pendingRegistrationTTL)
+		refreshed.Reminded = false
+		pending = &refreshed
+		pendingRegs[req.Email] = pending
+	}
+	username, code := pending.Username, pending.Code
+	mu.Unlock()
+
+	if !send {
+		log.Printf("[INFO] Repeat registration for %s; existing code still valid", req.Email)
+		respondJSON(w, http.StatusAccepted, map[string]interface{}{
+			"success": true,
+			"message": "A verification code was already sent to your email and is still valid.",
+		})
+		return
+	}
+
+	// Send OTP email asynchronously
+	job := composeEmail(req.Email, "Your Pawtner Hope Verification Code 🐾", "otp", otpEmailTmpl, map[string]string{
+		"Username": username,
+		"Code":     code,
+	}, otpEmailText)
+	enqueueNotification(job)
+
+	message := "Verification code sent to your email. It expires in 5 minutes."
+	if reused {
+		message = "We've sent your verification code again. It expires in 5 minutes."
+	}
+	log.Printf("[INFO] OTP sent to %s (expires in 5 min, resent=%v)", req.Email, reused)
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"message": message,
+	})
+}
+
+// recordWrongCode counts a failed verification against email's pending
+// registration and drops the registration once it reaches maxVerifyAttempts,
+// reporting whether it did.
+func recordWrongCode(email string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current, exists := pendingRegs[email]
+	if !exists {
+		return false
+	}
+	updated := *current
+	updated.Attempts++
+	if updated.Attempts >= maxVerifyAttempts {
+		delete(pendingRegs, email)
+		return true
+	}
+	pendingRegs[email] = &updated
+	return false
+}
+
+func verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	req.Code = strings.TrimSpace(req.Code)
+
+	mu.Lock()
+	var pending PendingRegistration
+	current, exists := pendingRegs[req.Email]
+	if exists {
+		pending = *current
+	}
+	mu.Unlock()
+
+	if !exists {
+		respondError(w, http.StatusBadRequest, "No pending registration for this email. Please sign up again.")
+		return
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		mu.Lock()
+		delete(pendingRegs, req.Email)
+		mu.Unlock()
+		respondError(w, http.StatusBadRequest, "Verification code has expired. Please sign up again.")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Code), []byte(pending.Code)) != 1 {
+		if recordWrongCode(req.Email) {
+			log.Printf("[WARN] Too many wrong verification codes for %s; registration dropped", req.Email)
+			respondError(w, http.StatusBadRequest, "Too many incorrect codes. Please sign up again.")
+			return
+		}
+		respondError(w, http.StatusBadRequest, "Invalid verification code.")
+		return
+	}
+	// The lists may have changed since the code was sent.
+	if err := validateUsername(pending.Username); err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Create user with pre-hashed password
+	user := User{
+		Email:     pending.Email,
+		Username:  pending.Username,
+		Password:  pending.HashedPassword,
+		Role:      "user",
+		CreatedAt: time.Now(),
+		IsActive:  true,
+	}
+
+	mu.Lock()
+	if _, stillPending := pendingRegs[req.Email]; !stillPending {
+		// A concurrent request already verified or dropped it.
+		mu.Unlock()
+		respondError(w, http.StatusBadRequest, "No pending registration for this email. Please sign up again.")
+		return
+	}
+	user.ID = fmt.Sprintf("usr-%03d", len(users)+1)
+	users = append(users, user)
+	usersByEmail[user.Email] = &users[len(users)-1]
+	delete(pendingRegs, req.Email)
+	mu.Unlock()
+
+	syncUserToDB(user)
+	sendWelcomeEmail(&user)
+	log.Printf("[INFO] User verified and created: %s (%s)", user.Username, user.Email)
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Account created successfully! You can now log in.",
+		"data":    user,
+	})
+}
+
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode login JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	// 5. FUNCTIONS AND ERROR HANDLING
+	token, err := Login(req.Email, req.Password)
+	if err != nil {
+		ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if entry, recorded := recordFailedLogin(req.Email, req.Password, ip); recorded {
+			log.Printf("[WARN] Failed login attempt for: %s from %s (%s)", entry.Email, entry.IP, entry.Reason)
+		}
+		respondErrorFor(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	log.Printf("[INFO] User logged in: %s", req.Email)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Login successful",
+		"data":    token,
+	})
+}
+
+func refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr := bearerToken(r)
+	if tokenStr == "" {
+		respondError(w, http.StatusUnauthorized, "Missing token")
+		return
+	}
+	token, err := RefreshToken(tokenStr)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTooEarly) {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		} else {
+			respondErrorFor(w, http.StatusUnauthorized, err)
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Token refreshed",
+		"data":    token,
+	})
+}
+
+// logoutHandler revokes the caller's token. Logging out twice is not an error.
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	tokenStr := bearerToken(r)
+	if tokenStr == "" {
+		respondError(w, http.StatusUnauthorized, "Missing token")
+		return
+	}
+	if user, err := ValidateToken(tokenStr); err == nil {
+		mu.Lock()
+		delete(tokenStore, tokenStr)
+		mu.Unlock()
+		log.Printf("[INFO] User logged out: %s", user.Email)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Logged out",
+	})
+}
+
+func deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Password == "" {
+		respondError(w, http.StatusBadRequest, "Password confirmation is required")
+		return
+	}
+
+	userID := user.ID
+	scrubbedDonations, scrubbedInquiries, err := AnonymizeUser(user, req.Password)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Incorrect password")
+		return
+	}
+
+	mu.RLock()
+	anonymized := *user
+	mu.RUnlock()
+	syncUserToDB(anonymized)
+	for _, d := range scrubbedDonations {
+		syncDonationToDB(d)
+	}
+	for _, inq := range scrubbedInquiries {
+		syncInquiryToDB(inq)
+	}
+
+	log.Printf("[INFO] Account deleted and anonymized: %s", userID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Your account has been deleted",
+	})
+}
+
+func setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := SetUserRole(userID, req.Role)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	mu.RLock()
+	updated := *user
+	mu.RUnlock()
+	syncUserToDB(updated)
+	log.Printf("[INFO] Role updated: User=%s, Role=%s", userID, updated.Role)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Role updated successfully",
+		"data":    updated,
+	})
+}
+
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	authHeader := r.Header.Get("Authorization")
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenStr == "" {
+		respondError(w, http.StatusUnauthorized, "Missing token")
+		return
+	}
+	user, err := ValidateToken(tokenStr)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"id":        user.ID,
+			"email":     user.Email,
+			"username":  user.Username,
+			"role":      user.Role,
+			"isadmin":   user.IsAdmin,
+			"createdAt": user.CreatedAt,
+		},
+	})
+}
 
-	mu.Lock()
-	petsCopy := make([]Pet, len(pets))
-	copy(petsCopy, pets)
-	mu.Unlock()
+func createAdoptionInquiryHandler(w http.ResponseWriter, r *http.Request) {
+	var inquiry AdoptionInquiry
 
-	var result []Pet
-	if query != "" {
-		for _, p := range petsCopy {
-			nameLower := strings.ToLower(p.Name)
-			speciesLower := strings.ToLower(p.Species)
-			breedLower := strings.ToLower(p.Breed)
-			qLower := strings.ToLower(query)
-			if strings.Contains(nameLower, qLower) ||
-				strings.Contains(speciesLower, qLower) ||
-				strings.Contains(breedLower, qLower) {
-				result = append(result, p)
-			}
-		}
-	} else {
-		result = petsCopy
+	// 8. JSON MARSHAL AND UNMARSHAL
+	if err := json.NewDecoder(r.Body).Decode(&inquiry); err != nil {
+		log.Printf("[ERROR] Failed to decode adoption inquiry JSON: %v", err)
+		respondDecodeError(w, err)
+		return
 	}
+	defer r.Body.Close()
 
-	if len(filters) > 0 {
-		result = ApplyFilters(result, filters)
+	inquiry.Email = strings.TrimSpace(inquiry.Email)
+	if inquiry.PetID == "" || inquiry.AdopterName == "" || inquiry.Email == "" {
+		respondError(w, http.StatusBadRequest, "PetID, adopter name and email are required")
+		return
 	}
-
-	return result, nil
-}
-
-func emailWorker(jobs <-chan NotificationJob) {
-	for job := range jobs {
-		SendEmailWithRetry(job.To, job.Subject, job.Body, 3)
+	if !isValidEmail(inquiry.Email) {
+		respondErrorFor(w, http.StatusBadRequest, ErrInvalidEmail)
+		return
 	}
-}
-
-func paymentProcessor(donationQueue <-chan Donation, confirmations chan<- PaymentConfirmation) {
-	for donation := range donationQueue {
-		time.Sleep(50 * time.Millisecond)
-		confirmation := PaymentConfirmation{
-			DonationID:    donation.ID,
-			Success:       true,
-			TransactionID: fmt.Sprintf("txn-%d", time.Now().UnixNano()),
-		}
-		confirmations <- confirmation
+	inquiry.Email = strings.ToLower(inquiry.Email)
 
-		// Only auto-send receipt for mobile UPI deeplink payments.
-		// Desktop donors must request a receipt via email.
-		if donation.PaymentViaDeeplink {
-			go func(d Donation) {
-				receipt := GenerateReceipt(d)
-				sendDonationReceipt(d, receipt)
-			}(donation)
-		} else {
-			log.Printf("[INFO] Desktop donation from %s — receipt not auto-sent (request required)", donation.DonorEmail)
-		}
+	mu.RLock()
+	pet, exists := petsByID[inquiry.PetID]
+	var species string
+	if exists {
+		species = pet.Species
 	}
-}
-
-func confirmationListener(confirmations <-chan PaymentConfirmation) {
-	for confirmation := range confirmations {
-		mu.Lock()
-		for i := range donations {
-			if donations[i].ID == confirmation.DonationID {
-				if confirmation.Success {
-					donations[i].Status = "Completed"
-					donations[i].TransactionID = confirmation.TransactionID
-				} else {
-					donations[i].Status = "Failed"
-				}
-				break
-			}
+	mu.RUnlock()
+	if !exists {
+		respondErrorFor(w, http.StatusNotFound, ErrPetNotFound)
+		return
+	}
+	if missing := missingAnswers(questionsForSpecies(species), inquiry.Answers); len(missing) > 0 {
+		errs := make([]string, len(missing))
+		for i, key := range missing {
+			errs[i] = fmt.Sprintf("answer %q is required", key)
 		}
-		mu.Unlock()
-		log.Printf("[PAYMENT] Processed: %s - Success: %v", confirmation.DonationID, confirmation.Success)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"code":    "VALIDATION_FAILED",
+			"message": "Validation failed",
+			"errors":  errs,
+		})
+		return
 	}
-}
 
-func startWorkers() {
-	// 11. GOROUTINES AND CHANNELS
-	go emailWorker(notificationCh)
-	go paymentProcessor(paymentCh, paymentConfirmCh)
-	go confirmationListener(paymentConfirmCh)
-}
+	inquiry.ID = fmt.Sprintf("inq-%03d", len(inquiries)+1)
+	inquiry.Status = "Pending"
+	inquiry.CreatedAt = time.Now()
 
-// HTTP Handlers
+	mu.Lock()
+	inquiries = append(inquiries, inquiry)
+	mu.Unlock()
 
-// Panic recovery middleware
-func recoverPanic(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("[PANIC RECOVERED] %v for request %s %s", err, r.Method, r.URL.Path)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				json.NewEncoder(w).Encode(map[string]interface{}{
-					"success": false,
-					"message": "Internal server error",
-				})
-			}
-		}()
-		next(w, r)
-	}
+	syncInquiryToDB(inquiry)
+	log.Printf("[INFO] Adoption inquiry: Pet=%s, Adopter=%s (%s)", inquiry.PetID, inquiry.AdopterName, inquiry.Email)
+
+	// 10. CONCURRENCY
+	enqueueNotification(NotificationJob{
+		To:      inquiry.Email,
+		Subject: "Adoption Inquiry Received - Pawtner Hope",
+		Body:    fmt.Sprintf("Dear %s, your adoption inquiry for pet %s has been received.", inquiry.AdopterName, inquiry.PetID),
+		JobType: "adoption",
+	})
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Adoption inquiry submitted successfully",
+		"data":    inquiry,
+	})
 }
 
-// 6. INTERFACE - http.HandlerFunc implements http.Handler
-func enableCORS(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// getAdoptionQuestionsHandler returns the questions the adoption form must
+// ask for ?species=.
+func getAdoptionQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    questionsForSpecies(r.URL.Query().Get("species")),
+	})
+}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		next(w, r)
-	}
+func getAdoptionInquiriesHandler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	result := make([]AdoptionInquiry, len(inquiries))
+	copy(result, inquiries)
+	mu.Unlock()
+
+	respondList(w, r, result)
 }
 
-// Safe file serving with error handling
-func serveHTMLFile(filename string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if _, err := os.Stat(filename); os.IsNotExist(err) {
-			log.Printf("[ERROR] File not found: %s", filename)
-			http.Error(w, "Page not found", http.StatusNotFound)
-			return
+func getAdoptionInquiryHandler(w http.ResponseWriter, r *http.Request) {
+	inquiryID := r.PathValue("id")
+
+	mu.RLock()
+	inquiry := findInquiry(inquiryID)
+	var result AdoptionInquiry
+	var feePayment *Donation
+	if inquiry != nil {
+		result = *inquiry
+		if d := findDonation(inquiry.DonationID); d != nil {
+			payment := *d
+			feePayment = &payment
 		}
-		http.ServeFile(w, r, filename)
 	}
-}
+	mu.RUnlock()
 
-// Safe JSON response with error handling
-func respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("[ERROR] Failed to encode JSON response: %v", err)
+	if inquiry == nil {
+		respondErrorFor(w, http.StatusNotFound, ErrInquiryNotFound)
+		return
 	}
-}
 
-// Error response helper
-func respondError(w http.ResponseWriter, statusCode int, message string) {
-	log.Printf("[ERROR] HTTP %d: %s", statusCode, message)
-	respondJSON(w, statusCode, map[string]interface{}{
-		"success": false,
-		"message": message,
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"data":       result,
+		"feePayment": feePayment,
 	})
 }
 
-func getPetsHandler(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	species := query.Get("species")
-	status := query.Get("status")
-	search := query.Get("q")
+func updateAdoptionInquiryHandler(w http.ResponseWriter, r *http.Request) {
+	inquiryID := r.PathValue("id")
 
-	var result []Pet
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
 
-	// 2. CONTROL FLOW
-	if search != "" {
-		var filters []Filterable
-		if species != "" {
-			filters = append(filters, SpeciesFilter{Species: species})
-		}
-		if status != "" {
-			filters = append(filters, StatusFilter{Status: status})
-		}
-		var err error
-		result, err = SearchPets(search, filters)
-		if err != nil {
-			result = pets
-		}
-	} else if species == "" && status == "" {
-		result = pets
-	} else {
-		var filters []Filterable
-		if species != "" {
-			filters = append(filters, SpeciesFilter{Species: species})
-		}
-		if status != "" {
-			filters = append(filters, StatusFilter{Status: status})
+	inquiry, petChanged, err := UpdateInquiryStatus(inquiryID, strings.TrimSpace(req.Status))
+	if err != nil {
+		if errors.Is(err, ErrInquiryNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else if errors.Is(err, ErrPetAlreadyAdopted) {
+			respondErrorFor(w, http.StatusConflict, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
 		}
-		result = ApplyFilters(pets, filters)
+		return
+	}
+
+	syncInquiryToDB(*inquiry)
+	if petChanged != nil {
+		syncPetToDB(*petChanged)
 	}
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	recordAudit(actor, "inquiry.status", inquiry.ID, inquiry.Status)
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"count":   len(result),
-		"data":    result,
+		"message": "Inquiry " + strings.ToLower(inquiry.Status),
+		"data":    inquiry,
 	})
 }
 
-func getPetByIDHandler(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/pets/")
-	petID := path
+func submitFosterHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Please log in to apply to foster")
+		return
+	}
 
-	pet, exists := petsByID[petID]
+	var req FosterApplication
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
 
-	// 2. CONTROL FLOW
-	if !exists {
-		respondError(w, http.StatusNotFound, "Pet not found")
+	app, err := SubmitFosterApplication(user, req)
+	if err != nil {
+		if errors.Is(err, ErrPetNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	syncFosterToDB(*app)
+	log.Printf("[INFO] Foster application: ID=%s, User=%s, Pet=%s", app.ID, app.UserID, app.PetID)
+	enqueueNotification(NotificationJob{
+		To:      app.Email,
+		Subject: "Foster Application Received - Pawtner Hope",
+		Body: fmt.Sprintf("Hi %s,\n\nThank you for offering to foster! We've received your application "+
+			"and will be in touch once our team has reviewed it.\n\nPawtner Hope Foundation", app.Name),
+		JobType:   "foster",
+		PlainText: true,
+	})
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"success": true,
-		"data":    pet,
+		"message": "Foster application submitted successfully",
+		"data":    app,
 	})
 }
 
-func addPetHandler(w http.ResponseWriter, r *http.Request) {
-	var newPet Pet
+// getFosterApplicationsHandler lists foster applications, filtered by ?status=.
+func getFosterApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	respondList(w, r, listFosterApplications(r.URL.Query().Get("status")))
+}
 
-	// 8. JSON MARSHAL AND UNMARSHAL
-	if err := json.NewDecoder(r.Body).Decode(&newPet); err != nil {
-		log.Printf("[ERROR] Failed to decode pet JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON data")
+func updateFosterHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
 		return
 	}
 	defer r.Body.Close()
 
-	valid, validationErrors := validatePet(newPet)
-	if !valid {
-		log.Printf("[ERROR] Pet validation failed: %v", validationErrors)
-		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"success": false,
-			"message": "Validation failed",
-			"errors":  validationErrors,
-		})
+	app, fostered, previous, err := UpdateFosterStatus(r.PathValue("id"), strings.TrimSpace(req.Status))
+	if err != nil {
+		if errors.Is(err, ErrFosterNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
 		return
 	}
 
-	newPet.ID = fmt.Sprintf("pet-%03d", len(pets)+1)
-	newPet.CreatedAt = time.Now()
-
-	mu.Lock()
-	pets = append(pets, newPet)
-	petsByID[newPet.ID] = &pets[len(pets)-1]
-	statusCounts[newPet.Status]++
-	petsByBreed[newPet.Breed] = append(petsByBreed[newPet.Breed], newPet.ID)
-	mu.Unlock()
+	syncFosterToDB(*app)
+	if fostered != nil {
+		syncPetToDB(*fostered)
+	}
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	recordAudit(actor, "foster.status", app.ID, app.Status)
+
+	// Only a real decision is news to the applicant; re-saving an
+	// application must not send the same email again.
+	if app.Status != "Pending" && app.Status != previous {
+		body := fmt.Sprintf("Hi %s,\n\nThank you for applying to foster with us. Unfortunately we can't "+
+			"place a pet with you right now, but we'll keep your details on file.\n\nPawtner Hope Foundation", app.Name)
+		if app.Status == "Approved" {
+			body = fmt.Sprintf("Hi %s,\n\nGreat news — your foster application has been approved! "+
+				"Our team will contact you to arrange the next steps.\n\nPawtner Hope Foundation", app.Name)
+		}
+		enqueueNotification(NotificationJob{
+			To:        app.Email,
+			Subject:   "Your Foster Application - Pawtner Hope",
+			Body:      body,
+			JobType:   "foster-status",
+			PlainText: true,
+		})
+	}
 
-	syncPetToDB(newPet)
-	log.Printf("[INFO] Pet added: ID=%s, Name=%s, Species=%s", newPet.ID, newPet.Name, newPet.Species)
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
+	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Pet added successfully",
-		"data":    newPet,
+		"message": "Foster application " + strings.ToLower(app.Status),
+		"data":    app,
 	})
 }
 
-func updatePetHandler(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/pets/")
-	petID := path
-
-	var update Pet
+// getEventsHandler lists upcoming published events. ?past=true includes
+// finished ones; admins can pass ?drafts=true to include unpublished ones.
+func getEventsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	includePast := query.Get("past") == "true"
+	includeDrafts := query.Get("drafts") == "true" && isAdminRequest(r)
+	respondList(w, r, listEvents(time.Now(), includePast, includeDrafts))
+}
 
-	// 8. JSON MARSHAL AND UNMARSHAL
-	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-		log.Printf("[ERROR] Failed to decode update JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON data")
+func createEventHandler(w http.ResponseWriter, r *http.Request) {
+	var req Event
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode event JSON: %v", err)
+		respondDecodeError(w, err)
 		return
 	}
 	defer r.Body.Close()
 
-	// 5. FUNCTIONS AND ERROR HANDLING
-	pet, err := UpdatePet(petID, update)
+	event, err := CreateEvent(req)
 	if err != nil {
-		if errors.Is(err, ErrPetNotFound) {
-			respondError(w, http.StatusNotFound, err.Error())
-		} else {
-			respondError(w, http.StatusBadRequest, err.Error())
-		}
+		respondErrorFor(w, http.StatusBadRequest, err)
 		return
 	}
 
-	log.Printf("[INFO] Pet updated: ID=%s", petID)
-	syncPetToDB(*pet)
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	recordAudit(actor, "event.create", event.ID, event.Title)
+
+	log.Printf("[INFO] Event created: ID=%s, Title=%s", event.ID, event.Title)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"success": true,
-		"message": "Pet updated successfully",
-		"data":    pet,
+		"message": "Event created",
+		"data":    event,
 	})
 }
 
-func deletePetHandler(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/api/pets/")
-	petID := path
+func updateEventHandler(w http.ResponseWriter, r *http.Request) {
+	var req Event
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode event JSON: %v", err)
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
 
-	// 5. FUNCTIONS AND ERROR HANDLING
-	if err := DeletePet(petID); err != nil {
-		if errors.Is(err, ErrPetNotFound) {
-			respondError(w, http.StatusNotFound, err.Error())
+	event, err := UpdateEvent(r.PathValue("id"), req)
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
 		} else {
-			respondError(w, http.StatusInternalServerError, err.Error())
+			respondErrorFor(w, http.StatusBadRequest, err)
 		}
 		return
 	}
 
-	log.Printf("[INFO] Pet deleted: ID=%s", petID)
-	deletePetFromDB(petID)
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
+	}
+	recordAudit(actor, "event.update", event.ID, event.Title)
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Pet deleted successfully",
+		"message": "Event updated",
+		"data":    event,
 	})
 }
 
-func getServicesHandler(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	category := query.Get("category")
-
-	var result []Service
+func deleteEventHandler(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	if err := DeleteEvent(eventID); err != nil {
+		respondErrorFor(w, http.StatusNotFound, err)
+		return
+	}
 
-	// 2. CONTROL FLOW and LOOPING
-	if category == "" {
-		result = services
-	} else {
-		for _, service := range services {
-			if service.Category == category {
-				result = append(result, service)
-			}
-		}
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
 	}
+	recordAudit(actor, "event.delete", eventID, "")
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"count":   len(result),
-		"data":    result,
+		"message": "Event deleted",
 	})
 }
 
-func getBookingsHandler(w http.ResponseWriter, _ *http.Request) {
-	mu.Lock()
-	result := make([]ServiceBooking, len(bookings))
-	copy(result, bookings)
-	mu.Unlock()
+func linkAdoptionFeeHandler(w http.ResponseWriter, r *http.Request) {
+	inquiryID := r.PathValue("id")
+
+	var req struct {
+		DonationID string `json:"donationId"`
+		FeePaid    bool   `json:"feePaid"` // paid offline, no donation to link
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	var inquiry *AdoptionInquiry
+	var err error
+	if req.DonationID == "" && req.FeePaid {
+		inquiry, err = RecordAdoptionFee(inquiryID)
+	} else {
+		inquiry, err = LinkAdoptionFee(inquiryID, req.DonationID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInquiryNotFound) || errors.Is(err, ErrDonationNotFound) {
+			respondErrorFor(w, http.StatusNotFound, err)
+		} else if errors.Is(err, ErrFeeAlreadyPaid) || errors.Is(err, ErrDonationLinked) {
+			respondErrorFor(w, http.StatusConflict, err)
+		} else {
+			respondErrorFor(w, http.StatusBadRequest, err)
+		}
+		return
+	}
 
+	syncInquiryToDB(*inquiry)
+	log.Printf("[INFO] Adoption fee recorded: Inquiry=%s, Donation=%s, Amount=%.2f", inquiry.ID, inquiry.DonationID, inquiry.FeeAmount)
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"count":   len(result),
-		"data":    result,
+		"message": "Adoption fee linked successfully",
+		"data":    inquiry,
 	})
 }
 
-func createBookingHandler(w http.ResponseWriter, r *http.Request) {
-	var booking ServiceBooking
+func resendReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	donationID := r.PathValue("id")
 
-	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
-		log.Printf("[ERROR] Failed to decode booking JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON data")
+	var req struct {
+		Email string `json:"email"` // optional; defaults to the donor's email
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondDecodeError(w, err)
+			return
+		}
+		defer r.Body.Close()
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !isValidEmail(req.Email) {
+		respondError(w, http.StatusBadRequest, "Invalid email address")
 		return
 	}
-	defer r.Body.Close()
 
-	// Validate required fields
-	if booking.ServiceID == "" || booking.OwnerName == "" || booking.Email == "" {
-		respondError(w, http.StatusBadRequest, "Service ID, owner name, and email are required")
+	mu.RLock()
+	found := findDonation(donationID)
+	var donation Donation
+	if found != nil {
+		donation = *found
+	}
+	mu.RUnlock()
+
+	if found == nil {
+		respondErrorFor(w, http.StatusNotFound, ErrDonationNotFound)
+		return
+	}
+	if donation.Status != "Completed" {
+		respondError(w, http.StatusBadRequest, "Only completed donations have receipts")
 		return
 	}
 
-	booking.ID = fmt.Sprintf("book-%03d", len(bookings)+1)
-	booking.BookedAt = time.Now()
-	booking.Status = "Pending"
+	if req.Email != "" {
+		donation.DonorEmail = req.Email
+	}
+	sendDonationReceipt(donation, GenerateReceipt(donation))
 
-	mu.Lock()
-	bookings = append(bookings, booking)
-	bookingsByID[booking.ID] = &bookings[len(bookings)-1]
-	if stats, exists := serviceStats[booking.ServiceID]; exists {
-		stats["bookings"] = stats["bookings"].(int) + 1
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
 	}
-	mu.Unlock()
+	recordAudit(actor, "donation.resend-receipt", donation.ID, "sent to "+donation.DonorEmail)
 
-	log.Printf("[INFO] Booking created: ID=%s, Service=%s, Owner=%s", booking.ID, booking.ServiceID, booking.OwnerName)
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
+	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Booking created successfully",
-		"data":    booking,
+		"message": "Receipt sent to " + donation.DonorEmail,
 	})
 }
 
-func submitContactHandler(w http.ResponseWriter, r *http.Request) {
-	var contact ContactForm
-
-	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
-		log.Printf("[ERROR] Failed to decode contact JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON data")
-		return
-	}
-	defer r.Body.Close()
-
-	// Validate required fields
-	if contact.Name == "" || contact.Email == "" || contact.Message == "" {
-		respondError(w, http.StatusBadRequest, "Name, email, and message are required")
+// requestReceiptHandler lets a signed-in donor have the receipt for a
+// completed donation emailed to them, at most once per receiptRequestInterval.
+// Only the donor (by account or email) or an admin may ask.
+func requestReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	donationID := r.PathValue("id")
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
 		return
 	}
 
-	contact.SentAt = time.Now()
 	mu.Lock()
-	contactMessages = append(contactMessages, contact)
+	found := findDonation(donationID)
+	var donation Donation
+	if found != nil {
+		donation = *found
+	}
+	allowed := found != nil && (user.IsAdmin || (donation.UserID != "" && donation.UserID == user.ID) ||
+		strings.EqualFold(donation.DonorEmail, user.Email))
+	var wait time.Duration
+	if allowed && donation.Status == "Completed" {
+		if last, ok := receiptRequests[donationID]; ok {
+			wait = receiptRequestInterval - time.Since(last)
+		}
+		if wait <= 0 {
+			receiptRequests[donationID] = time.Now()
+		}
+	}
 	mu.Unlock()
 
-	log.Printf("[INFO] Contact message received from: %s (%s)", contact.Name, contact.Email)
+	if found == nil {
+		respondErrorFor(w, http.StatusNotFound, ErrDonationNotFound)
+		return
+	}
+	if !allowed {
+		respondError(w, http.StatusForbidden, "You can only request receipts for your own donations")
+		return
+	}
+	if donation.Status != "Completed" {
+		respondError(w, http.StatusBadRequest, "Only completed donations have receipts")
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondError(w, http.StatusTooManyRequests, "A receipt was just sent. Please wait a minute before asking again.")
+		return
+	}
 
-	// 10. CONCURRENCY
-	go func() {
-		notificationCh <- NotificationJob{
-			To:      contact.Email,
-			Subject: "Thank you for contacting Pawtner Hope",
-			Body:    fmt.Sprintf("Dear %s, we received your message and will get back to you soon.", contact.Name),
-			JobType: "contact",
-		}
-	}()
+	sendDonationReceipt(donation, GenerateReceipt(donation))
+	log.Printf("[INFO] Receipt requested for donation %s by %s", donation.ID, user.Email)
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Message sent successfully",
+		"message": "Receipt sent to " + maskEmail(donation.DonorEmail),
 	})
 }
 
-func registerHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email    string `json:"email"`
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Failed to decode registration JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON")
+// getFailedLoginsHandler lists rejected logins, optionally within ?from= and ?to=.
+func getFailedLoginsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r.URL.Query())
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
 		return
 	}
-	defer r.Body.Close()
 
-	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
-	req.Username = strings.TrimSpace(req.Username)
-	if req.Email == "" || req.Username == "" || req.Password == "" {
-		respondError(w, http.StatusBadRequest, "Email, username and password are required")
-		return
+	mu.RLock()
+	result := make([]FailedLogin, 0, len(failedLogins))
+	for _, entry := range failedLogins {
+		if inDateRange(entry.Time, from, to) {
+			result = append(result, entry)
+		}
 	}
+	mu.RUnlock()
 
+	respondList(w, r, result)
+}
+
+// MarkContactHandled records that staff have responded to a contact message.
+func MarkContactHandled(id string) (ContactForm, error) {
 	mu.Lock()
-	_, alreadyExists := usersByEmail[req.Email]
-	_, pendingExists := pendingRegs[req.Email]
-	mu.Unlock()
-	if alreadyExists || pendingExists {
-		respondError(w, http.StatusConflict, ErrUserAlreadyExists.Error())
-		return
-	}
+	defer mu.Unlock()
 
-	code := generateOTP()
-	pending := &PendingRegistration{
-		Email:          req.Email,
-		Username:       req.Username,
-		HashedPassword: hashPassword(req.Password),
-		Code:           code,
-		ExpiresAt:      time.Now().Add(5 * time.Minute),
+	for i := range contactMessages {
+		if contactMessages[i].ID == id {
+			contactMessages[i].Handled = true
+			return contactMessages[i], nil
+		}
 	}
-	mu.Lock()
-	pendingRegs[req.Email] = pending
-	mu.Unlock()
+	return ContactForm{}, ErrContactNotFound
+}
 
-	// Send OTP email asynchronously
-	go func() {
-		html, err := renderTemplate(otpEmailTpl, map[string]string{
-			"Username": req.Username,
-			"Code":     code,
-		})
+// getContactMessagesHandler lists contact messages oldest first;
+// ?handled=false keeps only those still waiting on a reply.
+func getContactMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	var handled *bool
+	if v := r.URL.Query().Get("handled"); v != "" {
+		b, err := strconv.ParseBool(v)
 		if err != nil {
-			log.Printf("[EMAIL] Failed to render OTP template: %v", err)
+			respondError(w, http.StatusBadRequest, "handled must be true or false")
 			return
 		}
-		SendEmailWithRetry(req.Email, "Your Pawtner Hope Verification Code 🐾", html, 3)
-	}()
+		handled = &b
+	}
 
-	log.Printf("[INFO] OTP sent to %s (expires in 5 min)", req.Email)
-	respondJSON(w, http.StatusAccepted, map[string]interface{}{
-		"success": true,
-		"message": "Verification code sent to your email. It expires in 5 minutes.",
-	})
+	mu.RLock()
+	result := make([]contactMessageView, 0, len(contactMessages))
+	for _, m := range contactMessages {
+		if handled == nil || m.Handled == *handled {
+			result = append(result, contactMessageView{ContactForm: m, Handled: m.Handled})
+		}
+	}
+	mu.RUnlock()
+
+	respondList(w, r, result)
 }
 
-func verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email string `json:"email"`
-		Code  string `json:"code"`
+func markContactHandledHandler(w http.ResponseWriter, r *http.Request) {
+	message, err := MarkContactHandled(r.PathValue("id"))
+	if err != nil {
+		respondErrorFor(w, http.StatusNotFound, err)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid JSON")
-		return
+	actor := "unknown"
+	if admin, err := authenticate(r); err == nil {
+		actor = admin.Email
 	}
-	defer r.Body.Close()
+	recordAudit(actor, "contact.handled", message.ID, message.Email)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Message marked as handled",
+		"data":    contactMessageView{ContactForm: message, Handled: message.Handled},
+	})
+}
 
-	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
-	req.Code = strings.TrimSpace(req.Code)
+func getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	mu.RLock()
+	result := make([]AuditEntry, len(auditLog))
+	copy(result, auditLog)
+	mu.RUnlock()
 
-	mu.Lock()
-	pending, exists := pendingRegs[req.Email]
-	mu.Unlock()
+	respondList(w, r, result)
+}
 
-	if !exists {
-		respondError(w, http.StatusBadRequest, "No pending registration for this email. Please sign up again.")
-		return
+// rateLimiter allows each key at most limit hits per window. Windows are
+// fixed and start at a key's first hit.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// maxRateLimiterKeys bounds a limiter's memory; past it, finished windows
+// are swept before a new key is added.
+const maxRateLimiterKeys = 10000
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, hits: make(map[string]*rateWindow)}
+}
+
+// allow records a hit for key and reports whether it is within the limit,
+// and if not, how long until the key's window resets. A limit of 0 or less
+// allows everything.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
 	}
-	if time.Now().After(pending.ExpiresAt) {
-		mu.Lock()
-		delete(pendingRegs, req.Email)
-		mu.Unlock()
-		respondError(w, http.StatusBadRequest, "Verification code has expired. Please sign up again.")
-		return
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.hits[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		if !ok && len(l.hits) >= maxRateLimiterKeys {
+			for k, old := range l.hits {
+				if now.Sub(old.start) >= l.window {
+					delete(l.hits, k)
+				}
+			}
+		}
+		w = &rateWindow{start: now}
+		l.hits[key] = w
 	}
-	if req.Code != pending.Code {
-		respondError(w, http.StatusBadRequest, "Invalid verification code.")
-		return
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
 	}
+	w.count++
+	return true, 0
+}
 
-	// Create user with pre-hashed password
-	user := User{
-		ID:        fmt.Sprintf("usr-%03d", len(users)+1),
-		Email:     pending.Email,
-		Username:  pending.Username,
-		Password:  pending.HashedPassword,
-		Role:      "user",
-		CreatedAt: time.Now(),
-		IsActive:  true,
+// upiEscape query-escapes a UPI parameter, keeping "@" readable in VPAs and
+// using %20 for spaces, which some UPI apps require.
+func upiEscape(v string) string {
+	return strings.NewReplacer("+", "%20", "%40", "@").Replace(url.QueryEscape(v))
+}
+
+// upiDeeplink builds a upi://pay intent for the configured payee. ref is
+// echoed back by the UPI app so the payment can be matched to a donation.
+func upiDeeplink(amount float64, ref string) string {
+	return fmt.Sprintf("upi://pay?pa=%s&pn=%s&am=%.2f&cu=INR&tn=%s&tr=%s",
+		upiEscape(upiPayeeVPA), upiEscape(upiPayeeName), amount, upiEscape(upiNote), upiEscape(ref))
+}
+
+// CreatePendingUPIDonation registers a pending deeplink donation for amount
+// and returns it with its UPI link.
+func CreatePendingUPIDonation(amount float64, donorName, donorEmail string) (*Donation, string, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, "", fmt.Errorf("%w: amount must be a number", ErrInvalidPayment)
+	}
+	if amount < float64(upiMinAmount) || amount > float64(upiMaxAmount) {
+		return nil, "", fmt.Errorf("%w: amount must be between %d and %d", ErrInvalidPayment, upiMinAmount, upiMaxAmount)
 	}
 
 	mu.Lock()
-	users = append(users, user)
-	usersByEmail[user.Email] = &users[len(users)-1]
-	delete(pendingRegs, req.Email)
+	donation := Donation{
+		ID:                 fmt.Sprintf("don-%03d", len(donations)+1),
+		DonorName:          donorName,
+		DonorEmail:         donorEmail,
+		Amount:             amount,
+		PaymentMethod:      "UPI",
+		Status:             "Pending",
+		CreatedAt:          time.Now(),
+		PaymentViaDeeplink: true,
+	}
+	donations = append(donations, donation)
 	mu.Unlock()
 
-	syncUserToDB(user)
-	sendWelcomeEmail(&user)
-	log.Printf("[INFO] User verified and created: %s (%s)", user.Username, user.Email)
-
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"message": "Account created successfully! You can now log in.",
-		"data":    user,
-	})
+	syncDonationToDB(donation)
+	return &donation, upiDeeplink(amount, donation.ID), nil
 }
 
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+func upiLinkHandler(w http.ResponseWriter, r *http.Request) {
+	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+	if ok, wait := upiLinkLimiter.allow(ip, time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		respondError(w, http.StatusTooManyRequests, "Too many payment links requested. Please wait a minute and try again.")
+		return
 	}
 
+	var req struct {
+		Amount float64 `json:"amount"`
+		Name   string  `json:"name"`
+		Email  string  `json:"email"`
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Failed to decode login JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		respondDecodeError(w, err)
 		return
 	}
 	defer r.Body.Close()
 
-	// 5. FUNCTIONS AND ERROR HANDLING
-	token, err := Login(req.Email, req.Password)
+	donation, link, err := CreatePendingUPIDonation(req.Amount, req.Name, req.Email)
 	if err != nil {
-		log.Printf("[WARN] Failed login attempt for: %s", req.Email)
-		respondError(w, http.StatusUnauthorized, err.Error())
+		respondErrorFor(w, http.StatusBadRequest, err)
 		return
 	}
 
-	log.Printf("[INFO] User logged in: %s", req.Email)
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	log.Printf("[INFO] UPI link issued: Donation=%s, Amount=%.2f", donation.ID, donation.Amount)
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"success": true,
-		"message": "Login successful",
-		"data":    token,
+		"data": map[string]interface{}{
+			"link":       link,
+			"donationId": donation.ID,
+			"amount":     donation.Amount,
+		},
 	})
 }
 
-func meHandler(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-	if tokenStr == "" {
-		respondError(w, http.StatusUnauthorized, "Missing token")
+// ReconcileUPIPayment settles the pending deeplink donation ref once the
+// payment provider reports the outcome. A repeated success callback for the
+// same transaction is accepted again without changing anything.
+func ReconcileUPIPayment(ref, txnID string, amount float64, success bool) (Donation, error) {
+	mu.Lock()
+	found := findDonation(ref)
+	if found == nil || !found.PaymentViaDeeplink {
+		mu.Unlock()
+		return Donation{}, ErrDonationNotFound
+	}
+	if found.Status == "Completed" && success && found.TransactionID == txnID {
+		donation := *found
+		mu.Unlock()
+		return donation, nil
+	}
+	if found.Status != "Pending" {
+		mu.Unlock()
+		return Donation{}, ErrDonationNotPending
+	}
+	if success && math.Abs(found.Amount-amount) >= 0.005 {
+		mu.Unlock()
+		return Donation{}, fmt.Errorf("%w: paid %.2f but the link was for %.2f", ErrInvalidPayment, amount, found.Amount)
+	}
+
+	found.TransactionID = txnID
+	found.Status = "Failed"
+	if success {
+		found.Status = "Completed"
+	}
+	donation := *found
+	mu.Unlock()
+
+	syncDonationToDB(donation)
+	if success {
+		publishEvent(EventDonationCompleted, donation.ID, donation)
+		sendDonationReceipt(donation, GenerateReceipt(donation))
+	}
+	return donation, nil
+}
+
+// validUPICallbackSignature checks the provider's hex HMAC-SHA256 of body.
+func validUPICallbackSignature(body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(upiCallbackSecret))
+	mac.Write(body)
+	sig, err := hex.DecodeString(signature)
+	return err == nil && hmac.Equal(sig, mac.Sum(nil))
+}
+
+// upiCallbackHandler receives the payment provider's result for a deeplink
+// donation. Requests must carry X-Signature, the HMAC of the body under
+// UPI_CALLBACK_SECRET.
+func upiCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if upiCallbackSecret == "" {
+		respondError(w, http.StatusServiceUnavailable, "UPI callback is not configured")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64<<10))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Could not read callback body")
 		return
 	}
-	user, err := ValidateToken(tokenStr)
-	if err != nil {
-		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+	if !validUPICallbackSignature(body, r.Header.Get("X-Signature")) {
+		log.Printf("[WARN] UPI callback with a bad signature from %s", r.RemoteAddr)
+		respondError(w, http.StatusUnauthorized, "Invalid signature")
 		return
 	}
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data": map[string]interface{}{
-			"id":        user.ID,
-			"email":     user.Email,
-			"username":  user.Username,
-			"role":      user.Role,
-			"isadmin":   user.IsAdmin,
-			"createdAt": user.CreatedAt,
-		},
-	})
-}
-
-func createAdoptionInquiryHandler(w http.ResponseWriter, r *http.Request) {
-	var inquiry AdoptionInquiry
 
-	// 8. JSON MARSHAL AND UNMARSHAL
-	if err := json.NewDecoder(r.Body).Decode(&inquiry); err != nil {
-		log.Printf("[ERROR] Failed to decode adoption inquiry JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON")
+	var callback struct {
+		Ref    string  `json:"ref"` // the tr= we put in the deeplink
+		TxnID  string  `json:"txnId"`
+		Status string  `json:"status"` // SUCCESS or FAILURE
+		Amount float64 `json:"amount"`
+	}
+	if err := json.Unmarshal(body, &callback); err != nil {
+		respondDecodeError(w, err)
 		return
 	}
-	defer r.Body.Close()
-
-	if inquiry.PetID == "" || inquiry.AdopterName == "" || inquiry.Email == "" {
-		respondError(w, http.StatusBadRequest, "PetID, adopter name and email are required")
+	if callback.Ref == "" || callback.TxnID == "" || (callback.Status != "SUCCESS" && callback.Status != "FAILURE") {
+		respondError(w, http.StatusBadRequest, "ref, txnId and a SUCCESS or FAILURE status are required")
 		return
 	}
 
-	inquiry.ID = fmt.Sprintf("inq-%03d", len(inquiries)+1)
-	inquiry.Status = "Pending"
-	inquiry.CreatedAt = time.Now()
-
-	mu.Lock()
-	inquiries = append(inquiries, inquiry)
-	mu.Unlock()
-
-	syncInquiryToDB(inquiry)
-	log.Printf("[INFO] Adoption inquiry: Pet=%s, Adopter=%s (%s)", inquiry.PetID, inquiry.AdopterName, inquiry.Email)
-
-	// 10. CONCURRENCY
-	go func() {
-		notificationCh <- NotificationJob{
-			To:      inquiry.Email,
-			Subject: "Adoption Inquiry Received - Pawtner Hope",
-			Body:    fmt.Sprintf("Dear %s, your adoption inquiry for pet %s has been received.", inquiry.AdopterName, inquiry.PetID),
-			JobType: "adoption",
+	donation, err := ReconcileUPIPayment(callback.Ref, callback.TxnID, callback.Amount, callback.Status == "SUCCESS")
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, ErrDonationNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrDonationNotPending):
+			status = http.StatusConflict
 		}
-	}()
-
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"message": "Adoption inquiry submitted successfully",
-		"data":    inquiry,
-	})
-}
-
-func getAdoptionInquiriesHandler(w http.ResponseWriter, _ *http.Request) {
-	mu.Lock()
-	result := make([]AdoptionInquiry, len(inquiries))
-	copy(result, inquiries)
-	mu.Unlock()
+		log.Printf("[PAYMENT] UPI callback for %s rejected: %v", callback.Ref, err)
+		respondErrorFor(w, status, err)
+		return
+	}
 
+	log.Printf("[PAYMENT] UPI callback: %s %s (txn %s)", donation.ID, donation.Status, donation.TransactionID)
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"count":   len(result),
-		"data":    result,
+		"data":    map[string]string{"donationId": donation.ID, "status": donation.Status},
 	})
 }
 
@@ -1753,18 +8216,34 @@ func createDonationHandler(w http.ResponseWriter, r *http.Request) {
 	var donation Donation
 
 	// 8. JSON MARSHAL AND UNMARSHAL
-	if err := json.NewDecoder(r.Body).Decode(&donation); err != nil {
+	trapped, err := decodeForm(r, &donation)
+	if err != nil {
 		log.Printf("[ERROR] Failed to decode donation JSON: %v", err)
-		respondError(w, http.StatusBadRequest, "Invalid JSON")
+		respondDecodeError(w, err)
 		return
 	}
 	defer r.Body.Close()
+	if trapped {
+		respondHoneypot(w, r, "donation")
+		return
+	}
+
+	if !checkCaptcha(w, r, donation.CaptchaToken) {
+		return
+	}
+	donation.CaptchaToken = ""
+
+	// Link the donation to the donor's account when they are logged in.
+	donation.UserID = ""
+	if user, err := authenticate(r); err == nil {
+		donation.UserID = user.ID
+	}
 
 	// 5. FUNCTIONS AND ERROR HANDLING
 	receipt, err := ProcessDonation(&donation)
 	if err != nil {
 		log.Printf("[ERROR] Donation processing failed: %v", err)
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondErrorFor(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -1772,9 +8251,12 @@ func createDonationHandler(w http.ResponseWriter, r *http.Request) {
 		donation.Amount, donation.DonorName, donation.DonorEmail, donation.PaymentViaDeeplink)
 
 	// 11. GOROUTINES AND CHANNELS — send to payment processor
-	go func(d Donation) {
-		paymentCh <- d
-	}(donation)
+	enqueuePayment(donation)
+
+	if target, ok := donationRedirectURL(r, receipt.ReceiptID); ok {
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return
+	}
 
 	receiptHint := ""
 	if !donation.PaymentViaDeeplink {
@@ -1790,24 +8272,164 @@ func createDonationHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func getDonationsHandler(w http.ResponseWriter, _ *http.Request) {
+// donationRedirectURL returns the thank-you page for receiptID if the request
+// asked to be redirected and a success URL is configured.
+func donationRedirectURL(r *http.Request, receiptID string) (string, bool) {
+	if donationSuccessURL == "" {
+		return "", false
+	}
+	if r.URL.Query().Get("redirect") != "true" && !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return "", false
+	}
+	u, err := url.Parse(donationSuccessURL)
+	if err != nil {
+		return "", false
+	}
+	q := u.Query()
+	q.Set("receipt", receiptID)
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
+func getDonationsHandler(w http.ResponseWriter, r *http.Request) {
 	mu.Lock()
 	result := make([]Donation, len(donations))
 	copy(result, donations)
 	mu.Unlock()
 
+	respondList(w, r, result)
+}
+
+func donationStatementHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := authenticate(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	year := time.Now().Year()
+	if y := r.URL.Query().Get("year"); y != "" {
+		parsed, err := strconv.Atoi(y)
+		if err != nil || parsed < 2000 || parsed > year {
+			respondError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+		year = parsed
+	}
+
+	statement := BuildGivingStatement(*user, year)
+
+	if r.URL.Query().Get("format") == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="giving-statement-%d.pdf"`, year))
+		w.Write(renderStatementPDF(statement))
+		return
+	}
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"count":   len(result),
-		"data":    result,
+		"data":    statement,
+	})
+}
+
+func pendingCountsHandler(w http.ResponseWriter, _ *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pendingCounts(),
+	})
+}
+
+func weeklyReportHandler(w http.ResponseWriter, _ *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    weeklyDonationSummary(),
+	})
+}
+
+// adoptionFunnel counts each stage from listing to adoption for pets and
+// inquiries created in [from, to). A zero from or to leaves that end open.
+func adoptionFunnel(from, to time.Time) map[string]int {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	funnel := map[string]int{"listed": 0, "inquiries": 0, "approved": 0, "adopted": 0}
+	for _, pet := range pets {
+		if !inDateRange(pet.CreatedAt, from, to) {
+			continue
+		}
+		funnel["listed"]++
+		if pet.Status == "Adopted" {
+			funnel["adopted"]++
+		}
+	}
+	for _, inq := range inquiries {
+		if !inDateRange(inq.CreatedAt, from, to) {
+			continue
+		}
+		funnel["inquiries"]++
+		if inq.Status == "Approved" {
+			funnel["approved"]++
+		}
+	}
+	return funnel
+}
+
+// parseDateRange reads ?from= and ?to= as dates in the display timezone.
+// Either may be absent (zero); to is exclusive, covering the whole "to" day.
+func parseDateRange(query url.Values) (from, to time.Time, err error) {
+	if v := query.Get("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, displayLocation); err != nil {
+			return from, to, errors.New("from must be a date like 2024-01-31")
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, displayLocation); err != nil {
+			return from, to, errors.New("to must be a date like 2024-01-31")
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
+
+// inDateRange reports whether t falls in [from, to), treating a zero bound as open.
+func inDateRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+func getFunnelHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r.URL.Query())
+	if err != nil {
+		respondErrorFor(w, http.StatusBadRequest, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    adoptionFunnel(from, to),
 	})
 }
 
+// copyServiceStats returns a copy of serviceStats taken under mu.
+func copyServiceStats() map[string]map[string]interface{} {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	out := make(map[string]map[string]interface{}, len(serviceStats))
+	for id, stats := range serviceStats {
+		entry := make(map[string]interface{}, len(stats))
+		for k, v := range stats {
+			entry[k] = v
+		}
+		out[id] = entry
+	}
+	return out
+}
+
 func getStatisticsHandler(w http.ResponseWriter, r *http.Request) {
 	stats := calculateStatistics()
 	stats["serverVersion"] = serverVersion
 	stats["uptime"] = time.Since(serverStartTime).String()
-	stats["serviceStats"] = serviceStats
+	stats["serviceStats"] = copyServiceStats()
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
@@ -1815,6 +8437,254 @@ func getStatisticsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// impactCacheTTL is how long the landing-page impact numbers are reused
+// before being recomputed.
+const impactCacheTTL = 30 * time.Second
+
+var impactCache struct {
+	sync.Mutex
+	data      map[string]interface{}
+	expiresAt time.Time
+}
+
+// calculateImpact totals the numbers shown in the homepage impact block.
+// "rescued" counts every pet ever listed, so deleting a record doesn't
+// shrink it; the rest come from the live data.
+func calculateImpact() map[string]interface{} {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var funds float64
+	for _, d := range donations {
+		if d.Status == "Completed" {
+			funds += d.Amount
+		}
+	}
+	return map[string]interface{}{
+		"rescued":     petSeq,
+		"adopted":     statusCounts["Adopted"],
+		"fundsRaised": funds,
+		"volunteers":  len(volunteers),
+	}
+}
+
+// resetImpactCache drops the cached impact numbers so the next request
+// recomputes them.
+func resetImpactCache() {
+	impactCache.Lock()
+	impactCache.data = nil
+	impactCache.Unlock()
+}
+
+func impactHandler(w http.ResponseWriter, r *http.Request) {
+	impactCache.Lock()
+	if impactCache.data == nil || time.Now().After(impactCache.expiresAt) {
+		impactCache.data = calculateImpact()
+		impactCache.expiresAt = time.Now().Add(impactCacheTTL)
+	}
+	data := impactCache.data
+	impactCache.Unlock()
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// healthPingTimeout bounds the MongoDB ping made by the health check.
+const healthPingTimeout = 2 * time.Second
+
+// pingMongo checks that the database answers. A server that never managed to
+// connect counts as unreachable.
+func pingMongo(ctx context.Context) error {
+	client := mongoClient
+	if client == nil {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	return client.Ping(ctx, nil)
+}
+
+// healthHandler reports database reachability, uptime and queue depths for
+// deployment monitoring. It returns 503 only when MongoDB was configured but
+// doesn't answer, and never takes mu so it stays responsive under load.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	status, database := "ok", "disabled"
+	statusCode := http.StatusOK
+	if mongoConfigured {
+		database = "ok"
+		if err := pingMongo(r.Context()); err != nil {
+			log.Printf("[HEALTH] MongoDB unreachable: %v", err)
+			status, database = "degraded", "unreachable"
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
+	uptime := time.Since(serverStartTime)
+	respondJSON(w, statusCode, map[string]interface{}{
+		"success": statusCode == http.StatusOK,
+		"data": map[string]interface{}{
+			"status":        status,
+			"database":      database,
+			"uptime":        uptime.Round(time.Second).String(),
+			"uptimeSeconds": int64(uptime.Seconds()),
+			"queues": map[string]int{
+				"notifications": len(notificationCh),
+				"payments":      len(paymentCh),
+			},
+		},
+	})
+}
+
+// newHandler wraps the router in the middleware every request passes
+// through, so CORS preflights are answered the same way for HTML pages and
+// API routes.
+func newHandler() http.HandlerFunc {
+	return recoverPanic(enableCORS(newRouter().ServeHTTP))
+}
+
+// newRouter registers every route on a method-aware ServeMux (Go 1.22 patterns).
+// Handlers read path segments with r.PathValue.
+func newRouter() http.Handler {
+	mux := http.NewServeMux()
+
+	// Serve HTML files with error handling
+	mux.HandleFunc("GET /", serveHTMLFile("index.html"))
+	mux.HandleFunc("GET /about", serveHTMLFile("index.html"))
+	mux.HandleFunc("GET /service.html", serveHTMLFile("service.html"))
+	mux.HandleFunc("GET /adoption.html", serveHTMLFile("adoption.html"))
+	mux.HandleFunc("GET /donate.html", serveHTMLFile("donate.html"))
+	mux.HandleFunc("GET /auth.html", serveHTMLFile("auth.html"))
+	mux.HandleFunc("GET /admin.html", serveHTMLFile("admin.html"))
+	mux.HandleFunc("GET /dashboard.html", serveHTMLFile("dashboard.html"))
+
+	mux.HandleFunc("GET /api/pets", getPetsHandler)
+	mux.HandleFunc("POST /api/pets", requireAdmin(addPetHandler))
+	mux.HandleFunc("GET /api/pets/featured", getFeaturedPetHandler)
+	mux.HandleFunc("GET /api/pets/facets", getPetFacetsHandler)
+	mux.HandleFunc("POST /api/pets/bulk-delete", requireAdmin(bulkDeletePetsHandler))
+	mux.HandleFunc("POST /api/pets/bulk-archive", requireAdmin(bulkArchivePetsHandler))
+	mux.HandleFunc("POST /api/pets/import-csv", requireAdmin(importPetsCSVHandler))
+	mux.HandleFunc("GET /api/pets/export.csv", requireAdmin(exportPetsCSVHandler))
+	mux.HandleFunc("GET /api/pets/missing-photos", requireAdmin(getPetsMissingPhotosHandler))
+	mux.HandleFunc("GET /api/pets/broken-photos", requireAdmin(getPetsBrokenPhotosHandler))
+	mux.HandleFunc("GET /api/pets/checkup-due", requireAdmin(getPetsCheckupDueHandler))
+	mux.HandleFunc("GET /api/pets/{$}", getPetByIDHandler)
+	mux.HandleFunc("GET /api/pets/{id}", getPetByIDHandler)
+	mux.HandleFunc("GET /api/pets/{id}/{$}", getPetByIDHandler)
+	mux.HandleFunc("PUT /api/pets/{id}", requireAdmin(updatePetHandler))
+	mux.HandleFunc("DELETE /api/pets/{id}", requireAdmin(deletePetHandler))
+	mux.HandleFunc("POST /api/pets/{id}/feature", requireAdmin(featurePetHandler))
+	mux.HandleFunc("PUT /api/pets/{id}/feature", requireAdmin(featurePetHandler))
+	mux.HandleFunc("GET /api/pets/{id}/similar", getSimilarPetsHandler)
+	mux.HandleFunc("POST /api/pets/{id}/waitlist", joinWaitlistHandler)
+	mux.HandleFunc("POST /api/pets/{id}/favorite", favoritePetHandler)
+	mux.HandleFunc("DELETE /api/pets/{id}/favorite", unfavoritePetHandler)
+
+	mux.HandleFunc("GET /api/services", getServicesHandler)
+	mux.HandleFunc("POST /api/services", requireAdmin(createServiceHandler))
+	mux.HandleFunc("GET /api/services/{id}", getServiceHandler)
+	mux.HandleFunc("PUT /api/services/{id}", requireAdmin(updateServiceHandler))
+	mux.HandleFunc("DELETE /api/services/{id}", requireAdmin(deleteServiceHandler))
+	mux.HandleFunc("POST /api/services/{id}/rate", rateServiceHandler)
+	mux.HandleFunc("GET /api/packages", getPackagesHandler)
+	mux.HandleFunc("POST /api/packages", requireAdmin(createPackageHandler))
+	mux.HandleFunc("POST /api/packages/{id}/book", bookPackageHandler)
+	mux.HandleFunc("GET /api/bookings", getBookingsHandler)
+	mux.HandleFunc("POST /api/bookings", createBookingHandler)
+	mux.HandleFunc("GET /api/bookings/{id}", getBookingHandler)
+	mux.HandleFunc("DELETE /api/bookings/{id}", cancelBookingHandler)
+	mux.HandleFunc("PUT /api/bookings/{id}/complete", requireAdmin(completeBookingHandler))
+	mux.HandleFunc("POST /api/contact", submitContactHandler)
+	mux.HandleFunc("GET /api/testimonials", getTestimonialsHandler)
+	mux.HandleFunc("POST /api/testimonials", submitTestimonialHandler)
+	mux.HandleFunc("POST /api/testimonials/{id}/approve", requireAdmin(approveTestimonialHandler))
+	mux.HandleFunc("GET /api/volunteers", requireAdmin(getVolunteersHandler))
+	mux.HandleFunc("POST /api/volunteers", submitVolunteerHandler)
+	mux.HandleFunc("GET /api/events", getEventsHandler)
+	mux.HandleFunc("POST /api/events", requireAdmin(createEventHandler))
+	mux.HandleFunc("PUT /api/events/{id}", requireAdmin(updateEventHandler))
+	mux.HandleFunc("DELETE /api/events/{id}", requireAdmin(deleteEventHandler))
+	mux.HandleFunc("GET /api/health", healthHandler)
+	mux.HandleFunc("GET /api/impact", impactHandler)
+	mux.HandleFunc("GET /api/statistics", getStatisticsHandler)
+	mux.HandleFunc("GET /api/statistics/funnel", getFunnelHandler)
+	mux.HandleFunc("GET /api/admin/pending-counts", requireAdmin(pendingCountsHandler))
+	mux.HandleFunc("GET /api/admin/reports/weekly", requireAdmin(weeklyReportHandler))
+	mux.HandleFunc("PUT /api/admin/users/{id}/role", requireAdmin(setUserRoleHandler))
+	mux.HandleFunc("GET /api/admin/audit-log", requireAdmin(getAuditLogHandler))
+	mux.HandleFunc("GET /api/admin/failed-logins", requireAdmin(getFailedLoginsHandler))
+	mux.HandleFunc("GET /api/admin/contact-messages", requireAdmin(getContactMessagesHandler))
+	mux.HandleFunc("PUT /api/admin/contact-messages/{id}/handled", requireAdmin(markContactHandledHandler))
+
+	mux.HandleFunc("POST /api/auth/register", registerHandler)
+	mux.HandleFunc("POST /api/auth/login", loginHandler)
+	mux.HandleFunc("POST /api/auth/verify", verifyEmailHandler)
+	mux.HandleFunc("POST /api/auth/logout", logoutHandler)
+	mux.HandleFunc("POST /api/auth/refresh", refreshTokenHandler)
+	mux.HandleFunc("GET /api/auth/me", meHandler)
+	mux.HandleFunc("DELETE /api/auth/me", deleteAccountHandler)
+	mux.HandleFunc("GET /api/auth/me/favorites", getFavoritesHandler)
+
+	mux.HandleFunc("GET /api/adoptions", getAdoptionInquiriesHandler)
+	mux.HandleFunc("POST /api/adoptions", createAdoptionInquiryHandler)
+	mux.HandleFunc("GET /api/adoptions/questions", getAdoptionQuestionsHandler)
+	mux.HandleFunc("GET /api/adoptions/{id}", requireAdmin(getAdoptionInquiryHandler))
+	mux.HandleFunc("PUT /api/adoptions/{id}", requireAdmin(updateAdoptionInquiryHandler))
+	mux.HandleFunc("GET /api/foster", requireAdmin(getFosterApplicationsHandler))
+	mux.HandleFunc("POST /api/foster", submitFosterHandler)
+	mux.HandleFunc("PUT /api/foster/{id}", requireAdmin(updateFosterHandler))
+	mux.HandleFunc("POST /api/adoptions/{id}/fee", requireAdmin(linkAdoptionFeeHandler))
+
+	mux.HandleFunc("GET /api/donations", requireAdmin(getDonationsHandler))
+	mux.HandleFunc("POST /api/donations", createDonationHandler)
+	mux.HandleFunc("GET /api/donations/statement", donationStatementHandler)
+	mux.HandleFunc("POST /api/donations/upi-link", upiLinkHandler)
+	mux.HandleFunc("POST /api/donations/upi-callback", upiCallbackHandler)
+	mux.HandleFunc("POST /api/donations/{id}/receipt", requestReceiptHandler)
+	mux.HandleFunc("POST /api/donations/{id}/resend-receipt", requireAdmin(resendReceiptHandler))
+
+	return apiFallback(mux)
+}
+
+// routeMethods are the methods probed when working out a 405's Allow header.
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// apiFallback answers /api/ requests that match no API route with the JSON
+// error shape: 405 with an Allow header when the path exists under another
+// method, 404 otherwise. Left to the mux they would get a plain-text error,
+// or fall through to the "GET /" page handler.
+func apiFallback(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || apiRouteFor(mux, r) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		var allowed []string
+		for _, method := range routeMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if apiRouteFor(mux, probe) {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			respondError(w, http.StatusNotFound, "Endpoint not found")
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		respondError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s is not allowed here; use %s", r.Method, strings.Join(allowed, ", ")))
+	}
+}
+
+// apiRouteFor reports whether r matches a route other than the page catch-all.
+func apiRouteFor(mux *http.ServeMux, r *http.Request) bool {
+	_, pattern := mux.Handler(r)
+	return pattern != "" && pattern != "GET /"
+}
+
 func main() {
 	// Load .env before anything else so SMTP credentials are available.
 	loadEnv(".env")
@@ -1826,10 +8696,144 @@ func main() {
 		log.Println("[SMTP] No GMAIL_USER set \u2014 emails will be skipped")
 	}
 
+	if tz := os.Getenv("DISPLAY_TIMEZONE"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			displayLocation = loc
+		} else {
+			log.Printf("[WARN] Unknown DISPLAY_TIMEZONE %q, using server local time: %v", tz, err)
+		}
+	}
+
+	for _, name := range strings.Split(os.Getenv("RESERVED_USERNAMES"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			reservedUsernames = append(reservedUsernames, name)
+		}
+	}
+	for _, word := range strings.Split(os.Getenv("BLOCKED_USERNAME_WORDS"), ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			blockedUsernameWords = append(blockedUsernameWords, word)
+		}
+	}
+
+	domainsFile := os.Getenv("DISPOSABLE_DOMAINS_FILE")
+	if domainsFile == "" {
+		domainsFile = "disposable_domains.txt"
+	}
+	if domains, err := loadDomainList(domainsFile); err == nil {
+		disposableDomains = domains
+		log.Printf("[INFO] Loaded %d disposable email domains from %s", len(domains), domainsFile)
+	} else {
+		log.Printf("[WARN] Disposable email blocklist not loaded: %v", err)
+	}
+
+	smtpRequireTLS = envBool("SMTP_REQUIRE_TLS", smtpRequireTLS)
+	smtpTLSMinVersion = envTLSVersion("SMTP_TLS_MIN_VERSION", smtpTLSMinVersion)
+
+	loadMongoWritePolicies()
+	corsMaxAge = envInt("CORS_MAX_AGE", corsMaxAge)
+	if status := envInt("CORS_PREFLIGHT_STATUS", corsPreflightStatus); status == http.StatusOK || status == http.StatusNoContent {
+		corsPreflightStatus = status
+	} else {
+		log.Printf("[CONFIG] Ignoring CORS_PREFLIGHT_STATUS=%d; use 200 or 204", status)
+	}
+	shutdownTimeout = envDuration("SHUTDOWN_TIMEOUT", shutdownTimeout)
+	if v, ok := os.LookupEnv("HONEYPOT_FIELD"); ok {
+		honeypotField = strings.TrimSpace(v)
+	}
+	photoCheckEnabled = envBool("PHOTO_CHECK_ENABLED", photoCheckEnabled)
+	photoCheckTimeout = envDuration("PHOTO_CHECK_TIMEOUT", photoCheckTimeout)
+	if v := os.Getenv("ALLOWED_ORIGINS"); v != "" {
+		allowedOrigins = nil
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+				allowedOrigins = append(allowedOrigins, origin)
+			}
+		}
+	}
+	mongoMaxRetries = envInt("MONGO_MAX_RETRIES", mongoMaxRetries)
+	mongoMaxConcurrent = envInt("MONGO_MAX_CONCURRENT", mongoMaxConcurrent)
+	tokenIdleTimeout = envDuration("TOKEN_IDLE_TIMEOUT", tokenIdleTimeout)
+	tokenCleanupInterval = envDuration("TOKEN_CLEANUP_INTERVAL", tokenCleanupInterval)
+	tokenLifetime = envDuration("TOKEN_LIFETIME", tokenLifetime)
+	tokenRefreshWindow = envDuration("TOKEN_REFRESH_WINDOW", tokenRefreshWindow)
+	// MAX_LIST_LIMIT is the old name for MAX_PAGE_SIZE.
+	maxPageSize = envInt("MAX_PAGE_SIZE", envInt("MAX_LIST_LIMIT", maxPageSize))
+	defaultPageSize = envInt("DEFAULT_PAGE_SIZE", defaultPageSize)
+	if defaultPageSize > maxPageSize {
+		defaultPageSize = maxPageSize
+	}
+	youngMinAge = envInt("AGE_YOUNG_MIN", youngMinAge)
+	adultMinAge = envInt("AGE_ADULT_MIN", adultMinAge)
+	seniorMinAge = envInt("AGE_SENIOR_MIN", seniorMinAge)
+	emailRatePerMinute = envInt("EMAIL_RATE_PER_MINUTE", emailRatePerMinute)
+	emailWorkerCount = envInt("EMAIL_WORKERS", emailWorkerCount)
+	otpReminderEnabled = envBool("OTP_REMINDER_ENABLED", otpReminderEnabled)
+	otpReminderWindow = envDuration("OTP_REMINDER_WINDOW", otpReminderWindow)
+	featuredDuration = envDuration("FEATURED_DURATION", featuredDuration)
+	staleListingAge = envDuration("STALE_LISTING_AGE", staleListingAge)
+	staleListingInterval = envDuration("STALE_LISTING_INTERVAL", staleListingInterval)
+	staleListingEmail = envBool("STALE_LISTING_EMAIL", staleListingEmail)
+	pendingDonationTTL = envDuration("PENDING_DONATION_TTL", pendingDonationTTL)
+	receiptRequestInterval = envDuration("RECEIPT_REQUEST_INTERVAL", receiptRequestInterval)
+
+	if v := os.Getenv("ADMIN_EMAIL"); v != "" {
+		adminEmail = v
+	}
+	if v := os.Getenv("UPI_VPA"); v != "" {
+		upiPayeeVPA = v
+	}
+	if v := os.Getenv("UPI_PAYEE_NAME"); v != "" {
+		upiPayeeName = v
+	}
+	if v := os.Getenv("UPI_NOTE"); v != "" {
+		upiNote = v
+	}
+	upiMinAmount = envInt("UPI_MIN_AMOUNT", upiMinAmount)
+	upiMaxAmount = envInt("UPI_MAX_AMOUNT", upiMaxAmount)
+	upiLinkRateLimit = envInt("UPI_LINK_RATE_LIMIT", upiLinkRateLimit)
+	waitlistRateLimit = envInt("WAITLIST_RATE_LIMIT", waitlistRateLimit)
+	maxWaitlistPerPet = envInt("MAX_WAITLIST_PER_PET", maxWaitlistPerPet)
+	failedLoginRecordLimit = envInt("FAILED_LOGIN_RECORD_LIMIT", failedLoginRecordLimit)
+	dedicationNoticeLimit = envInt("DEDICATION_NOTICE_LIMIT", dedicationNoticeLimit)
+	upiCallbackSecret = os.Getenv("UPI_CALLBACK_SECRET")
+	if v := os.Getenv("DONATION_SUCCESS_URL"); v != "" {
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Printf("[WARN] Ignoring DONATION_SUCCESS_URL %q: must be an absolute http(s) URL", v)
+		} else {
+			donationSuccessURL = v
+		}
+	}
+
+	smsEnabled = envBool("SMS_ENABLED", smsEnabled)
+	if sid := os.Getenv("TWILIO_ACCOUNT_SID"); sid != "" {
+		smsSender = twilioSMSSender{
+			AccountSID: sid,
+			AuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+			From:       os.Getenv("TWILIO_FROM_NUMBER"),
+			Client:     &http.Client{Timeout: 10 * time.Second},
+		}
+		log.Printf("[SMS] Twilio configured (enabled=%v)", smsEnabled)
+	}
+
+	captchaEnabled = envBool("CAPTCHA_ENABLED", captchaEnabled)
+	if secret := os.Getenv("CAPTCHA_SECRET"); secret != "" {
+		verifyURL := recaptchaVerifyURL
+		if strings.EqualFold(os.Getenv("CAPTCHA_PROVIDER"), "hcaptcha") {
+			verifyURL = hcaptchaVerifyURL
+		}
+		captchaVerifier = siteVerifyCaptcha{
+			Secret:    secret,
+			VerifyURL: verifyURL,
+			Client:    &http.Client{Timeout: 10 * time.Second},
+		}
+		log.Printf("[CAPTCHA] Verifier configured at %s (enabled=%v)", verifyURL, captchaEnabled)
+	}
+
 	initializeData()
-	startWorkers()
+	workers := startWorkers(context.Background())
 
 	mongoURI := os.Getenv("MONGODB_URI")
+	mongoConfigured = mongoURI != ""
 	if mongoURI == "" {
 		log.Println("⚠ MONGODB_URI not set, running without database")
 	} else {
@@ -1858,108 +8862,8 @@ func main() {
 		}
 	}
 
-	// Serve HTML files with error handling
-	http.HandleFunc("/", recoverPanic(serveHTMLFile("index.html")))
-	http.HandleFunc("/about", recoverPanic(serveHTMLFile("index.html")))
-	http.HandleFunc("/service.html", recoverPanic(serveHTMLFile("service.html")))
-	http.HandleFunc("/adoption.html", recoverPanic(serveHTMLFile("adoption.html")))
-	http.HandleFunc("/donate.html", recoverPanic(serveHTMLFile("donate.html")))
-	http.HandleFunc("/auth.html", recoverPanic(serveHTMLFile("auth.html")))
-	http.HandleFunc("/admin.html", recoverPanic(serveHTMLFile("admin.html")))
-	http.HandleFunc("/dashboard.html", recoverPanic(serveHTMLFile("dashboard.html")))
-
-	http.HandleFunc("/api/pets", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		// 2. CONTROL FLOW
-		switch r.Method {
-		case "GET":
-			getPetsHandler(w, r)
-		case "POST":
-			addPetHandler(w, r)
-		default:
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/pets/", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "GET":
-			getPetByIDHandler(w, r)
-		case "PUT":
-			updatePetHandler(w, r)
-		case "DELETE":
-			deletePetHandler(w, r)
-		default:
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/services", recoverPanic(enableCORS(getServicesHandler)))
-	http.HandleFunc("/api/bookings", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "GET":
-			getBookingsHandler(w, r)
-		case "POST":
-			createBookingHandler(w, r)
-		default:
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-	http.HandleFunc("/api/contact", recoverPanic(enableCORS(submitContactHandler)))
-	http.HandleFunc("/api/statistics", recoverPanic(enableCORS(getStatisticsHandler)))
-
-	http.HandleFunc("/api/auth/register", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			registerHandler(w, r)
-		} else {
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/auth/login", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			loginHandler(w, r)
-		} else {
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/auth/verify", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			verifyEmailHandler(w, r)
-		} else {
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/auth/me", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" {
-			meHandler(w, r)
-		} else {
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/adoptions", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "GET":
-			getAdoptionInquiriesHandler(w, r)
-		case "POST":
-			createAdoptionInquiryHandler(w, r)
-		default:
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
-
-	http.HandleFunc("/api/donations", recoverPanic(enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case "GET":
-			getDonationsHandler(w, r)
-		case "POST":
-			createDonationHandler(w, r)
-		default:
-			respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		}
-	})))
+	// 6. INTERFACE - the mux is an http.Handler; middleware wraps every route once
+	handler := newHandler()
 
 	log.Println("==============================================")
 	log.Println("🐾 Pawtner Hope Foundation Server")
@@ -1967,6 +8871,7 @@ func main() {
 	log.Printf("Version: %s\n", serverVersion)
 	log.Printf("Initialized with %d pets\n", len(pets))
 	log.Printf("Initialized with %d services\n", len(services))
+	log.Printf("Email workers: %d (EMAIL_WORKERS, default 4)\n", max(emailWorkerCount, 1))
 	log.Println("==============================================")
 	log.Println("Default admin login:")
 	log.Println("  Email:    admin@pawtner.com")
@@ -1975,24 +8880,109 @@ func main() {
 	log.Println("API Endpoints:")
 	log.Println("  GET    /api/pets              - Get all pets")
 	log.Println("  GET    /api/pets/:id          - Get pet by ID")
-	log.Println("  POST   /api/pets              - Add new pet")
-	log.Println("  PUT    /api/pets/:id          - Update pet")
-	log.Println("  DELETE /api/pets/:id          - Delete pet")
+	log.Println("  POST   /api/pets              - Add new pet (admin)")
+	log.Println("  PUT    /api/pets/:id          - Update pet; ?clear= blanks fields (admin)")
+	log.Println("  DELETE /api/pets/:id          - Delete pet (admin)")
+	log.Println("  GET    /api/pets/featured     - Get pet of the week")
+	log.Println("  GET    /api/pets/facets       - Attribute value counts (?attr=Color,Size, plus list filters)")
+	log.Println("  POST   /api/pets/bulk-delete  - Delete several pets (admin)")
+	log.Println("  POST   /api/pets/bulk-archive - Archive several pets (admin)")
+	log.Println("  POST   /api/pets/import-csv   - Add pets from a CSV upload (admin)")
+	log.Println("  GET    /api/pets/export.csv   - Download the pet list as CSV, same filters as /api/pets (admin)")
+	log.Println("  POST   /api/pets/:id/feature  - Feature pet for a week (admin)")
+	log.Println("  GET    /api/pets/missing-photos - Available pets without photos (admin)")
+	log.Println("  GET    /api/pets/broken-photos - Pets with unreachable photo URLs (admin)")
+	log.Println("  GET    /api/pets/checkup-due  - Pets overdue for a vet checkup, ?months=12 (admin)")
+	log.Println("  GET    /api/pets/:id/similar  - Up to five similar pets")
+	log.Println("  POST   /api/pets/:id/waitlist - Join waitlist for unavailable pet")
+	log.Println("  POST   /api/pets/:id/favorite - Add pet to favorites")
+	log.Println("  DELETE /api/pets/:id/favorite - Remove pet from favorites")
 	log.Println("  GET    /api/services          - Get all services")
+	log.Println("  POST   /api/services          - Add a service (admin)")
+	log.Println("  GET    /api/services/:id      - Get a service with its rating")
+	log.Println("  PUT    /api/services/:id      - Update a service (admin)")
+	log.Println("  DELETE /api/services/:id      - Delete a service not in an active package (admin)")
+	log.Println("  POST   /api/services/:id/rate - Rate a service 1-5, optionally for a completed booking")
+	log.Println("  GET    /api/packages          - Get service packages")
+	log.Println("  POST   /api/packages          - Create a service package (admin)")
+	log.Println("  POST   /api/packages/:id/book - Book every service in a package")
 	log.Println("  GET    /api/bookings          - Get all bookings")
 	log.Println("  POST   /api/bookings          - Create booking")
+	log.Println("  GET    /api/bookings/:id      - Get booking with its service")
+	log.Println("  DELETE /api/bookings/:id      - Cancel booking (owner or admin)")
+	log.Println("  PUT    /api/bookings/:id/complete - Mark a booking completed (admin)")
 	log.Println("  POST   /api/contact           - Submit contact form")
+	log.Println("  GET    /api/testimonials      - Get approved testimonials")
+	log.Println("  POST   /api/testimonials      - Submit testimonial (held for review)")
+	log.Println("  POST   /api/testimonials/:id/approve - Approve testimonial (admin)")
+	log.Println("  GET    /api/volunteers        - List volunteer sign-ups, ?status= (admin)")
+	log.Println("  POST   /api/volunteers        - Volunteer sign-up")
+	log.Println("  GET    /api/events            - Upcoming events, ?past=true (admins: ?drafts=true)")
+	log.Println("  POST   /api/events            - Create event (admin)")
+	log.Println("  PUT    /api/events/:id        - Update event (admin)")
+	log.Println("  DELETE /api/events/:id        - Delete event (admin)")
+	log.Println("  GET    /api/health            - Database, uptime and queue health")
+	log.Println("  GET    /api/impact            - Rescued, adopted, funds raised and volunteers")
 	log.Println("  GET    /api/statistics        - Get statistics")
+	log.Println("  GET    /api/statistics/funnel - Adoption funnel counts (?from=, ?to=)")
+	log.Println("  GET    /api/admin/pending-counts - Pending item counts for admin badges (admin)")
+	log.Println("  GET    /api/admin/reports/weekly - Weekly donations summary (admin)")
+	log.Println("  PUT    /api/admin/users/:id/role - Change a user's role (admin)")
+	log.Println("  GET    /api/admin/audit-log   - Administrative action log (admin)")
+	log.Println("  GET    /api/admin/failed-logins - Rejected login attempts, ?from=&to= (admin)")
+	log.Println("  GET    /api/admin/contact-messages - Contact messages, ?handled=false for unanswered (admin)")
+	log.Println("  PUT    /api/admin/contact-messages/:id/handled - Mark a contact message handled (admin)")
 	log.Println("  POST   /api/auth/register     - Register user")
 	log.Println("  POST   /api/auth/login        - Login user")
+	log.Println("  POST   /api/auth/logout       - Revoke current token")
+	log.Println("  POST   /api/auth/refresh      - Swap a token near expiry for a new one")
+	log.Println("  DELETE /api/auth/me           - Delete (anonymize) own account")
+	log.Println("  GET    /api/auth/me/favorites - Get favorited pets")
 	log.Println("  GET    /api/adoptions         - Get adoption inquiries")
 	log.Println("  POST   /api/adoptions         - Submit adoption inquiry")
-	log.Println("  GET    /api/donations         - Get donations")
+	log.Println("  GET    /api/adoptions/questions - Adoption form questions (?species=)")
+	log.Println("  GET    /api/adoptions/:id     - Get adoption inquiry with fee payment (admin)")
+	log.Println("  PUT    /api/adoptions/:id     - Approve or reject an inquiry (admin)")
+	log.Println("  GET    /api/foster            - List foster applications, ?status= (admin)")
+	log.Println("  POST   /api/foster            - Apply to foster (logged in)")
+	log.Println("  PUT    /api/foster/:id        - Approve or reject a foster application (admin)")
+	log.Println("  POST   /api/adoptions/:id/fee - Link adoption-fee donation (admin)")
+	log.Println("  GET    /api/donations         - Get donations (admin)")
 	log.Println("  POST   /api/donations         - Process donation")
+	log.Println("  GET    /api/donations/statement - Yearly giving statement (?year=, ?format=pdf)")
+	log.Println("  POST   /api/donations/upi-link - UPI deeplink for a pending donation")
+	log.Println("  POST   /api/donations/upi-callback - Payment provider result for a UPI deeplink (signed)")
+	log.Println("  POST   /api/donations/:id/receipt - Email the receipt for a completed donation (donor or admin)")
+	log.Println("  POST   /api/donations/:id/resend-receipt - Resend receipt, optionally to a new email (admin)")
 	log.Println("==============================================")
 	log.Println("Server starting on http://localhost:8080")
 
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":8080", Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down: no longer accepting requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Handlers may still be queueing work, and closing the channels
+		// under them would panic, so leave the queues to exit with us.
+		log.Printf("[SHUTDOWN] HTTP server did not stop cleanly: %v; not draining workers", err)
+		workers.cancel()
+		return
+	}
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelStop()
+	payments, emails, err := workers.stop(stopCtx)
+	if err != nil {
+		log.Printf("[SHUTDOWN] Gave up waiting for workers: %v", err)
 	}
+	log.Printf("[SHUTDOWN] Drained %d queued payment(s) and %d queued email(s)", payments, emails)
 }
@@ -1,32 +1,121 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/smtp"
+	"net/url"
 	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // 9. UNIT TEST CASES
 
 func TestMain(m *testing.M) {
+	bcryptCost = bcrypt.MinCost // keep the many initializeData calls fast
 	initializeData()
-	startWorkers()
+	startWorkers(context.Background())
 	os.Exit(m.Run())
 }
 
 // Test authentication logic, token generation, password validation
 
 func TestHashPassword(t *testing.T) {
-	h1 := hashPassword("secret")
-	h2 := hashPassword("secret")
-	if h1 != h2 {
-		t.Error("same password should produce same hash")
+	h1, err1 := hashPassword("secret")
+	h2, err2 := hashPassword("secret")
+	if err1 != nil || err2 != nil {
+		t.Fatalf("hashPassword: %v, %v", err1, err2)
+	}
+	if h1 == h2 {
+		t.Error("bcrypt hashes should be salted, got identical hashes")
+	}
+	if h1 == "secret" || strings.Contains(h1, "secret") {
+		t.Error("hash should not contain the plaintext")
+	}
+	if !checkPassword(h1, "secret") || !checkPassword(h2, "secret") {
+		t.Error("both hashes should verify the original password")
+	}
+	if checkPassword(h1, "wrong") {
+		t.Error("wrong password should not verify")
+	}
+	if !checkPassword("hashed_secret_pawtnersalt", "secret") {
+		t.Error("legacy hashes should still verify")
+	}
+	if h, err := hashPassword(strings.Repeat("x", maxPasswordBytes+1)); err == nil || h != "" {
+		t.Errorf("overlong password: got %q, %v; want an error", h, err)
+	}
+	if checkPassword("", "") {
+		t.Error("an empty hash should never verify")
+	}
+}
+
+func TestLegacyPasswordMigratedOnLogin(t *testing.T) {
+	initializeData()
+	user, _ := Register("old@example.com", "oldtimer", "pass123")
+	user.Password = "hashed_pass123_pawtnersalt"
+
+	mock := &mockCollection{writes: make(chan interface{}, 1)}
+	orig := writeCollection
+	writeCollection = func(string, *writeconcern.WriteConcern) docCollection { return mock }
+	defer func() { writeCollection = orig }()
+
+	if _, err := Login("old@example.com", "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if user.Password != "hashed_pass123_pawtnersalt" {
+		t.Error("a failed login must not touch the stored hash")
+	}
+
+	if _, err := Login("old@example.com", "pass123"); err != nil {
+		t.Fatalf("Login with legacy hash failed: %v", err)
+	}
+	stored := usersByEmail["old@example.com"].Password
+	if strings.HasPrefix(stored, "hashed_") || !strings.HasPrefix(stored, "$2") {
+		t.Errorf("expected legacy hash to be replaced with bcrypt after login, got %q", stored)
+	}
+	select {
+	case doc := <-mock.writes:
+		if persisted, ok := doc.(User); !ok || persisted.Password != stored {
+			t.Errorf("expected upgraded hash to be persisted, got %+v", doc)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the upgraded user to be synced to MongoDB")
 	}
-	if h1 == "secret" {
-		t.Error("hash should not equal plaintext")
+
+	if _, err := Login("old@example.com", "pass123"); err != nil {
+		t.Errorf("Login after migration failed: %v", err)
+	}
+	if usersByEmail["old@example.com"].Password != stored {
+		t.Error("a bcrypt hash should not be re-hashed on later logins")
 	}
 }
 
@@ -113,6 +202,239 @@ func TestValidateToken(t *testing.T) {
 	}
 }
 
+func TestLogout(t *testing.T) {
+	initializeData()
+	Register("bye@example.com", "byeuser", "byepass")
+	token, _ := Login("bye@example.com", "byepass")
+	router := newRouter()
+
+	logout := func(auth string) int {
+		req := httptest.NewRequest("POST", "/api/auth/logout", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := logout("Bearer " + token.Token); code != http.StatusOK {
+		t.Fatalf("logout: expected 200, got %d", code)
+	}
+	if _, err := ValidateToken(token.Token); err != ErrInvalidCredentials {
+		t.Errorf("token should be revoked after logout, got %v", err)
+	}
+	if code := logout("Bearer " + token.Token); code != http.StatusOK {
+		t.Errorf("repeat logout: expected 200, got %d", code)
+	}
+	if code := logout(""); code != http.StatusUnauthorized {
+		t.Errorf("logout without token: expected 401, got %d", code)
+	}
+}
+
+func TestFailedLoginAudit(t *testing.T) {
+	initializeData()
+	Register("audit@example.com", "audituser", "auditpass")
+	router := newRouter()
+
+	login := func(email, password string) int {
+		body := fmt.Sprintf(`{"email":%q,"password":%q}`, email, password)
+		req := httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(body))
+		req.RemoteAddr = "203.0.113.7:5555"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := login("audit@example.com", "wrongpass"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: expected 401, got %d", code)
+	}
+	if code := login("nobody@example.com", "whatever"); code != http.StatusUnauthorized {
+		t.Fatalf("unknown email: expected 401, got %d", code)
+	}
+	if code := login("audit@example.com", "auditpass"); code != http.StatusOK {
+		t.Fatalf("valid login: expected 200, got %d", code)
+	}
+
+	if len(failedLogins) != 2 {
+		t.Fatalf("expected 2 failed login records, got %d", len(failedLogins))
+	}
+	first := failedLogins[0]
+	if first.Email != "audit@example.com" || first.IP != "203.0.113.7" || first.Reason != "wrong_password" {
+		t.Errorf("unexpected record for wrong password: %+v", first)
+	}
+	if failedLogins[1].Reason != "unknown_email" {
+		t.Errorf("expected unknown_email, got %q", failedLogins[1].Reason)
+	}
+
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	list := func(query string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/admin/failed-logins"+query, nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer "+admin.Token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := list("", false); rr.Code != http.StatusUnauthorized {
+		t.Errorf("without token: expected 401, got %d", rr.Code)
+	}
+
+	var resp struct {
+		Data []FailedLogin `json:"data"`
+	}
+	rr := list("", true)
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != http.StatusOK || len(resp.Data) != 2 {
+		t.Fatalf("expected 200 with 2 records, got %d with %d", rr.Code, len(resp.Data))
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	resp.Data = nil
+	rr = list("?from="+tomorrow, true)
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != http.StatusOK || len(resp.Data) != 0 {
+		t.Errorf("from=tomorrow: expected 200 with no records, got %d with %d", rr.Code, len(resp.Data))
+	}
+
+	if rr := list("?from=yesterday", true); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad date: expected 400, got %d", rr.Code)
+	}
+
+	initializeData()
+	long := strings.Repeat("a", 5000) + "@example.com"
+	for i := 0; i < failedLoginRecordLimit+5; i++ {
+		if code := login(long, "whatever"); code != http.StatusUnauthorized {
+			t.Fatalf("flood attempt %d: expected 401, got %d", i, code)
+		}
+	}
+	if len(failedLogins) != failedLoginRecordLimit {
+		t.Fatalf("expected %d records for one IP within a minute, got %d", failedLoginRecordLimit, len(failedLogins))
+	}
+	last := failedLogins[len(failedLogins)-1]
+	if len(last.Email) != maxFailedLoginEmail || last.Repeats != 5 {
+		t.Errorf("expected email cut to %d bytes and 5 repeats, got %d bytes and %d repeats", maxFailedLoginEmail, len(last.Email), last.Repeats)
+	}
+}
+
+func TestValidateTokenIdleTimeout(t *testing.T) {
+	initializeData()
+	Register("idle@example.com", "idleuser", "idlepass")
+	token, _ := Login("idle@example.com", "idlepass")
+
+	if _, err := ValidateToken(token.Token); err != nil {
+		t.Fatalf("fresh token should validate: %v", err)
+	}
+
+	tokenStore[token.Token].LastUsedAt = time.Now().Add(-tokenIdleTimeout - time.Minute)
+	if time.Now().After(tokenStore[token.Token].ExpiresAt) {
+		t.Fatal("absolute expiry should not have passed")
+	}
+	_, err := ValidateToken(token.Token)
+	if err != ErrSessionIdle {
+		t.Errorf("expected ErrSessionIdle for idle token, got %v", err)
+	}
+	if _, exists := tokenStore[token.Token]; exists {
+		t.Error("idle token should be removed from the store")
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	initializeData()
+	Register("fresh@example.com", "freshuser", "freshpass")
+	old, _ := Login("fresh@example.com", "freshpass")
+	router := newRouter()
+
+	refresh := func(token string) (int, AuthToken) {
+		req := httptest.NewRequest("POST", "/api/auth/refresh", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp struct {
+			Data AuthToken `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp.Data
+	}
+
+	if code, _ := refresh(old.Token); code != http.StatusBadRequest {
+		t.Errorf("expected 400 refreshing a brand-new token, got %d", code)
+	}
+
+	tokenStore[old.Token].ExpiresAt = time.Now().Add(time.Hour)
+	code, fresh := refresh(old.Token)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 refreshing within the window, got %d", code)
+	}
+	if fresh.Token == "" || fresh.Token == old.Token {
+		t.Errorf("expected a new token string, got %q", fresh.Token)
+	}
+	if fresh.Email != old.Email || fresh.Username != old.Username || fresh.Role != old.Role {
+		t.Errorf("expected account details copied over, got %+v", fresh)
+	}
+	if time.Until(fresh.ExpiresAt) < tokenLifetime-time.Minute {
+		t.Errorf("expected a full lifetime, expires at %v", fresh.ExpiresAt)
+	}
+	if _, err := ValidateToken(old.Token); err != ErrInvalidCredentials {
+		t.Errorf("old token should be revoked, got %v", err)
+	}
+	if user, err := ValidateToken(fresh.Token); err != nil || user.Email != "fresh@example.com" {
+		t.Errorf("new token should validate, got %v", err)
+	}
+
+	tokenStore[fresh.Token].ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := RefreshToken(fresh.Token); err != ErrTokenExpired {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+	tokenStore[fresh.Token] = &AuthToken{Token: fresh.Token, ExpiresAt: time.Now().Add(-time.Minute)}
+	if code, _ := refresh(fresh.Token); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 refreshing an expired token, got %d", code)
+	}
+}
+
+func TestCleanupExpiredTokens(t *testing.T) {
+	initializeData()
+	Register("sweep@example.com", "sweepuser", "sweeppass")
+	live, _ := Login("sweep@example.com", "sweeppass")
+	tokenStore["stale-token"] = &AuthToken{
+		Token:      "stale-token",
+		UserID:     live.UserID,
+		ExpiresAt:  time.Now().Add(-time.Minute),
+		LastUsedAt: time.Now().Add(-time.Minute),
+	}
+
+	if n := cleanupExpiredTokens(); n != 1 {
+		t.Errorf("expected 1 token removed, got %d", n)
+	}
+	if _, exists := tokenStore["stale-token"]; exists {
+		t.Error("expired token should be removed")
+	}
+	if _, exists := tokenStore[live.Token]; !exists {
+		t.Error("live token should be kept")
+	}
+
+	tokenStore["stale-token"] = &AuthToken{Token: "stale-token", ExpiresAt: time.Now().Add(-time.Minute)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tokenCleanupWorker(ctx, 5*time.Millisecond)
+	deadline := time.After(time.Second)
+	for {
+		mu.RLock()
+		_, exists := tokenStore["stale-token"]
+		mu.RUnlock()
+		if !exists {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("cleanup worker should remove expired tokens")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
 // Test pet CRUD operations, validation logic
 
 func TestValidatePet(t *testing.T) {
@@ -158,6 +480,53 @@ func TestUpdatePet(t *testing.T) {
 	}
 }
 
+func TestUpdatePetClearFields(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	petsByID["pet-001"].IsVaccinated = true
+	availableBefore := statusCounts["Available"]
+
+	update := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/pets/pet-001"+query, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := update("?clear=description", `{"gender":"Female","tags":["calm"]}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	pet := petsByID["pet-001"]
+	if pet.Description != "" {
+		t.Errorf("description should be cleared, got %q", pet.Description)
+	}
+	if pet.Gender != "Female" || !slices.Equal(pet.Tags, []string{"calm"}) {
+		t.Errorf("gender and tags should be updated, got %q %v", pet.Gender, pet.Tags)
+	}
+	if pet.Name != "Max" || !pet.IsVaccinated {
+		t.Errorf("fields not mentioned should be left alone, got %+v", pet)
+	}
+
+	if rr := update("", `{"status":"Adopted","clear":["isVaccinated"]}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if pet.IsVaccinated {
+		t.Error("isVaccinated should be toggled to false")
+	}
+	if statusCounts["Available"] != availableBefore-1 || statusCounts["Adopted"] != 1 {
+		t.Errorf("status counts not kept in step: %v", statusCounts)
+	}
+
+	if rr := update("?clear=name", `{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("clearing a required field: expected 400, got %d", rr.Code)
+	}
+	if pet.Name != "Max" {
+		t.Errorf("a rejected update must not change the pet, got name %q", pet.Name)
+	}
+}
+
 func TestDeletePet(t *testing.T) {
 	initializeData()
 
@@ -176,102 +545,3826 @@ func TestDeletePet(t *testing.T) {
 	}
 }
 
-func TestGetPetByID(t *testing.T) {
+func TestImpactRescuedSurvivesDelete(t *testing.T) {
 	initializeData()
+	router := newRouter()
 
-	pet, exists := petsByID["pet-001"]
-	if !exists {
-		t.Fatal("pet-001 should exist after initializeData")
+	impact := func() map[string]interface{} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/impact", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.Data
 	}
-	if pet.Name != "Max" {
-		t.Errorf("expected Max, got %s", pet.Name)
+
+	before := impact()["rescued"].(float64)
+	if int(before) != len(pets) {
+		t.Fatalf("expected rescued to start at %d, got %v", len(pets), before)
 	}
 
-	_, exists = petsByID["pet-999"]
-	if exists {
-		t.Error("pet-999 should not exist")
+	if err := DeletePet("pet-003"); err != nil {
+		t.Fatalf("DeletePet failed: %v", err)
+	}
+	resetImpactCache()
+	if got := impact()["rescued"].(float64); got != before {
+		t.Errorf("rescued dropped after a delete: %v -> %v", before, got)
 	}
-}
 
-// Test payment processing, receipt generation
+	// New pets keep counting up and never reuse a deleted pet's ID.
+	mu.Lock()
+	pet := addPetLocked(Pet{Name: "Biscuit", Breed: "Beagle", Status: "Available"})
+	mu.Unlock()
+	if want := fmt.Sprintf("pet-%03d", int(before)+1); pet.ID != want {
+		t.Errorf("expected new pet ID %s, got %s", want, pet.ID)
+	}
+	resetImpactCache()
+	if got := impact()["rescued"].(float64); got != before+1 {
+		t.Errorf("expected rescued %v after adding a pet, got %v", before+1, got)
+	}
+}
 
-func TestProcessDonation(t *testing.T) {
+func TestFeaturePet(t *testing.T) {
 	initializeData()
 
-	donation := &Donation{
-		DonorName:     "Jane Doe",
-		DonorEmail:    "jane@example.com",
-		Amount:        500.00,
-		PaymentMethod: "UPI",
+	if _, _, err := FeaturePet("pet-001"); err != nil {
+		t.Fatalf("FeaturePet failed: %v", err)
 	}
-	receipt, err := ProcessDonation(donation)
+	pet, previous, err := FeaturePet("pet-002")
 	if err != nil {
-		t.Fatalf("ProcessDonation failed: %v", err)
+		t.Fatalf("FeaturePet failed: %v", err)
 	}
-	if receipt.ReceiptID == "" {
-		t.Error("receipt ID should not be empty")
+	if !pet.Featured || pet.FeaturedAt == nil {
+		t.Error("pet-002 should be featured")
 	}
-	if receipt.Amount != 500.00 {
-		t.Errorf("expected amount 500.00, got %.2f", receipt.Amount)
+	if previous == nil || previous.ID != "pet-001" {
+		t.Errorf("expected pet-001 to be returned as previously featured, got %v", previous)
 	}
-	if donation.Status != "Completed" {
-		t.Errorf("expected status Completed, got %s", donation.Status)
+	if petsByID["pet-001"].Featured {
+		t.Error("pet-001 should have been unfeatured")
 	}
 
-	_, err = ProcessDonation(&Donation{DonorName: "A", DonorEmail: "a@b.com", Amount: -100, PaymentMethod: "UPI"})
-	if err != ErrInvalidPayment {
-		t.Errorf("expected ErrInvalidPayment for negative amount, got %v", err)
+	current, ok := featuredPet()
+	if !ok || current.ID != "pet-002" {
+		t.Errorf("expected pet-002 as featured pet, got %v", current)
 	}
 
-	_, err = ProcessDonation(&Donation{DonorName: "", DonorEmail: "a@b.com", Amount: 100, PaymentMethod: "UPI"})
-	if err == nil {
-		t.Error("expected error for missing donor name")
+	_, _, err = FeaturePet("pet-999")
+	if err != ErrPetNotFound {
+		t.Errorf("expected ErrPetNotFound, got %v", err)
 	}
+}
 
-	_, err = ProcessDonation(&Donation{DonorName: "A", DonorEmail: "a@b.com", Amount: 100, PaymentMethod: ""})
-	if err == nil {
-		t.Error("expected error for missing payment method")
+func TestFeaturedPetOfTheWeek(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	feature := func(id string) int {
+		req := httptest.NewRequest("POST", "/api/pets/"+id+"/feature", nil)
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	current := func() (Pet, int) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/pets/featured", nil))
+		var resp struct {
+			Data Pet `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.Data, rr.Code
+	}
+
+	if code := feature("pet-001"); code != http.StatusOK {
+		t.Fatalf("expected 200 featuring pet-001, got %d", code)
+	}
+	pet, code := current()
+	if code != http.StatusOK || pet.ID != "pet-001" {
+		t.Fatalf("expected pet-001 featured, got %d %+v", code, pet)
+	}
+	if pet.FeaturedUntil == nil || pet.FeaturedUntil.Sub(*pet.FeaturedAt) != featuredDuration {
+		t.Errorf("expected expiry %v after featuring, got %v", featuredDuration, pet.FeaturedUntil)
+	}
+
+	feature("pet-002")
+	if pet, _ := current(); pet.ID != "pet-002" {
+		t.Errorf("expected pet-002 to replace pet-001, got %s", pet.ID)
+	}
+	if petsByID["pet-001"].Featured || petsByID["pet-001"].FeaturedUntil != nil {
+		t.Error("pet-001 should no longer be featured")
+	}
+
+	reapFeaturedPet(time.Now().Add(featuredDuration + time.Minute))
+	if petsByID["pet-002"].Featured {
+		t.Error("expected featured pet to expire after featuredDuration")
+	}
+	if _, code := current(); code != http.StatusNotFound {
+		t.Errorf("expected 404 once the feature expired, got %d", code)
+	}
+
+	feature("pet-001")
+	past := time.Now().Add(-time.Minute)
+	petsByID["pet-001"].FeaturedUntil = &past
+	if _, code := current(); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a feature that expired between reaps, got %d", code)
+	}
+	if petsByID["pet-001"].Featured {
+		t.Error("reading an expired feature should clear it")
+	}
+
+	req := httptest.NewRequest("POST", "/api/pets/pet-001/feature", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rr.Code)
 	}
 }
 
-func TestGenerateReceipt(t *testing.T) {
-	donation := Donation{
-		ID:        "don-001",
-		DonorName: "John",
-		Amount:    1000.00,
+// Run with -race: the featured pet must be copied before mu is released.
+func TestFeaturedPetConcurrentWithUpdates(t *testing.T) {
+	initializeData()
+	if _, _, err := FeaturePet("pet-001"); err != nil {
+		t.Fatalf("FeaturePet failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := UpdatePet("pet-001", Pet{Description: fmt.Sprintf("update %d", i)}); err != nil {
+				t.Errorf("UpdatePet failed: %v", err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			rr := httptest.NewRecorder()
+			getFeaturedPetHandler(rr, httptest.NewRequest("GET", "/api/pets/featured", nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rr.Code)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestGetPetsConcurrentWithUpdates(t *testing.T) {
+	initializeData()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := UpdatePet("pet-001", Pet{Description: fmt.Sprintf("update %d", i)}); err != nil {
+				t.Errorf("UpdatePet failed: %v", err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			rr := httptest.NewRecorder()
+			getPetsHandler(rr, httptest.NewRequest("GET", "/api/pets?species=Dog&sort=name", nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rr.Code)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestPetsMissingPhotos(t *testing.T) {
+	initializeData()
+	petsByID["pet-001"].Images = []string{"https://example.com/max.jpg"}
+
+	result := petsMissingPhotos()
+	if len(result) != 1 || result[0].ID != "pet-002" {
+		t.Errorf("expected only pet-002 (available, no photos), got %v", result)
+	}
+}
+
+func TestCheckupDue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	petList := []Pet{
+		{ID: "pet-001", Status: "Available", Medical: &MedicalInfo{LastCheckup: "2023-11-20"}},
+		{ID: "pet-002", Status: "Available", Medical: &MedicalInfo{LastCheckup: "10/03/2025"}},
+		{ID: "pet-003", Status: "Under Care", Medical: &MedicalInfo{LastCheckup: "sometime last spring"}},
+	}
+
+	due, unparseable := checkupDue(petList, now, 12)
+	if len(due) != 1 || due[0].ID != "pet-001" {
+		t.Errorf("expected only the overdue pet-001, got %v", due)
+	}
+	if len(unparseable) != 1 || unparseable[0] != "pet-003" {
+		t.Errorf("expected pet-003 flagged as unparseable, got %v", unparseable)
+	}
+
+	// Through the handler; months must be a positive number.
+	initializeData()
+	petsByID["pet-001"].Medical = &MedicalInfo{LastCheckup: time.Now().Format("2006-01-02")}
+	rr := httptest.NewRecorder()
+	getPetsCheckupDueHandler(rr, httptest.NewRequest("GET", "/api/pets/checkup-due?months=6", nil))
+	var resp struct {
+		Data []Pet `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	for _, p := range resp.Data {
+		if p.ID == "pet-001" {
+			t.Errorf("pet-001 was just checked and shouldn't be due")
+		}
+	}
+	rr = httptest.NewRecorder()
+	getPetsCheckupDueHandler(rr, httptest.NewRequest("GET", "/api/pets/checkup-due?months=0", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for months=0, got %d", rr.Code)
+	}
+}
+
+func TestBrokenPhotoCheck(t *testing.T) {
+	initializeData()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok.jpg" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ok, gone := srv.URL+"/ok.jpg", srv.URL+"/gone.jpg"
+	petsByID["pet-001"].Images = []string{ok, gone}
+	checkPetPhotos("pet-001", []string{ok, gone})
+
+	if got := petsByID["pet-001"].BrokenImages; len(got) != 1 || got[0] != gone {
+		t.Errorf("expected only the 404 URL flagged, got %v", got)
+	}
+	if result := petsWithBrokenPhotos(); len(result) != 1 || result[0].ID != "pet-001" {
+		t.Errorf("expected pet-001 in the broken photos report, got %v", result)
+	}
+	if n := pendingCounts()["brokenPhotos"]; n != 1 {
+		t.Errorf("expected 1 pet with broken photos in pending counts, got %d", n)
+	}
+
+	// Through the update handler the check runs in the background.
+	photoCheckEnabled = true
+	defer func() { photoCheckEnabled = false }()
+	body := fmt.Sprintf(`{"images":[%q]}`, gone)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/pets/pet-002", strings.NewReader(body))
+	req.SetPathValue("id", "pet-002")
+	updatePetHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 from update, got %d", rr.Code)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.RLock()
+		n := len(petsByID["pet-002"].BrokenImages)
+		mu.RUnlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected background check to flag pet-002's photo")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSimilarPets(t *testing.T) {
+	initializeData()
+
+	similar := similarPets(*petsByID["pet-001"], pets, 5)
+	if len(similar) != 1 || similar[0].Name != "Charlie" {
+		t.Errorf("expected Charlie to be similar to Max, got %v", similar)
+	}
+	similar = similarPets(*petsByID["pet-003"], pets, 5)
+	if len(similar) != 1 || similar[0].Name != "Max" {
+		t.Errorf("expected Max to be similar to Charlie, got %v", similar)
+	}
+
+	candidates := []Pet{
+		{ID: "a", Species: "Dog", Breed: "Pug", Status: "Available", Tags: []string{"Friendly", "Energetic"}},
+		{ID: "b", Species: "Dog", Breed: "Beagle", Status: "Available", Tags: []string{"friendly"}},
+		{ID: "c", Species: "Dog", Breed: "Beagle", Status: "Available", Tags: []string{"Friendly", "Energetic"}},
+		{ID: "d", Species: "Dog", Breed: "Beagle", Status: "Adopted", Tags: []string{"Friendly", "Energetic"}},
+		{ID: "e", Species: "Cat", Breed: "Beagle", Status: "Available"},
+	}
+	target := Pet{ID: "t", Species: "Dog", Breed: "Beagle", Tags: []string{"Friendly", "Energetic"}}
+	var ids []string
+	for _, p := range similarPets(target, candidates, 5) {
+		ids = append(ids, p.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"c", "b", "a"}) {
+		t.Errorf("expected same breed first, then by tag overlap, got %v", ids)
+	}
+	if got := similarPets(target, candidates, 2); len(got) != 2 {
+		t.Errorf("expected limit to cap results at 2, got %d", len(got))
+	}
+
+	router := newRouter()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/pets/pet-999/similar", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown pet, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/pets/pet-001/similar", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestWaitlist(t *testing.T) {
+	initializeData()
+	UpdatePet("pet-001", Pet{Status: "Adopted"})
+
+	added, err := JoinWaitlist("pet-001", "Fan@Example.com")
+	if err != nil || !added {
+		t.Fatalf("JoinWaitlist failed: added=%v err=%v", added, err)
+	}
+	added, _ = JoinWaitlist("pet-001", "fan@example.com")
+	if added {
+		t.Error("duplicate email should not be added twice")
+	}
+	if got := waitlistByPet["pet-001"]; len(got) != 1 || got[0] != "fan@example.com" {
+		t.Errorf("expected waitlist [fan@example.com], got %v", got)
+	}
+
+	if _, err := JoinWaitlist("pet-002", "fan@example.com"); err == nil {
+		t.Error("expected error when waitlisting an available pet")
+	}
+
+	UpdatePet("pet-001", Pet{Status: "Available"})
+	select {
+	case job := <-notificationCh:
+		if job.To != "fan@example.com" || job.JobType != "waitlist" {
+			t.Errorf("unexpected notification job: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected waitlist notification when pet returned")
+	}
+	if len(waitlistByPet["pet-001"]) != 0 {
+		t.Error("waitlist should be cleared after notifying")
+	}
+
+	UpdatePet("pet-001", Pet{Status: "Adopted"})
+	if _, err := JoinWaitlist("pet-001", "not-an-email"); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("expected ErrInvalidEmail, got %v", err)
+	}
+	defer func(n int) { maxWaitlistPerPet = n }(maxWaitlistPerPet)
+	maxWaitlistPerPet = 1
+	JoinWaitlist("pet-001", "fan@example.com")
+	if _, err := JoinWaitlist("pet-001", "other@example.com"); !errors.Is(err, ErrWaitlistFull) {
+		t.Errorf("expected ErrWaitlistFull, got %v", err)
+	}
+
+	// Withdrawing an adoption puts the pet back up and notifies the waitlist.
+	UpdatePet("pet-001", Pet{Status: "Available"})
+	select {
+	case <-notificationCh:
+	case <-time.After(time.Second):
+		t.Fatal("expected waitlist notification when pet returned")
+	}
+	inquiries = append(inquiries, AdoptionInquiry{ID: "inq-wl", PetID: "pet-001", Email: "adopter@example.com", Status: "Pending"})
+	if _, _, err := UpdateInquiryStatus("inq-wl", "Approved"); err != nil {
+		t.Fatalf("approving inquiry failed: %v", err)
+	}
+	JoinWaitlist("pet-001", "fan@example.com")
+	if _, _, err := UpdateInquiryStatus("inq-wl", "Rejected"); err != nil {
+		t.Fatalf("withdrawing approval failed: %v", err)
+	}
+	for notified := false; !notified; {
+		select {
+		case job := <-notificationCh:
+			notified = job.JobType == "waitlist" && job.To == "fan@example.com"
+		case <-time.After(time.Second):
+			t.Fatal("expected waitlist notification when an approval was withdrawn")
+		}
+	}
+	if len(waitlistByPet["pet-001"]) != 0 {
+		t.Error("waitlist should be cleared after a withdrawn approval")
+	}
+}
+
+func TestWaitlistRateLimit(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	UpdatePet("pet-001", Pet{Status: "Adopted"})
+
+	codes := make([]int, 0, waitlistRateLimit+1)
+	for i := 0; i <= waitlistRateLimit; i++ {
+		body := fmt.Sprintf(`{"email":"fan%d@example.com"}`, i)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/pets/pet-001/waitlist", strings.NewReader(body)))
+		codes = append(codes, rr.Code)
+	}
+	if codes[0] != http.StatusCreated {
+		t.Errorf("expected 201 joining the waitlist, got %d", codes[0])
+	}
+	if last := codes[len(codes)-1]; last != http.StatusTooManyRequests {
+		t.Errorf("expected 429 past %d sign-ups a minute, got %d", waitlistRateLimit, last)
+	}
+}
+
+func TestGetPetByID(t *testing.T) {
+	initializeData()
+
+	pet, exists := petsByID["pet-001"]
+	if !exists {
+		t.Fatal("pet-001 should exist after initializeData")
+	}
+	if pet.Name != "Max" {
+		t.Errorf("expected Max, got %s", pet.Name)
+	}
+
+	_, exists = petsByID["pet-999"]
+	if exists {
+		t.Error("pet-999 should not exist")
+	}
+}
+
+func TestGetPetByIDHandler(t *testing.T) {
+	initializeData()
+
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/api/pets/pet-001", http.StatusOK},
+		{"/api/pets/pet-001/", http.StatusOK},
+		{"/api/pets/pet-999", http.StatusNotFound},
+		{"/api/pets/", http.StatusBadRequest},
+		{"/api/pets/pet%20001", http.StatusBadRequest},
+		{"/api/pets/pet-001/extra", http.StatusNotFound},
+	}
+	router := newRouter()
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("GET %s: expected %d, got %d", tc.path, tc.want, rr.Code)
+		}
+	}
+}
+
+func TestRouterPathValues(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	req := httptest.NewRequest("GET", "/api/pets/pet-002", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var resp struct {
+		Data Pet `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != http.StatusOK || resp.Data.ID != "pet-002" {
+		t.Errorf("expected pet-002, got %d %+v", rr.Code, resp.Data)
+	}
+
+	req = httptest.NewRequest("PUT", "/api/pets/pet-003/feature", nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !petsByID["pet-003"].Featured {
+		t.Errorf("expected nested route to feature pet-003, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/pets/featured", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Data.ID != "pet-003" {
+		t.Errorf("GET /api/pets/featured should not be treated as an ID, got %+v", resp.Data)
+	}
+
+	req = httptest.NewRequest("PATCH", "/api/pets", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("expected 405 allowing GET, POST for unsupported method, got %d %q", rr.Code, rr.Header().Get("Allow"))
+	}
+
+	// Unmatched API paths answer in the JSON error shape, and the page
+	// catch-all at "GET /" must not turn them into a 405 or an HTML page.
+	cases := []struct {
+		method, path string
+		want         int
+		code         string
+	}{
+		{"PATCH", "/api/pets", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED"},
+		{"GET", "/api/pets/pet-001/extra", http.StatusNotFound, "NOT_FOUND"},
+		{"GET", "/api/nothing-here", http.StatusNotFound, "NOT_FOUND"},
+		{"POST", "/api/nothing-here", http.StatusNotFound, "NOT_FOUND"},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		var body struct {
+			Success bool   `json:"success"`
+			Code    string `json:"code"`
+		}
+		err := json.NewDecoder(rr.Body).Decode(&body)
+		if rr.Code != tc.want || err != nil || body.Success || body.Code != tc.code {
+			t.Errorf("%s %s: expected %d %s as JSON, got %d %+v (%v)", tc.method, tc.path, tc.want, tc.code, rr.Code, body, err)
+		}
+	}
+
+	// Extra segments must not reach the {id} handlers.
+	for _, method := range []string{"PUT", "DELETE"} {
+		req = httptest.NewRequest(method, "/api/pets/pet-001/photos", strings.NewReader(`{"name":"Renamed"}`))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code < 400 {
+			t.Errorf("%s with a trailing segment should be rejected, got %d", method, rr.Code)
+		}
+	}
+	if pet, exists := petsByID["pet-001"]; !exists || pet.Name != "Max" {
+		t.Errorf("pet-001 should be untouched by rejected requests, got %+v", pet)
+	}
+}
+
+// Test payment processing, receipt generation
+
+func TestProcessDonation(t *testing.T) {
+	initializeData()
+
+	donation := &Donation{
+		DonorName:     "Jane Doe",
+		DonorEmail:    "jane@example.com",
+		Amount:        500.00,
+		PaymentMethod: "UPI",
+	}
+	receipt, err := ProcessDonation(donation)
+	if err != nil {
+		t.Fatalf("ProcessDonation failed: %v", err)
+	}
+	if receipt.ReceiptID == "" {
+		t.Error("receipt ID should not be empty")
+	}
+	if receipt.Amount != 500.00 {
+		t.Errorf("expected amount 500.00, got %.2f", receipt.Amount)
+	}
+	if donation.Status != "Completed" {
+		t.Errorf("expected status Completed, got %s", donation.Status)
+	}
+
+	_, err = ProcessDonation(&Donation{DonorName: "A", DonorEmail: "a@b.com", Amount: -100, PaymentMethod: "UPI"})
+	if err != ErrInvalidPayment {
+		t.Errorf("expected ErrInvalidPayment for negative amount, got %v", err)
+	}
+
+	_, err = ProcessDonation(&Donation{DonorName: "", DonorEmail: "a@b.com", Amount: 100, PaymentMethod: "UPI"})
+	if err == nil {
+		t.Error("expected error for missing donor name")
+	}
+
+	_, err = ProcessDonation(&Donation{DonorName: "A", DonorEmail: "a@b.com", Amount: 100, PaymentMethod: ""})
+	if err == nil {
+		t.Error("expected error for missing payment method")
+	}
+}
+
+func TestLinkAdoptionFee(t *testing.T) {
+	initializeData()
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", PetID: "pet-001", Status: "Approved"},
+		AdoptionInquiry{ID: "inq-002", PetID: "pet-002", Status: "Pending"},
+	)
+	donations = append(donations,
+		Donation{ID: "don-001", Amount: 2500, Status: "Completed"},
+		Donation{ID: "don-002", Amount: 2500, Status: "Pending"},
+	)
+
+	inquiry, err := LinkAdoptionFee("inq-001", "don-001")
+	if err != nil {
+		t.Fatalf("LinkAdoptionFee failed: %v", err)
+	}
+	if inquiry.DonationID != "don-001" {
+		t.Errorf("expected donation don-001 linked, got %q", inquiry.DonationID)
+	}
+
+	if _, err := LinkAdoptionFee("inq-001", "don-002"); err == nil {
+		t.Error("expected error linking a non-completed donation")
+	}
+	if _, err := LinkAdoptionFee("inq-002", "don-001"); err == nil {
+		t.Error("expected error linking to a pending inquiry")
+	}
+	if _, err := LinkAdoptionFee("inq-001", "don-999"); err != ErrDonationNotFound {
+		t.Errorf("expected ErrDonationNotFound, got %v", err)
+	}
+
+	// A paid inquiry keeps its payment, and a donation pays for one adoption.
+	inquiries = append(inquiries, AdoptionInquiry{ID: "inq-003", PetID: "pet-003", Status: "Approved"})
+	donations = append(donations, Donation{ID: "don-003", Amount: 4000, Status: "Completed"})
+	if _, err := LinkAdoptionFee("inq-001", "don-003"); !errors.Is(err, ErrFeeAlreadyPaid) {
+		t.Errorf("expected ErrFeeAlreadyPaid relinking a paid inquiry, got %v", err)
+	}
+	if inquiries[0].DonationID != "don-001" || inquiries[0].FeeAmount != 2500 {
+		t.Errorf("paid inquiry was overwritten: donation=%s amount=%.2f", inquiries[0].DonationID, inquiries[0].FeeAmount)
+	}
+	if _, err := LinkAdoptionFee("inq-003", "don-001"); !errors.Is(err, ErrDonationLinked) {
+		t.Errorf("expected ErrDonationLinked reusing a donation, got %v", err)
+	}
+	if stats := calculateStatistics(); stats["adoptionFeesCollected"] != 2500.0 {
+		t.Errorf("expected 2500 in adoption fees collected, got %v", stats["adoptionFeesCollected"])
+	}
+
+	router := newRouter()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/adoptions/inq-001", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for inquiry detail without a token, got %d", rr.Code)
+	}
+
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	req := httptest.NewRequest("GET", "/api/adoptions/inq-001", nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var resp map[string]interface{}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	payment, ok := resp["feePayment"].(map[string]interface{})
+	if !ok || payment["id"] != "don-001" {
+		t.Errorf("expected fee payment don-001 in inquiry detail, got %v", resp["feePayment"])
+	}
+}
+
+func TestAdoptionQuestionsBySpecies(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	submit := func(petID, answers string) int {
+		body := `{"petId":"` + petID + `","adopterName":"Kiran","email":"kiran@example.com","answers":` + answers + `}`
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/adoptions", strings.NewReader(body)))
+		return rr.Code
+	}
+
+	// pet-001 (Max) is a dog, pet-002 (Luna) is a cat.
+	if code := submit("pet-001", `{"hoursAlone":"4"}`); code != http.StatusBadRequest {
+		t.Errorf("dog inquiry without a yard answer: expected 400, got %d", code)
+	}
+	if code := submit("pet-001", `{"yard":"Small, fenced","hoursAlone":"4"}`); code != http.StatusCreated {
+		t.Errorf("complete dog inquiry: expected 201, got %d", code)
+	}
+	if code := submit("pet-002", `{"indoorOnly":"yes"}`); code != http.StatusCreated {
+		t.Errorf("cat inquiry without a yard answer: expected 201, got %d", code)
+	}
+	if code := submit("pet-002", `{}`); code != http.StatusBadRequest {
+		t.Errorf("cat inquiry without indoorOnly: expected 400, got %d", code)
+	}
+	if code := submit("pet-999", `{}`); code != http.StatusNotFound {
+		t.Errorf("inquiry for an unknown pet: expected 404, got %d", code)
+	}
+
+	if got := questionsForSpecies("rabbit"); !reflect.DeepEqual(got, defaultAdoptionQuestions) {
+		t.Errorf("species without config should get the default questions, got %v", got)
+	}
+	if got := questionsForSpecies("dog"); len(got) == 0 || got[0].Key != "yard" {
+		t.Errorf("species lookup should ignore case, got %v", got)
+	}
+}
+
+func TestUpdateInquiryStatus(t *testing.T) {
+	initializeData()
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", PetID: "pet-001", AdopterName: "Kiran", Email: "kiran@example.com", Status: "Pending"},
+		AdoptionInquiry{ID: "inq-002", PetID: "pet-002", AdopterName: "Devi", Email: "devi@example.com", Status: "Pending"},
+	)
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	availableBefore := statusCounts["Available"]
+
+	update := func(id, body string) int {
+		req := httptest.NewRequest("PUT", "/api/adoptions/"+id, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := update("inq-001", `{"status":"Approved"}`); code != http.StatusOK {
+		t.Fatalf("expected 200 approving, got %d", code)
+	}
+	if inquiries[0].Status != "Approved" {
+		t.Errorf("expected inquiry approved, got %s", inquiries[0].Status)
+	}
+	if petsByID["pet-001"].Status != "Adopted" {
+		t.Errorf("expected pet-001 adopted, got %s", petsByID["pet-001"].Status)
+	}
+	if statusCounts["Available"] != availableBefore-1 || statusCounts["Adopted"] != 1 {
+		t.Errorf("status counts not updated: %v", statusCounts)
+	}
+
+	if code := update("inq-002", `{"status":"Rejected"}`); code != http.StatusOK {
+		t.Errorf("expected 200 rejecting, got %d", code)
+	}
+	if inquiries[1].Status != "Rejected" || petsByID["pet-002"].Status != "Available" {
+		t.Errorf("rejecting should leave the pet alone, got inquiry %s, pet %s", inquiries[1].Status, petsByID["pet-002"].Status)
+	}
+
+	inquiries = append(inquiries, AdoptionInquiry{ID: "inq-003", PetID: "pet-001", AdopterName: "Ravi", Email: "ravi@example.com", Status: "Pending"})
+	if code := update("inq-003", `{"status":"Approved"}`); code != http.StatusConflict {
+		t.Errorf("expected 409 approving a second adopter for pet-001, got %d", code)
+	}
+	if inquiries[2].Status != "Pending" {
+		t.Errorf("refused approval should leave the inquiry Pending, got %s", inquiries[2].Status)
+	}
+	if code := update("inq-001", `{"status":"Rejected"}`); code != http.StatusOK {
+		t.Fatalf("expected 200 withdrawing the approval, got %d", code)
+	}
+	if petsByID["pet-001"].Status != "Available" || statusCounts["Available"] != availableBefore || statusCounts["Adopted"] != 0 {
+		t.Errorf("withdrawn approval should restore pet-001, got %s with counts %v", petsByID["pet-001"].Status, statusCounts)
+	}
+	if code := update("inq-003", `{"status":"Approved"}`); code != http.StatusOK {
+		t.Errorf("expected 200 approving once the pet is free again, got %d", code)
+	}
+
+	if code := update("inq-002", `{"status":"Maybe"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid status, got %d", code)
+	}
+	if code := update("inq-999", `{"status":"Approved"}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown inquiry, got %d", code)
+	}
+
+	req := httptest.NewRequest("PUT", "/api/adoptions/inq-002", strings.NewReader(`{"status":"Approved"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an admin token, got %d", rr.Code)
+	}
+}
+
+func TestAdoptionApprovalEmail(t *testing.T) {
+	initializeData()
+	emailShouldFail.Store(false)
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", PetID: "pet-002", AdopterName: "Devi", Email: "devi@example.com", Status: "Pending"},
+	)
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	approve := func() {
+		req := httptest.NewRequest("PUT", "/api/adoptions/inq-001", strings.NewReader(`{"status":"Approved"}`))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 approving, got %d", rr.Code)
+		}
+	}
+
+	approve()
+	select {
+	case job := <-notificationCh:
+		if job.JobType != "adoption-approved" || job.To != "devi@example.com" {
+			t.Errorf("unexpected job: %+v", job)
+		}
+		if !strings.Contains(job.Body, "Devi") || !strings.Contains(job.Body, "Luna") {
+			t.Errorf("email should name the adopter and the pet, got %q", job.Body)
+		}
+		if err := deliverNotification(job, 1); err != nil {
+			t.Errorf("delivering approval email: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an approval email to be enqueued")
+	}
+
+	approve()
+	select {
+	case job := <-notificationCh:
+		t.Errorf("re-approving should not send another email, got %+v", job)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBookingConfirmationEmail(t *testing.T) {
+	initializeData()
+	emailShouldFail.Store(false)
+	router := newRouter()
+
+	book := func(serviceID string) int {
+		body := fmt.Sprintf(`{"serviceId":%q,"ownerName":"Asha","email":"asha@example.com","date":"2025-07-01","time":"10:00"}`, serviceID)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(body)))
+		return rr.Code
+	}
+
+	if code := book("svc-001"); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	select {
+	case job := <-notificationCh:
+		if job.JobType != "booking-confirmation" || job.To != "asha@example.com" {
+			t.Errorf("unexpected job: %+v", job)
+		}
+		for _, want := range []string{"Pet Grooming", "2025-07-01", "10:00", "1500.00", "book-001"} {
+			if !strings.Contains(job.Body, want) {
+				t.Errorf("confirmation should mention %q, got %q", want, job.Body)
+			}
+		}
+		if err := deliverNotification(job, 1); err != nil {
+			t.Errorf("delivering confirmation: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a booking confirmation to be enqueued")
+	}
+
+	// A booking for a service we don't know still succeeds, just without an email.
+	if code := book("svc-999"); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	select {
+	case job := <-notificationCh:
+		t.Errorf("expected no email for an unknown service, got %+v", job)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventBusFanOut(t *testing.T) {
+	bus := newEventBus()
+	first, second := bus.Subscribe(), bus.Subscribe()
+
+	bus.Publish(DomainEvent{Type: EventPetAdopted, EntityID: "pet-001"})
+	for i, sub := range []<-chan DomainEvent{first, second} {
+		select {
+		case event := <-sub:
+			if event.Type != EventPetAdopted || event.EntityID != "pet-001" {
+				t.Errorf("subscriber %d: unexpected event %+v", i+1, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("subscriber %d did not receive the event", i+1)
+		}
+	}
+
+	// second stops reading; publishing must not block on it.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < eventBusBuffer+10; i++ {
+			bus.Publish(DomainEvent{Type: EventDonationCompleted, EntityID: fmt.Sprintf("don-%03d", i)})
+			<-first
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+	if len(second) != eventBusBuffer {
+		t.Errorf("slow subscriber should hold a full buffer of %d, got %d", eventBusBuffer, len(second))
+	}
+
+	bus.Unsubscribe(first)
+	if _, open := <-first; open {
+		t.Error("Unsubscribe should close the channel")
+	}
+}
+
+func TestInquiryApprovalPublishesAdoption(t *testing.T) {
+	initializeData()
+	adoptions := eventBus.Subscribe()
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", PetID: "pet-001", AdopterName: "Kiran", Email: "kiran@example.com", Status: "Pending"},
+	)
+
+	if _, _, err := UpdateInquiryStatus("inq-001", "Approved"); err != nil {
+		t.Fatalf("UpdateInquiryStatus: %v", err)
+	}
+	select {
+	case event := <-adoptions:
+		pet, ok := event.Data.(Pet)
+		if event.Type != EventPetAdopted || event.EntityID != "pet-001" || !ok || pet.Status != "Adopted" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	default:
+		t.Fatal("approving an inquiry should publish pet.adopted")
+	}
+
+	UpdateInquiryStatus("inq-001", "Approved")
+	if len(adoptions) != 0 {
+		t.Errorf("an already adopted pet should not be published again, got %d events", len(adoptions))
+	}
+}
+
+func TestFosterApplications(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	Register("foster@example.com", "fosterer", "fosterpass")
+	user, _ := Login("foster@example.com", "fosterpass")
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do("POST", "/api/foster", `{"petId":"pet-001"}`, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 applying without logging in, got %d", rr.Code)
+	}
+	if rr := do("POST", "/api/foster", `{"petId":"pet-999"}`, user.Token); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown pet, got %d", rr.Code)
+	}
+
+	rr := do("POST", "/api/foster", `{"petId":"pet-001","phone":"98765 43210","message":"Happy to help","status":"Approved"}`, user.Token)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Data FosterApplication `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+	app := created.Data
+	if app.Status != "Pending" || app.UserID != user.UserID || app.Email != "foster@example.com" {
+		t.Errorf("expected a Pending application linked to the user, got %+v", app)
+	}
+	if rr := do("POST", "/api/foster", `{"message":"Any pet"}`, user.Token); rr.Code != http.StatusCreated {
+		t.Errorf("expected 201 without a pet, got %d", rr.Code)
+	}
+
+	if rr := do("GET", "/api/foster?status=pending", "", user.Token); rr.Code != http.StatusForbidden {
+		t.Errorf("expected 403 listing as a normal user, got %d", rr.Code)
+	}
+	var list struct {
+		Data []FosterApplication `json:"data"`
+	}
+	json.NewDecoder(do("GET", "/api/foster?status=pending", "", admin.Token).Body).Decode(&list)
+	if len(list.Data) != 2 {
+		t.Errorf("expected 2 pending applications, got %d", len(list.Data))
+	}
+
+	if rr := do("PUT", "/api/foster/"+app.ID, `{"status":"Maybe"}`, admin.Token); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid status, got %d", rr.Code)
+	}
+	if rr := do("PUT", "/api/foster/fst-999", `{"status":"Approved"}`, admin.Token); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown application, got %d", rr.Code)
+	}
+	if rr := do("PUT", "/api/foster/"+app.ID, `{"status":"Approved"}`, admin.Token); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 approving, got %d", rr.Code)
+	}
+	if petsByID["pet-001"].Status != "In Foster" || statusCounts["In Foster"] != 1 {
+		t.Errorf("expected pet-001 In Foster, got %s (counts %v)", petsByID["pet-001"].Status, statusCounts)
+	}
+
+	sent := map[string]bool{}
+	timeout := time.After(time.Second)
+	for !sent["foster"] || !sent["foster-status"] {
+		select {
+		case job := <-notificationCh:
+			if job.To == "foster@example.com" {
+				sent[job.JobType] = true
+			}
+		case <-timeout:
+			t.Fatalf("expected received and status emails, got %v", sent)
+		}
+	}
+
+	// Re-saving the approval sends nothing more.
+	if rr := do("PUT", "/api/foster/"+app.ID, `{"status":"Approved"}`, admin.Token); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 re-saving the approval, got %d", rr.Code)
+	}
+	for wait := time.After(50 * time.Millisecond); ; {
+		select {
+		case job := <-notificationCh:
+			if job.JobType == "foster-status" {
+				t.Errorf("expected no status email re-saving an approval, got one to %s", job.To)
+			}
+			continue
+		case <-wait:
+		}
+		break
+	}
+
+	// Withdrawing the approval puts the pet back.
+	if rr := do("PUT", "/api/foster/"+app.ID, `{"status":"Rejected"}`, admin.Token); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 rejecting, got %d", rr.Code)
+	}
+	if petsByID["pet-001"].Status != "Available" || statusCounts["In Foster"] != 0 {
+		t.Errorf("expected pet-001 Available again, got %s (counts %v)", petsByID["pet-001"].Status, statusCounts)
+	}
+}
+
+func TestAdoptionFeeCollected(t *testing.T) {
+	initializeData()
+	if valid, _ := validatePet(Pet{Name: "Coco", Species: "Cat", Status: "Available", AdoptionFee: -1}); valid {
+		t.Error("expected negative adoption fee to be rejected")
+	}
+
+	UpdatePet("pet-002", Pet{AdoptionFee: 1500})
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", PetID: "pet-001", Status: "Approved"},
+		AdoptionInquiry{ID: "inq-002", PetID: "pet-002", Status: "Approved"},
+	)
+	donations = append(donations, Donation{ID: "don-001", Amount: 2500, Status: "Completed"})
+
+	if _, err := LinkAdoptionFee("inq-001", "don-001"); err != nil {
+		t.Fatalf("LinkAdoptionFee failed: %v", err)
+	}
+	inquiry, err := RecordAdoptionFee("inq-002")
+	if err != nil {
+		t.Fatalf("RecordAdoptionFee failed: %v", err)
+	}
+	if !inquiry.FeePaid || inquiry.FeeAmount != 1500 {
+		t.Errorf("expected fee of 1500 recorded, got paid=%v amount=%.2f", inquiry.FeePaid, inquiry.FeeAmount)
+	}
+	if _, err := RecordAdoptionFee("inq-001"); !errors.Is(err, ErrFeeAlreadyPaid) {
+		t.Errorf("expected ErrFeeAlreadyPaid over a linked donation, got %v", err)
+	}
+	if _, err := RecordAdoptionFee("inq-002"); !errors.Is(err, ErrFeeAlreadyPaid) {
+		t.Errorf("expected ErrFeeAlreadyPaid recording twice, got %v", err)
+	}
+	if inquiries[0].FeeAmount != 2500 {
+		t.Errorf("linked donation amount should be kept, got %.2f", inquiries[0].FeeAmount)
+	}
+
+	stats := calculateStatistics()
+	if stats["adoptionFeesCollected"] != 4000.0 {
+		t.Errorf("expected 4000 in adoption fees collected, got %v", stats["adoptionFeesCollected"])
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	check := func() (int, map[string]interface{}) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/health", nil))
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp.Data
+	}
+
+	code, data := check()
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 without MongoDB configured, got %d", code)
+	}
+	if data["status"] != "ok" || data["database"] != "disabled" {
+		t.Errorf("unexpected health data: %v", data)
+	}
+	if _, ok := data["queues"].(map[string]interface{})["notifications"]; !ok {
+		t.Errorf("expected queue depths, got %v", data["queues"])
+	}
+
+	// Configured but never connected: the database is unreachable.
+	mongoConfigured = true
+	defer func() { mongoConfigured = false }()
+	code, data = check()
+	if code != http.StatusServiceUnavailable || data["database"] != "unreachable" {
+		t.Errorf("expected 503 with database unreachable, got %d %v", code, data)
+	}
+
+	// The check must not wait on mu.
+	mu.Lock()
+	done := make(chan int)
+	go func() {
+		c, _ := check()
+		done <- c
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("health check blocked on mu")
+	}
+	mu.Unlock()
+}
+
+// Run with -race: statistics must not read pets or statusCounts while
+// UpdatePet mutates them.
+func TestStatisticsConcurrentWithUpdates(t *testing.T) {
+	initializeData()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			status := "Available"
+			if i%2 == 0 {
+				status = "Adopted"
+			}
+			if _, err := UpdatePet("pet-001", Pet{Status: status}); err != nil {
+				t.Errorf("UpdatePet failed: %v", err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			rr := httptest.NewRecorder()
+			getStatisticsHandler(rr, httptest.NewRequest("GET", "/api/statistics", nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d", rr.Code)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	stats := calculateStatistics()
+	byStatus := stats["petsByStatus"].(map[string]int)
+	byStatus["Available"] = 999
+	if statusCounts["Available"] == 999 {
+		t.Error("petsByStatus should be a copy, not the live statusCounts map")
+	}
+}
+
+// mockSMSSender records messages instead of sending them.
+type mockSMSSender struct {
+	sent chan string
+}
+
+func (m mockSMSSender) Send(to, message string) error {
+	m.sent <- to
+	return nil
+}
+
+func TestDonationSMS(t *testing.T) {
+	initializeData()
+	mock := mockSMSSender{sent: make(chan string, 1)}
+	origSender, origEnabled := smsSender, smsEnabled
+	smsSender, smsEnabled = mock, true
+	defer func() { smsSender, smsEnabled = origSender, origEnabled }()
+
+	_, err := ProcessDonation(&Donation{DonorName: "Asha", DonorEmail: "asha@example.com", DonorPhone: "+919800000000", Amount: 300, PaymentMethod: "UPI"})
+	if err != nil {
+		t.Fatalf("ProcessDonation failed: %v", err)
+	}
+	select {
+	case to := <-mock.sent:
+		if to != "+919800000000" {
+			t.Errorf("expected SMS to donor phone, got %s", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected thank-you SMS to be sent")
+	}
+
+	ProcessDonation(&Donation{DonorName: "Ravi", DonorEmail: "ravi@example.com", Amount: 300, PaymentMethod: "UPI"})
+	select {
+	case to := <-mock.sent:
+		t.Errorf("no SMS expected without a phone, got one to %s", to)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// mockCaptchaVerifier accepts only the token "valid" and counts calls.
+type mockCaptchaVerifier struct {
+	calls *int
+}
+
+func (m mockCaptchaVerifier) Verify(token, remoteIP string) (bool, error) {
+	*m.calls++
+	return token == "valid", nil
+}
+
+func TestCaptchaVerification(t *testing.T) {
+	initializeData()
+	calls := 0
+	origVerifier, origEnabled := captchaVerifier, captchaEnabled
+	captchaVerifier, captchaEnabled = mockCaptchaVerifier{calls: &calls}, true
+	defer func() { captchaVerifier, captchaEnabled = origVerifier, origEnabled }()
+
+	submit := func(token string) int {
+		body := fmt.Sprintf(`{"name":"Test","email":"t@example.com","message":"Hi","captchaToken":%q}`, token)
+		req := httptest.NewRequest("POST", "/api/contact", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		submitContactHandler(rr, req)
+		return rr.Code
+	}
+
+	if code := submit("valid"); code != http.StatusOK {
+		t.Errorf("expected 200 for valid token, got %d", code)
+	}
+	if code := submit("bogus"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid token, got %d", code)
+	}
+	if code := submit(""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for missing token, got %d", code)
+	}
+
+	captchaEnabled = false
+	calls = 0
+	if code := submit(""); code != http.StatusOK {
+		t.Errorf("expected 200 with CAPTCHA disabled, got %d", code)
+	}
+	if calls != 0 {
+		t.Errorf("verifier should not be called when disabled, got %d calls", calls)
+	}
+}
+
+func TestCancelBooking(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		body := `{"serviceId":"svc-001","ownerName":"Asha","email":"asha@example.com"}`
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(body)))
+		var resp struct {
+			Data ServiceBooking `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		ids = append(ids, resp.Data.ID)
+	}
+	if n := serviceStats["svc-001"]["bookings"]; n != 3 {
+		t.Fatalf("expected 3 bookings counted, got %v", n)
+	}
+
+	Register("asha@example.com", "asha", "pass123")
+	Register("ravi@example.com", "ravi", "pass123")
+	owner, _ := Login("asha@example.com", "pass123")
+	stranger, _ := Login("ravi@example.com", "pass123")
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	cancelAs := func(id, token string) int {
+		req := httptest.NewRequest("DELETE", "/api/bookings/"+id, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	cancel := func(id string) int { return cancelAs(id, owner.Token) }
+
+	// Only the owner or an admin may cancel.
+	if code := cancelAs(ids[0], ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+	if code := cancelAs(ids[0], stranger.Token); code != http.StatusForbidden {
+		t.Errorf("expected 403 cancelling someone else's booking, got %d", code)
+	}
+	if code := cancelAs(ids[2], admin.Token); code != http.StatusOK {
+		t.Errorf("expected admins to cancel any booking, got %d", code)
+	}
+	if n := serviceStats["svc-001"]["bookings"]; n != 2 {
+		t.Fatalf("expected 2 bookings counted after the admin cancel, got %v", n)
+	}
+
+	if code := cancel(ids[0]); code != http.StatusOK {
+		t.Fatalf("expected 200 cancelling %s, got %d", ids[0], code)
+	}
+	if bookings[0].Status != "Cancelled" {
+		t.Errorf("expected the stored booking to be Cancelled, got %s", bookings[0].Status)
+	}
+	if n := serviceStats["svc-001"]["bookings"]; n != 1 {
+		t.Errorf("expected booking count to drop to 1, got %v", n)
+	}
+	if code := cancel(ids[0]); code != http.StatusConflict {
+		t.Errorf("expected 409 cancelling twice, got %d", code)
+	}
+	if err := CancelBooking(ids[0]); !errors.Is(err, ErrBookingCancelled) {
+		t.Errorf("expected ErrBookingCancelled, got %v", err)
+	}
+	if code := cancel("book-999"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown booking, got %d", code)
+	}
+
+	// A service that already happened can't be cancelled or uncounted.
+	bookingsByID[ids[1]].Status = "Completed"
+	if code := cancel(ids[1]); code != http.StatusConflict {
+		t.Errorf("expected 409 cancelling a completed booking, got %d", code)
+	}
+	if err := CancelBooking(ids[1]); !errors.Is(err, ErrBookingCompleted) {
+		t.Errorf("expected ErrBookingCompleted, got %v", err)
+	}
+	if n := serviceStats["svc-001"]["bookings"]; n != 1 {
+		t.Errorf("expected booking count to stay at 1, got %v", n)
+	}
+}
+
+func TestConcurrentBookings(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			body := `{"serviceId":"svc-001","ownerName":"Asha","email":"asha@example.com"}`
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(body)))
+			if rr.Code != http.StatusCreated {
+				t.Errorf("expected 201, got %d", rr.Code)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for _, path := range []string{"/api/bookings", fmt.Sprintf("/api/bookings/book-%03d", i), "/api/statistics"} {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+			}
+		}(i + 1)
+	}
+	wg.Wait()
+
+	if len(bookings) != n || len(bookingsByID) != n {
+		t.Fatalf("expected %d bookings, got %d (map %d)", n, len(bookings), len(bookingsByID))
+	}
+	for i := range bookings {
+		if bookingsByID[bookings[i].ID] != &bookings[i] {
+			t.Errorf("bookingsByID[%s] doesn't point at the stored booking", bookings[i].ID)
+		}
+	}
+	if got := serviceStats["svc-001"]["bookings"]; got != n {
+		t.Errorf("expected %d bookings counted, got %v", n, got)
+	}
+}
+
+func TestBookingConflict(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	book := func(serviceID, date, slot string) int {
+		body := fmt.Sprintf(`{"serviceId":%q,"ownerName":"Asha","email":"asha@example.com","date":%q,"time":%q}`, serviceID, date, slot)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(body)))
+		return rr.Code
+	}
+
+	if code := book("svc-001", "2025-07-01", "10:00"); code != http.StatusCreated {
+		t.Fatalf("expected 201 for a free slot, got %d", code)
+	}
+	if !hasBookingConflict("svc-001", "2025-07-01", "10:00") {
+		t.Error("expected the booked slot to conflict")
+	}
+	if code := book("svc-001", "2025-07-01", "10:00"); code != http.StatusConflict {
+		t.Errorf("expected 409 for the same slot, got %d", code)
+	}
+	if len(bookings) != 1 {
+		t.Errorf("a rejected booking must not be stored, have %d", len(bookings))
+	}
+
+	// Another time, another service, or a package covering the slot.
+	if code := book("svc-001", "2025-07-01", "11:00"); code != http.StatusCreated {
+		t.Errorf("expected 201 for a different time, got %d", code)
+	}
+	if code := book("svc-002", "2025-07-01", "10:00"); code != http.StatusCreated {
+		t.Errorf("expected 201 for a different service, got %d", code)
+	}
+	pkg, err := AddPackage(ServicePackage{Name: "Bundle", ServiceIDs: []string{"svc-001", "svc-003"}, Active: true})
+	if err != nil {
+		t.Fatalf("AddPackage failed: %v", err)
+	}
+	if _, err := BookPackage(pkg.ID, ServiceBooking{OwnerName: "Ravi", Email: "ravi@example.com", Date: "2025-07-01", Time: "11:00"}); !errors.Is(err, ErrBookingConflict) {
+		t.Errorf("expected ErrBookingConflict booking a package into a taken slot, got %v", err)
+	}
+
+	// Cancelling frees the slot again.
+	if err := CancelBooking(bookings[0].ID); err != nil {
+		t.Fatalf("CancelBooking failed: %v", err)
+	}
+	if code := book("svc-001", "2025-07-01", "10:00"); code != http.StatusCreated {
+		t.Errorf("expected 201 after the clashing booking was cancelled, got %d", code)
+	}
+}
+
+func TestGetBooking(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	body := `{"serviceId":"svc-001","ownerName":"Asha","email":"asha@example.com"}`
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(body)))
+	var created struct {
+		Data ServiceBooking `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/bookings/"+created.Data.ID, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 fetching %s, got %d", created.Data.ID, rr.Code)
+	}
+	var resp struct {
+		Data BookingDetail `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Data.ID != created.Data.ID || resp.Data.OwnerName != "Asha" {
+		t.Errorf("expected the created booking, got %+v", resp.Data.ServiceBooking)
+	}
+	if resp.Data.Service == nil || resp.Data.Service.ID != "svc-001" {
+		t.Errorf("expected service svc-001 joined in, got %+v", resp.Data.Service)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/bookings/book-999", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown booking, got %d", rr.Code)
+	}
+}
+
+func TestTestimonials(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	list := func(auth string) []Testimonial {
+		req := httptest.NewRequest("GET", "/api/testimonials?pending=true", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp struct {
+			Data []Testimonial `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.Data
+	}
+
+	body := `{"author":"Priya","text":"We adopted <b>Luna</b> last spring! <img src=x onerror=alert(1)","petName":"Luna","approved":true}`
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/testimonials", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "approvedAt") {
+		t.Errorf("a pending testimonial should omit approvedAt, got %s", rr.Body.String())
+	}
+	var created struct {
+		Data Testimonial `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+	if created.Data.Approved {
+		t.Error("a submission must start unapproved even if it claims otherwise")
+	}
+	// Stored as typed, escaped on the way out, unclosed tags included.
+	if testimonials[0].Text != "We adopted <b>Luna</b> last spring! <img src=x onerror=alert(1)" {
+		t.Errorf("expected the text stored as submitted, got %q", testimonials[0].Text)
+	}
+	if created.Data.Text != "We adopted &lt;b&gt;Luna&lt;/b&gt; last spring! &lt;img src=x onerror=alert(1)" {
+		t.Errorf("expected the text HTML-escaped in the response, got %q", created.Data.Text)
+	}
+
+	if got := list(""); len(got) != 0 {
+		t.Errorf("public list should exclude unapproved testimonials, got %d", len(got))
+	}
+	if got := list(admin.Token); len(got) != 1 {
+		t.Errorf("admins should see pending testimonials, got %d", len(got))
+	}
+	if n := pendingCounts()["testimonials"]; n != 1 {
+		t.Errorf("expected 1 testimonial awaiting review, got %d", n)
+	}
+
+	approve := func(id, auth string) int {
+		req := httptest.NewRequest("POST", "/api/testimonials/"+id+"/approve", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := approve(created.Data.ID, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 approving without a token, got %d", code)
+	}
+	if code := approve(created.Data.ID, admin.Token); code != http.StatusOK {
+		t.Fatalf("expected 200 approving, got %d", code)
+	}
+	if got := list(""); len(got) != 1 || got[0].Author != "Priya" || !got[0].Approved || got[0].ApprovedAt == nil {
+		t.Errorf("approved testimonial should be public, got %+v", got)
+	}
+	if code := approve("tst-999", admin.Token); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown testimonial, got %d", code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/testimonials", strings.NewReader(`{"author":" \u0007 ","text":"hi"}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when the author is empty after sanitizing, got %d", rr.Code)
+	}
+}
+
+func TestFreeTextEscapedOnOutput(t *testing.T) {
+	payload := `<img src=x onerror=alert(1)`
+	for name, v := range map[string]interface{}{
+		"volunteer": Volunteer{Name: payload, Interests: []string{payload}},
+		"event":     Event{Title: payload, Location: payload},
+		"foster":    FosterApplication{Message: payload},
+	} {
+		out, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var decoded map[string]interface{}
+		json.Unmarshal(out, &decoded)
+		if strings.Contains(fmt.Sprint(decoded), "<img") {
+			t.Errorf("%s: expected markup escaped, got %s", name, out)
+		}
+	}
+}
+
+func TestVolunteerSignUp(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/volunteers", strings.NewReader(body)))
+		return rr
+	}
+
+	rr := post(`{"name":"Anil","email":"Anil@Example.com","phone":"+91 98765 43210","interests":["dog walking","events","dog walking"],"availability":"weekends","status":"Active"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Data Volunteer `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+	v := created.Data
+	if v.ID == "" || v.Status != "Pending" || v.Email != "anil@example.com" {
+		t.Errorf("expected a Pending sign-up with a normalized email, got %+v", v)
+	}
+	if !reflect.DeepEqual(v.Interests, []string{"dog walking", "events"}) {
+		t.Errorf("expected duplicate interests dropped, got %v", v.Interests)
+	}
+
+	var job NotificationJob
+	timeout := time.After(time.Second)
+	for job.JobType != "volunteer" {
+		select {
+		case job = <-notificationCh:
+		case <-timeout:
+			t.Fatal("expected a confirmation email to be queued")
+		}
+	}
+	if job.To != "anil@example.com" {
+		t.Errorf("expected confirmation to the volunteer, got %s", job.To)
+	}
+
+	if rr := post(`{"name":"Bea","email":"not-an-email","availability":"evenings"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid email, got %d", rr.Code)
+	}
+	if rr := post(`{"name":"Bea","email":"bea@example.com"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without availability, got %d", rr.Code)
+	}
+	post(`{"name":"Bea","email":"bea@example.com","availability":"evenings"}`)
+	volunteers[1].Status = "Active"
+
+	list := func(query, token string) (int, []Volunteer) {
+		req := httptest.NewRequest("GET", "/api/volunteers"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp struct {
+			Data []Volunteer `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp.Data
+	}
+	if code, _ := list("", ""); code != http.StatusUnauthorized {
+		t.Errorf("listing volunteers should require admin, got %d", code)
+	}
+	if _, all := list("", admin.Token); len(all) != 2 {
+		t.Errorf("expected 2 volunteers, got %d", len(all))
+	}
+	if _, pending := list("?status=pending", admin.Token); len(pending) != 1 || pending[0].Name != "Anil" {
+		t.Errorf("expected only Anil pending, got %+v", pending)
+	}
+	if n := pendingCounts()["volunteers"]; n != 1 {
+		t.Errorf("expected 1 volunteer awaiting contact, got %d", n)
+	}
+}
+
+func TestEventDateValidation(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/events", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name     string
+		startsAt string
+		endsAt   string
+		wantCode int
+	}{
+		{"ends after start", "2030-05-01T10:00:00Z", "2030-05-01T16:00:00Z", http.StatusCreated},
+		{"ends before start", "2030-05-01T16:00:00Z", "2030-05-01T10:00:00Z", http.StatusBadRequest},
+		{"ends at start", "2030-05-01T10:00:00Z", "2030-05-01T10:00:00Z", http.StatusBadRequest},
+		{"missing end", "2030-05-01T10:00:00Z", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body := fmt.Sprintf(`{"title":"Adoption Day","startsAt":%q}`, tt.startsAt)
+		if tt.endsAt != "" {
+			body = fmt.Sprintf(`{"title":"Adoption Day","startsAt":%q,"endsAt":%q}`, tt.startsAt, tt.endsAt)
+		}
+		rr := post(body)
+		if rr.Code != tt.wantCode {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.wantCode, rr.Code, rr.Body.String())
+			continue
+		}
+		if tt.wantCode == http.StatusBadRequest && !strings.Contains(rr.Body.String(), "INVALID_EVENT_TIME") {
+			t.Errorf("%s: expected INVALID_EVENT_TIME, got %s", tt.name, rr.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/api/events", strings.NewReader(`{"title":"x"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("create without token: expected 401, got %d", rr.Code)
+	}
+
+	event := events[0]
+	event.EndsAt = event.StartsAt.Add(-time.Hour)
+	if _, err := UpdateEvent(event.ID, event); err != ErrInvalidEventTime {
+		t.Errorf("update with end before start: expected ErrInvalidEventTime, got %v", err)
+	}
+}
+
+func TestEventsUpcomingOnly(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	now := time.Now()
+	past, _ := CreateEvent(Event{Title: "Spring Drive", StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-44 * time.Hour), Published: true})
+	later, _ := CreateEvent(Event{Title: "Summer Drive", StartsAt: now.Add(72 * time.Hour), EndsAt: now.Add(76 * time.Hour), Published: true})
+	soon, _ := CreateEvent(Event{Title: "Adoption Day", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Published: true})
+	draft, _ := CreateEvent(Event{Title: "Gala", StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(28 * time.Hour)})
+
+	list := func(query, token string) []string {
+		req := httptest.NewRequest("GET", "/api/events"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp struct {
+			Data []Event `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		ids := make([]string, 0, len(resp.Data))
+		for _, e := range resp.Data {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	if got, want := list("", ""), []string{soon.ID, later.ID}; !slices.Equal(got, want) {
+		t.Errorf("public list: expected %v, got %v", want, got)
+	}
+	if got, want := list("?past=true", ""), []string{past.ID, soon.ID, later.ID}; !slices.Equal(got, want) {
+		t.Errorf("?past=true: expected %v, got %v", want, got)
+	}
+	if got, want := list("?drafts=true", ""), []string{soon.ID, later.ID}; !slices.Equal(got, want) {
+		t.Errorf("drafts must stay hidden from the public, expected %v, got %v", want, got)
+	}
+	if got, want := list("?drafts=true", admin.Token), []string{soon.ID, draft.ID, later.ID}; !slices.Equal(got, want) {
+		t.Errorf("admin ?drafts=true: expected %v, got %v", want, got)
+	}
+
+	req := httptest.NewRequest("DELETE", "/api/events/"+later.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", rr.Code)
+	}
+	if got, want := list("", ""), []string{soon.ID}; !slices.Equal(got, want) {
+		t.Errorf("after delete: expected %v, got %v", want, got)
+	}
+}
+
+func TestHoneypotDropsSubmissions(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	post := func(path, body string) int {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rr.Code
+	}
+
+	code := post("/api/contact", `{"name":"Bot","email":"bot@example.com","message":"buy now","website":"http://spam.example"}`)
+	if code != http.StatusOK || len(contactMessages) != 0 {
+		t.Errorf("expected fake 200 and no stored message, got %d with %d messages", code, len(contactMessages))
+	}
+	code = post("/api/donations", `{"donorName":"Bot","donorEmail":"bot@example.com","amount":10,"paymentMethod":"UPI","website":"x"}`)
+	if code != http.StatusOK || len(donations) != 0 {
+		t.Errorf("expected fake 200 and no stored donation, got %d with %d donations", code, len(donations))
+	}
+	code = post("/api/auth/register", `{"email":"bot@example.com","username":"botty","password":"pass123","website":"x"}`)
+	if _, pending := pendingRegs["bot@example.com"]; code != http.StatusOK || pending {
+		t.Errorf("expected fake 200 and no pending registration, got %d (pending=%v)", code, pending)
+	}
+
+	if code := post("/api/contact", `{"name":"Asha","email":"asha@example.com","message":"hi","website":""}`); code != http.StatusOK || len(contactMessages) != 1 {
+		t.Errorf("expected an empty honeypot to be accepted, got %d with %d messages", code, len(contactMessages))
+	}
+
+	orig := honeypotField
+	honeypotField = "fax"
+	defer func() { honeypotField = orig }()
+	post("/api/contact", `{"name":"Bot","email":"bot@example.com","message":"spam","fax":"555"}`)
+	if len(contactMessages) != 1 {
+		t.Errorf("expected the configured honeypot field to be checked, got %d messages", len(contactMessages))
+	}
+}
+
+func TestRateService(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	rate := func(body, token string) int {
+		req := httptest.NewRequest("POST", "/api/services/svc-001/rate", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	for _, r := range []int{5, 4, 3, 4} {
+		if code := rate(fmt.Sprintf(`{"rating":%d}`, r), ""); code != http.StatusOK {
+			t.Fatalf("rating %d: expected 200, got %d", r, code)
+		}
+	}
+	if code := rate(`{"rating":6}`, ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a rating of 6, got %d", code)
+	}
+	if code := rate(`{"rating":0}`, ""); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a rating of 0, got %d", code)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/services/svc-001", nil))
+	var resp struct {
+		Data ServiceDetail `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Data.ID != "svc-001" || resp.Data.RatingCount != 4 || resp.Data.Rating != 4.0 {
+		t.Errorf("expected average 4.0 over 4 ratings, got %.2f over %d", resp.Data.Rating, resp.Data.RatingCount)
+	}
+
+	// A rating tied to a booking needs the booking to be completed, rated
+	// only once, and rated by its owner or an admin.
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(`{"serviceId":"svc-001","ownerName":"Asha","email":"asha@example.com"}`)))
+	var created struct {
+		Data ServiceBooking `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+	bookingID := created.Data.ID
+
+	Register("asha@example.com", "asha", "pass123")
+	Register("ravi@example.com", "ravi", "pass123")
+	owner, _ := Login("asha@example.com", "pass123")
+	stranger, _ := Login("ravi@example.com", "pass123")
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	body := fmt.Sprintf(`{"rating":5,"bookingId":%q}`, bookingID)
+
+	if code := rate(body, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 rating a booking without a token, got %d", code)
+	}
+	if code := rate(body, stranger.Token); code != http.StatusForbidden {
+		t.Errorf("expected 403 rating someone else's booking, got %d", code)
+	}
+	if code := rate(body, owner.Token); code != http.StatusConflict {
+		t.Errorf("expected 409 rating a pending booking, got %d", code)
+	}
+	if code := rate(`{"rating":5,"bookingId":"book-999"}`, owner.Token); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown booking, got %d", code)
+	}
+
+	complete := func(token string) int {
+		req := httptest.NewRequest("PUT", "/api/bookings/"+bookingID+"/complete", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := complete(owner.Token); code != http.StatusForbidden {
+		t.Errorf("expected 403 completing a booking as a non-admin, got %d", code)
+	}
+	if code := complete(admin.Token); code != http.StatusOK {
+		t.Fatalf("expected 200 completing a booking, got %d", code)
+	}
+	if code := complete(admin.Token); code != http.StatusConflict {
+		t.Errorf("expected 409 completing a booking twice, got %d", code)
+	}
+
+	if code := rate(body, owner.Token); code != http.StatusOK {
+		t.Errorf("expected owner to rate a completed booking, got %d", code)
+	}
+	if code := rate(body, admin.Token); code != http.StatusConflict {
+		t.Errorf("expected 409 rating a booking twice, got %d", code)
+	}
+	if _, err := RateService("svc-001", 5, bookingID); !errors.Is(err, ErrBookingRated) {
+		t.Errorf("expected ErrBookingRated, got %v", err)
+	}
+	if _, err := RateService("svc-999", 5, ""); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("expected ErrServiceNotFound, got %v", err)
+	}
+}
+
+func TestEmptyListsAreArrays(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	donations = nil
+
+	for _, path := range []string{
+		"/api/services?category=Astrology",
+		"/api/donations",
+		"/api/pets?species=Parrot",
+		"/api/pets?page=50",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp map[string]json.RawMessage
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if string(resp["data"]) != "[]" {
+			t.Errorf("GET %s: expected data to be [], got %s", path, resp["data"])
+		}
+	}
+}
+
+func TestUPILink(t *testing.T) {
+	initializeData()
+	orig := upiPayeeVPA
+	upiPayeeVPA = "shelter@okbank"
+	defer func() { upiPayeeVPA = orig }()
+
+	router := newRouter()
+	requestLink := func(amount string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/donations/upi-link", strings.NewReader(`{"amount":`+amount+`}`)))
+		return rr
+	}
+	rr := requestLink("500")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	var resp struct {
+		Data struct {
+			Link       string `json:"link"`
+			DonationID string `json:"donationId"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	link := resp.Data.Link
+	if !strings.HasPrefix(link, "upi://pay?") || !strings.Contains(link, "pa=shelter@okbank") || !strings.Contains(link, "am=500.00") {
+		t.Errorf("expected link with configured VPA and amount, got %s", link)
+	}
+	if !strings.Contains(link, "tr="+resp.Data.DonationID) {
+		t.Errorf("expected donation ID as transaction ref, got %s", link)
+	}
+	d := findDonation(resp.Data.DonationID)
+	if d == nil || d.Status != "Pending" || !d.PaymentViaDeeplink {
+		t.Errorf("expected a pending deeplink donation to be registered, got %+v", d)
+	}
+
+	for _, amount := range []string{"0", "100001", `"abc"`} {
+		if rr := requestLink(amount); rr.Code != http.StatusBadRequest {
+			t.Errorf("amount=%s: expected 400, got %d", amount, rr.Code)
+		}
+	}
+	for _, amount := range []float64{math.NaN(), math.Inf(1)} {
+		if _, _, err := CreatePendingUPIDonation(amount, "", ""); !errors.Is(err, ErrInvalidPayment) {
+			t.Errorf("amount=%v: expected ErrInvalidPayment, got %v", amount, err)
+		}
+	}
+
+	// Four requests so far; use up the rest of this client's minute.
+	for i := 4; i < upiLinkRateLimit; i++ {
+		requestLink("500")
+	}
+	if rr := requestLink("500"); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After once over the limit, got %d", rr.Code)
+	}
+}
+
+func TestUPICallback(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	donation, _, err := CreatePendingUPIDonation(500, "Meera", "meera@example.com")
+	if err != nil {
+		t.Fatalf("CreatePendingUPIDonation failed: %v", err)
+	}
+
+	callback := func(body, signature string) int {
+		req := httptest.NewRequest("POST", "/api/donations/upi-callback", strings.NewReader(body))
+		req.Header.Set("X-Signature", signature)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(upiCallbackSecret))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	paid := fmt.Sprintf(`{"ref":%q,"txnId":"UTR123","status":"SUCCESS","amount":500}`, donation.ID)
+
+	if code := callback(paid, ""); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a callback secret, got %d", code)
+	}
+	upiCallbackSecret = "s3cret"
+	defer func() { upiCallbackSecret = "" }()
+
+	if code := callback(paid, sign("other body")); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a bad signature, got %d", code)
+	}
+	short := fmt.Sprintf(`{"ref":%q,"txnId":"UTR123","status":"SUCCESS","amount":5}`, donation.ID)
+	if code := callback(short, sign(short)); code != http.StatusBadRequest {
+		t.Errorf("expected 400 when the paid amount doesn't match, got %d", code)
+	}
+	if code := callback(paid, sign(paid)); code != http.StatusOK {
+		t.Fatalf("expected 200 reconciling the payment, got %d", code)
+	}
+	if d := findDonation(donation.ID); d.Status != "Completed" || d.TransactionID != "UTR123" {
+		t.Errorf("expected the donation completed with the provider's txn, got %+v", d)
+	}
+	if code := callback(paid, sign(paid)); code != http.StatusOK {
+		t.Errorf("expected a repeated callback to be accepted, got %d", code)
+	}
+	failed := fmt.Sprintf(`{"ref":%q,"txnId":"UTR999","status":"FAILURE"}`, donation.ID)
+	if code := callback(failed, sign(failed)); code != http.StatusConflict {
+		t.Errorf("expected 409 once the donation is settled, got %d", code)
+	}
+}
+
+func TestExpireStaleDonations(t *testing.T) {
+	initializeData()
+	now := time.Now()
+	donations = []Donation{
+		{ID: "don-001", Status: "Pending", CreatedAt: now.Add(-pendingDonationTTL - time.Hour)},
+		{ID: "don-002", Status: "Pending", CreatedAt: now.Add(-time.Minute)},
+		{ID: "don-003", Status: "Completed", CreatedAt: now.Add(-pendingDonationTTL - time.Hour)},
+	}
+
+	if got := staleDonations(donations, now.Add(-pendingDonationTTL)); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("expected only the old pending donation to be stale, got %v", got)
+	}
+
+	expireStaleDonations(now)
+	want := []string{"Expired", "Pending", "Completed"}
+	for i, d := range donations {
+		if d.Status != want[i] {
+			t.Errorf("%s: expected %s, got %s", d.ID, want[i], d.Status)
+		}
+	}
+}
+
+func TestServiceCategoryNormalization(t *testing.T) {
+	initializeData()
+
+	if category, ok := normalizeServiceCategory(" grooming"); !ok || category != "Grooming" {
+		t.Errorf("expected category normalized to Grooming, got %q", category)
+	}
+	if _, ok := normalizeServiceCategory("Pampering"); ok {
+		t.Error("expected Pampering to be rejected")
+	}
+
+	req := httptest.NewRequest("GET", "/api/services?category=MEDICAL", nil)
+	rr := httptest.NewRecorder()
+	getServicesHandler(rr, req)
+
+	var resp struct {
+		Data []Service `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Data) != 1 || resp.Data[0].Category != "Medical" {
+		t.Errorf("expected 1 Medical service for ?category=MEDICAL, got %v", resp.Data)
+	}
+}
+
+func TestCreateService(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	before := len(services)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/services", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := create(`{"name":"Puppy Class","category":"training","price":0,"duration":45,"available":true}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Data Service `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+	wantID := fmt.Sprintf("svc-%03d", before+1)
+	if created.Data.ID != wantID || created.Data.Category != "Training" {
+		t.Errorf("expected %s in Training, got %+v", wantID, created.Data)
+	}
+	if _, ok := servicesByID[wantID]; !ok {
+		t.Error("new service should be indexed by ID")
+	}
+	if _, ok := serviceStats[wantID]; !ok {
+		t.Error("new service should have a stats entry")
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"missing name and category", `{"price":100}`, []string{"Service name is required", "Category is required"}},
+		{"unknown category", `{"name":"Spa","category":"Pampering","price":100}`, []string{"Category must be one of: Care, Medical, Training, Grooming, Boarding"}},
+		{"negative price", `{"name":"Bath","category":"Grooming","price":-5}`, []string{"Price cannot be negative"}},
+	}
+	for _, tt := range tests {
+		rr := create(tt.body)
+		var resp struct {
+			Code   string   `json:"code"`
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if rr.Code != http.StatusBadRequest || resp.Code != "VALIDATION_FAILED" || !slices.Equal(resp.Errors, tt.want) {
+			t.Errorf("%s: expected 400 with %v, got %d %s %v", tt.name, tt.want, rr.Code, resp.Code, resp.Errors)
+		}
+	}
+	if _, err := AddService(Service{Name: "Bath", Category: "Grooming", Price: -5}); err == nil || err.Error() != "Price cannot be negative" {
+		t.Errorf("expected AddService to apply validateService, got %v", err)
+	}
+	if len(services) != before+1 {
+		t.Errorf("invalid services must not be stored, have %d services", len(services))
+	}
+}
+
+func TestUpdateAndDeleteService(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	original := *servicesByID["svc-002"]
+	if rr := do("PUT", "/api/services/svc-002", `{"price":2500}`); rr.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d", rr.Code)
+	}
+	updated := servicesByID["svc-002"]
+	if updated.Price != 2500 || updated.Name != original.Name || updated.Category != original.Category {
+		t.Errorf("update should only change the price, got %+v", updated)
+	}
+	if rr := do("PUT", "/api/services/svc-999", `{"price":1}`); rr.Code != http.StatusNotFound {
+		t.Errorf("update unknown: expected 404, got %d", rr.Code)
+	}
+
+	before := len(services)
+	if rr := do("DELETE", "/api/services/svc-001", ""); rr.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", rr.Code)
+	}
+	if len(services) != before-1 {
+		t.Errorf("expected %d services after delete, got %d", before-1, len(services))
+	}
+	if _, ok := servicesByID["svc-001"]; ok {
+		t.Error("deleted service should be removed from the index")
+	}
+	if _, ok := serviceStats["svc-001"]; ok {
+		t.Error("deleted service should have no stats entry")
+	}
+	for id, svc := range servicesByID {
+		if svc.ID != id {
+			t.Errorf("servicesByID[%s] points at %s after delete", id, svc.ID)
+		}
+	}
+	if rr := do("DELETE", "/api/services/svc-001", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("repeat delete: expected 404, got %d", rr.Code)
+	}
+
+	// A new service must not reuse a deleted service's ID.
+	svc, err := AddService(Service{Name: "Agility", Category: "Training"})
+	if err != nil {
+		t.Fatalf("AddService: %v", err)
+	}
+	if _, err := AddService(Service{Name: "Agility II", Category: "Training"}); err != nil {
+		t.Fatalf("AddService: %v", err)
+	}
+	if svc.ID == "svc-001" || len(servicesByID) != len(services) {
+		t.Errorf("IDs collided after delete: new ID %s, %d indexed for %d services", svc.ID, len(servicesByID), len(services))
+	}
+
+	if _, err := AddPackage(ServicePackage{Name: "Combo", ServiceIDs: []string{"svc-002", "svc-003"}, Active: true}); err != nil {
+		t.Fatalf("AddPackage: %v", err)
+	}
+	if rr := do("DELETE", "/api/services/svc-003", ""); rr.Code != http.StatusConflict {
+		t.Errorf("deleting a packaged service: expected 409, got %d", rr.Code)
+	}
+}
+
+func TestServicePackages(t *testing.T) {
+	initializeData()
+
+	pkg, err := AddPackage(ServicePackage{Name: "New Pup Bundle", ServiceIDs: []string{"svc-001", "svc-002"}, Active: true})
+	if err != nil {
+		t.Fatalf("AddPackage failed: %v", err)
+	}
+	if pkg.SuggestedPrice != 3150 || pkg.Price != 3150 {
+		t.Errorf("expected suggested price 3150 (10%% off 3500), got suggested=%.2f price=%.2f", pkg.SuggestedPrice, pkg.Price)
+	}
+
+	if _, err := AddPackage(ServicePackage{Name: "Broken", ServiceIDs: []string{"svc-001", "svc-999"}}); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("expected ErrServiceNotFound for unknown service, got %v", err)
+	}
+
+	created, err := BookPackage(pkg.ID, ServiceBooking{OwnerName: "Asha", Email: "asha@example.com", Date: "2024-07-01"})
+	if err != nil {
+		t.Fatalf("BookPackage failed: %v", err)
+	}
+	if len(created) != 2 || created[0].PackageID != pkg.ID || created[1].ServiceID != "svc-002" {
+		t.Errorf("unexpected child bookings: %+v", created)
+	}
+
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	packages = packages[:0] // IDs come from packageSeq, not the slice length
+	req := httptest.NewRequest("POST", "/api/packages", strings.NewReader(`{"name":"Spa Day","serviceIds":["svc-001","svc-002"]}`))
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+	var resp struct {
+		Data ServicePackage `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != http.StatusCreated || resp.Data.ID != "pkg-002" || !resp.Data.Active {
+		t.Fatalf("expected an active pkg-002, got %d %+v", rr.Code, resp.Data)
+	}
+	if _, err := BookPackage("pkg-002", ServiceBooking{OwnerName: "Asha", Email: "asha@example.com", Date: "2024-07-02"}); err != nil {
+		t.Errorf("a package created without \"active\" should be bookable, got %v", err)
+	}
+}
+
+func TestAgeCategory(t *testing.T) {
+	initializeData()
+
+	expected := map[string]string{"pet-001": "Adult", "pet-002": "Young", "pet-003": "Young"}
+	for id, want := range expected {
+		if got := ageCategory(*petsByID[id]); got != want {
+			t.Errorf("%s: expected %s, got %s", id, want, got)
+		}
+	}
+	if got := ageCategory(Pet{Age: 0}); got != "Baby" {
+		t.Errorf("expected Baby for age 0, got %s", got)
+	}
+	if got := ageCategory(Pet{Age: 9}); got != "Senior" {
+		t.Errorf("expected Senior for age 9, got %s", got)
+	}
+
+	pets = append(pets, Pet{ID: "pet-004", Name: "Old Timer", Species: "Dog", Age: 10, Status: "Available"})
+	req := httptest.NewRequest("GET", "/api/pets?ageCategory=Senior", nil)
+	rr := httptest.NewRecorder()
+	getPetsHandler(rr, req)
+
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Data) != 1 || resp.Data[0]["id"] != "pet-004" || resp.Data[0]["ageCategory"] != "Senior" {
+		t.Errorf("expected only pet-004 tagged Senior, got %v", resp.Data)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	initializeData()
+	Register("fan@example.com", "fan", "pass123")
+	token, _ := Login("fan@example.com", "pass123")
+	router := newRouter()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	listFavorites := func() []Pet {
+		var resp struct {
+			Data []Pet `json:"data"`
+		}
+		json.NewDecoder(do("GET", "/api/auth/me/favorites").Body).Decode(&resp)
+		return resp.Data
+	}
+
+	if rr := do("POST", "/api/pets/pet-001/favorite"); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 adding favorite, got %d", rr.Code)
+	}
+	do("POST", "/api/pets/pet-002/favorite")
+	if rr := do("POST", "/api/pets/pet-999/favorite"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 favoriting unknown pet, got %d", rr.Code)
+	}
+	if favs := listFavorites(); len(favs) != 2 || favs[0].ID != "pet-001" {
+		t.Errorf("expected pet-001 and pet-002 in favorites, got %v", favs)
+	}
+
+	if rr := do("DELETE", "/api/pets/pet-001/favorite"); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 removing favorite, got %d", rr.Code)
+	}
+	if favs := listFavorites(); len(favs) != 1 || favs[0].ID != "pet-002" {
+		t.Errorf("expected only pet-002 after removal, got %v", favs)
+	}
+
+	if err := DeletePet("pet-002"); err != nil {
+		t.Fatalf("DeletePet failed: %v", err)
+	}
+	if favs := listFavorites(); len(favs) != 0 {
+		t.Errorf("expected deleted pet to be skipped, got %v", favs)
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	initializeData()
+	user, _ := Register("staffer@example.com", "staffer", "pass123")
+
+	if _, err := SetUserRole(user.ID, "superuser"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("expected ErrInvalidRole, got %v", err)
+	}
+	if user.Role != "user" {
+		t.Errorf("role should be unchanged after rejection, got %s", user.Role)
+	}
+
+	updated, err := SetUserRole(user.ID, "admin")
+	if err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if !updated.IsAdmin {
+		t.Error("expected role admin to set IsAdmin")
+	}
+
+	updated, _ = SetUserRole(user.ID, "staff")
+	if updated.IsAdmin {
+		t.Error("expected role staff to clear IsAdmin")
+	}
+}
+
+func TestPendingRegistrationReminder(t *testing.T) {
+	initializeData()
+	now := time.Now()
+	pendingRegs["soon@example.com"] = &PendingRegistration{Email: "soon@example.com", Username: "soon", Code: "123456", ExpiresAt: now.Add(time.Minute)}
+	pendingRegs["later@example.com"] = &PendingRegistration{Email: "later@example.com", Username: "later", Code: "654321", ExpiresAt: now.Add(4 * time.Minute)}
+	pendingRegs["gone@example.com"] = &PendingRegistration{Email: "gone@example.com", Username: "gone", Code: "000000", ExpiresAt: now.Add(-time.Minute)}
+
+	// Other tests' handlers may still be enqueueing, so only look at reminders.
+	nextReminder := func(wait time.Duration) *NotificationJob {
+		timeout := time.After(wait)
+		for {
+			select {
+			case job := <-notificationCh:
+				if job.JobType == "otp-reminder" {
+					return &job
+				}
+			case <-timeout:
+				return nil
+			}
+		}
+	}
+
+	reapPendingRegistrations(now)
+	job := nextReminder(time.Second)
+	if job == nil {
+		t.Fatal("expected reminder for near-expiry registration")
+	}
+	if job.To != "soon@example.com" {
+		t.Errorf("expected reminder to soon@example.com, got %s", job.To)
+	}
+	if _, exists := pendingRegs["gone@example.com"]; exists {
+		t.Error("expected expired registration to be reaped")
+	}
+
+	reapPendingRegistrations(now.Add(10 * time.Second))
+	if job := nextReminder(50 * time.Millisecond); job != nil {
+		t.Errorf("reminder should only be sent once, got another to %s", job.To)
+	}
+}
+
+func TestWorkersDrainOnStop(t *testing.T) {
+	initializeData()
+	defer initializeData() // leave open channels for the tests that follow
+	origRate := emailRatePerMinute
+	emailRatePerMinute = 0
+	defer func() { emailRatePerMinute = origRate }()
+
+	notifications, payments := notificationCh, paymentCh
+	workers := startWorkers(context.Background())
+	for i := 0; i < 3; i++ {
+		enqueueNotification(NotificationJob{To: "drain@example.com", Subject: "Queued", Body: "b", JobType: "test"})
+	}
+	enqueuePayment(Donation{ID: "don-drain", DonorEmail: "drain@example.com", Amount: 10})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	drainedPayments, drainedEmails, err := workers.stop(ctx)
+	if err != nil {
+		t.Fatalf("workers did not stop: %v", err)
+	}
+	if drainedPayments > 1 || drainedEmails > 3 {
+		t.Errorf("drained more jobs than were queued: %d payments, %d emails", drainedPayments, drainedEmails)
+	}
+	if _, open := <-notifications; open {
+		t.Error("expected notification channel to be closed and drained")
+	}
+	if _, open := <-payments; open {
+		t.Error("expected payment channel to be closed and drained")
+	}
+}
+
+func TestEmailWorkerRateLimit(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	pacer := newEmailPacer(20) // one every 3s
+	pacer.now = func() time.Time { return clock }
+	pacer.sleep = func(d time.Duration) { clock = clock.Add(d) }
+
+	jobs := make(chan NotificationJob, 4)
+	for i := 0; i < 4; i++ {
+		jobs <- NotificationJob{To: fmt.Sprintf("user%d@example.com", i)}
+	}
+	close(jobs)
+
+	var sentAt []time.Time
+	runEmailWorker(jobs, pacer, func(job NotificationJob) {
+		sentAt = append(sentAt, clock)
+	})
+
+	if len(sentAt) != 4 {
+		t.Fatalf("expected 4 sends, got %d", len(sentAt))
+	}
+	for i := 1; i < len(sentAt); i++ {
+		if gap := sentAt[i].Sub(sentAt[i-1]); gap != 3*time.Second {
+			t.Errorf("send %d: expected 3s gap, got %v", i, gap)
+		}
+	}
+}
+
+func TestEmailWorkerPoolDrains(t *testing.T) {
+	const workers, total = 4, 50
+	jobs := make(chan NotificationJob, total)
+	for i := 0; i < total; i++ {
+		jobs <- NotificationJob{To: fmt.Sprintf("user%d@example.com", i)}
+	}
+	close(jobs)
+
+	// The first deliveries hold until every worker has one in hand, which
+	// only happens if the pool really runs them side by side.
+	var delivered, inFlight atomic.Int32
+	allBusy := make(chan struct{})
+	var once sync.Once
+	var wg sync.WaitGroup
+	startEmailWorkers(workers, jobs, newEmailPacer(0), &wg, func(job NotificationJob) {
+		if inFlight.Add(1) == workers {
+			once.Do(func() { close(allBusy) })
+		}
+		select {
+		case <-allBusy:
+		case <-time.After(time.Second):
+		}
+		inFlight.Add(-1)
+		delivered.Add(1)
+	})
+	wg.Wait()
+
+	if n := delivered.Load(); n != total {
+		t.Errorf("expected all %d jobs delivered, got %d", total, n)
+	}
+	select {
+	case <-allBusy:
+	default:
+		t.Errorf("expected %d workers delivering at once", workers)
+	}
+}
+
+func TestCareReason(t *testing.T) {
+	initializeData()
+
+	pet := Pet{Name: "Patch", Species: "Dog", Age: 2, Status: "Under Care"}
+	if valid, _ := validatePet(pet); valid {
+		t.Error("expected Under Care without a reason to be rejected")
+	}
+	pet.CareReason = "Spa Day"
+	if valid, _ := validatePet(pet); valid {
+		t.Error("expected unknown care reason to be rejected")
+	}
+	pet.CareReason = "Quarantine"
+	if valid, errs := validatePet(pet); !valid {
+		t.Errorf("expected Under Care with a valid reason to pass, got %v", errs)
+	}
+
+	if _, err := UpdatePet("pet-001", Pet{Status: "Under Care"}); err == nil {
+		t.Error("expected UpdatePet to Under Care without a reason to fail")
+	}
+	updated, err := UpdatePet("pet-001", Pet{Status: "Under Care", CareReason: "Medical"})
+	if err != nil {
+		t.Fatalf("UpdatePet failed: %v", err)
+	}
+	if updated.CareReason != "Medical" {
+		t.Errorf("expected care reason Medical, got %s", updated.CareReason)
+	}
+	updated, _ = UpdatePet("pet-001", Pet{Status: "Available"})
+	if updated.CareReason != "" {
+		t.Errorf("expected care reason cleared when leaving Under Care, got %s", updated.CareReason)
+	}
+
+	stats := calculateStatistics()
+	byReason := stats["petsByCareReason"].(map[string]int)
+	if byReason["Behavioral"] != 1 {
+		t.Errorf("expected 1 Behavioral pet in statistics, got %v", byReason)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	initializeData()
+	user, _ := Register("leaving@example.com", "leaving", "pass123")
+	token, _ := Login("leaving@example.com", "pass123")
+	ProcessDonation(&Donation{DonorName: "Leaving", DonorEmail: "leaving@example.com", Amount: 250, PaymentMethod: "UPI", UserID: user.ID})
+	donationCount := len(donations)
+	bookings = append(bookings, ServiceBooking{ID: "book-del", OwnerName: "Leaving", Email: "leaving@example.com", Phone: "98765 43210"})
+	contactMessages = append(contactMessages, ContactForm{ID: "msg-del", Name: "Leaving", Email: "Leaving@Example.com"})
+	UpdatePet("pet-001", Pet{Status: "Adopted"})
+	JoinWaitlist("pet-001", "leaving@example.com")
+	JoinWaitlist("pet-001", "staying@example.com")
+
+	deleteReq := func(password string) int {
+		req := httptest.NewRequest("DELETE", "/api/auth/me", strings.NewReader(fmt.Sprintf(`{"password":%q}`, password)))
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := deleteReq("wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for wrong password, got %d", code)
+	}
+	if code := deleteReq("pass123"); code != http.StatusOK {
+		t.Fatalf("expected 200 deleting account, got %d", code)
+	}
+
+	if user.Email == "leaving@example.com" || user.Username == "leaving" || user.IsActive {
+		t.Errorf("expected profile anonymized and deactivated, got %+v", *user)
+	}
+	if _, err := ValidateToken(token.Token); err == nil {
+		t.Error("expected token to be revoked")
+	}
+	if _, err := Login("leaving@example.com", "pass123"); err == nil {
+		t.Error("expected login with old credentials to fail")
+	}
+	if len(donations) != donationCount {
+		t.Errorf("expected %d donations to be preserved, got %d", donationCount, len(donations))
+	}
+	if d := donations[donationCount-1]; d.UserID != user.ID || d.DonorEmail == "leaving@example.com" {
+		t.Errorf("expected donation kept under user ID with scrubbed email, got %+v", d)
+	}
+	if b := bookings[len(bookings)-1]; b.Email != user.Email || b.OwnerName == "Leaving" || b.Phone != "" {
+		t.Errorf("expected booking scrubbed, got %+v", b)
+	}
+	if msg := contactMessages[len(contactMessages)-1]; msg.Email != user.Email || msg.Name == "Leaving" {
+		t.Errorf("expected contact message scrubbed, got %+v", msg)
+	}
+	if got := waitlistByPet["pet-001"]; !reflect.DeepEqual(got, []string{"staying@example.com"}) {
+		t.Errorf("expected only the other address left on the waitlist, got %v", got)
+	}
+}
+
+// stubSMTPServer speaks just enough SMTP to accept one message, optionally
+// advertising STARTTLS with the given certificate.
+func stubSMTPServer(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		reply := func(line string) {
+			rw.WriteString(line + "\r\n")
+			rw.Flush()
+		}
+
+		reply("220 stub ESMTP")
+		inData := false
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if inData {
+				if line == "." {
+					inData = false
+					reply("250 OK")
+				}
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO", "HELO":
+				if cert != nil {
+					reply("250-stub")
+					reply("250-STARTTLS")
+				}
+				reply("250 AUTH PLAIN")
+			case "STARTTLS":
+				reply("220 Ready to start TLS")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*cert}})
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				conn = tlsConn
+				rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+			case "AUTH":
+				reply("235 Authenticated")
+			case "MAIL", "RCPT", "RSET", "NOOP":
+				reply("250 OK")
+			case "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("500 Unknown command")
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestSendMailRequireTLS(t *testing.T) {
+	// Borrow httptest's self-signed 127.0.0.1 certificate for the stub.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	tlsConfig := &tls.Config{ServerName: "127.0.0.1", RootCAs: roots, MinVersion: tls.VersionTLS12}
+	auth := smtp.PlainAuth("", "user", "pass", "127.0.0.1")
+	msg := []byte("Subject: hi\r\n\r\nhello")
+
+	addr := stubSMTPServer(t, &ts.TLS.Certificates[0])
+	if err := sendMailRequireTLS(addr, tlsConfig, auth, "from@example.com", []string{"to@example.com"}, msg); err != nil {
+		t.Errorf("expected send over STARTTLS to succeed, got %v", err)
+	}
+
+	addr = stubSMTPServer(t, nil)
+	if err := sendMailRequireTLS(addr, tlsConfig, auth, "from@example.com", []string{"to@example.com"}, msg); err == nil {
+		t.Error("expected send to fail when server does not offer STARTTLS")
+	}
+}
+
+func TestEnvTLSVersion(t *testing.T) {
+	cases := []struct {
+		value string
+		want  uint16
+	}{
+		{"", tls.VersionTLS12},
+		{"1.2", tls.VersionTLS12},
+		{"1.3", tls.VersionTLS13},
+		{"1.1", tls.VersionTLS12},
+		{"tls13", tls.VersionTLS12},
+	}
+	for _, tc := range cases {
+		t.Setenv("SMTP_TLS_MIN_VERSION", tc.value)
+		if got := envTLSVersion("SMTP_TLS_MIN_VERSION", tls.VersionTLS12); got != tc.want {
+			t.Errorf("SMTP_TLS_MIN_VERSION=%q: expected %x, got %x", tc.value, tc.want, got)
+		}
+	}
+}
+
+func TestDisplayTimezone(t *testing.T) {
+	orig := displayLocation
+	defer func() { displayLocation = orig }()
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	displayLocation = loc
+
+	utc := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := displayTime(utc, displayDateTimeLayout); got != "1 Jan 2024, 5:30 AM IST" {
+		t.Errorf("expected 1 Jan 2024, 5:30 AM IST, got %q", got)
+	}
+}
+
+func TestBulkDeletePets(t *testing.T) {
+	initializeData()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	availableBefore := statusCounts["Available"]
+
+	body := `{"ids":["pet-001","pet-999","pet-003"]}`
+	req := httptest.NewRequest("POST", "/api/pets/bulk-delete", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var resp struct {
+		Data []BulkPetResult `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Data) != 3 || !resp.Data[0].Success || resp.Data[1].Success || resp.Data[1].Reason != "not found" || !resp.Data[2].Success {
+		t.Errorf("unexpected per-ID results: %+v", resp.Data)
+	}
+
+	if len(pets) != 1 || pets[0].ID != "pet-002" {
+		t.Fatalf("expected only pet-002 left, got %v", pets)
+	}
+	if p := petsByID["pet-002"]; p == nil || p != &pets[0] {
+		t.Error("expected petsByID to point at the remaining pet after deletes")
+	}
+	if _, exists := petsByID["pet-001"]; exists {
+		t.Error("expected pet-001 removed from index")
+	}
+	if statusCounts["Available"] != availableBefore-1 || statusCounts["Under Care"] != 0 {
+		t.Errorf("status counts out of sync: %v", statusCounts)
+	}
+}
+
+func TestImportPetsCSV(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	before := len(pets)
+
+	csvData := "Name,Species,Breed,Age,Gender,Status,Vaccinated\n" +
+		"Biscuit,Dog,Beagle,2,Male,,yes\n" +
+		"Ghost,,Persian,old,Female,Missing,maybe\n"
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "pets.csv")
+	part.Write([]byte(csvData))
+	form.Close()
+
+	upload := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/pets/import-csv", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := upload(""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rr.Code)
+	}
+
+	rr := upload(admin.Token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data []CSVRowResult `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected a result per row, got %+v", resp.Data)
+	}
+
+	ok := resp.Data[0]
+	if ok.Line != 2 || !ok.Success || ok.ID == "" {
+		t.Errorf("row 2 should be imported, got %+v", ok)
+	}
+	pet := petsByID[ok.ID]
+	if pet == nil || pet.Name != "Biscuit" || pet.Status != "Available" || !pet.IsVaccinated || pet.Age != 2 {
+		t.Errorf("imported pet not stored as expected: %+v", pet)
+	}
+
+	bad := resp.Data[1]
+	if bad.Line != 3 || bad.Success || bad.ID != "" {
+		t.Errorf("row 3 should be rejected, got %+v", bad)
+	}
+	for _, want := range []string{`Age "old" is not a whole number`, `Vaccinated "maybe" must be yes or no`, "Species is required", "Invalid status"} {
+		if !slices.Contains(bad.Errors, want) {
+			t.Errorf("row 3 errors %v missing %q", bad.Errors, want)
+		}
+	}
+	if len(pets) != before+1 {
+		t.Errorf("expected exactly one pet added, have %d (was %d)", len(pets), before)
+	}
+
+	req := httptest.NewRequest("POST", "/api/pets/import-csv", strings.NewReader("Breed,Age\nBeagle,2\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a header without name/species, got %d", rr.Code)
+	}
+}
+
+func TestExportPetsCSV(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	export := func(query, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/pets/export.csv"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := export("", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rr.Code)
+	}
+
+	rr := export("?species=dog", admin.Token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="pets-`) {
+		t.Errorf("expected a download filename, got %q", cd)
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	if !slices.Equal(records[0], petExportHeader) {
+		t.Errorf("unexpected header: %v", records[0])
+	}
+
+	dog := petsByID["pet-001"]
+	var row []string
+	for _, record := range records[1:] {
+		if record[2] != "Dog" {
+			t.Errorf("?species=dog should only export dogs, got %v", record)
+		}
+		if record[0] == "pet-001" {
+			row = record
+		}
+	}
+	want := []string{"pet-001", "Max", "Dog", dog.Breed, strconv.Itoa(dog.Age), dog.Gender, "Available", strconv.FormatBool(dog.IsVaccinated)}
+	if row == nil || !slices.Equal(row[:8], want) {
+		t.Errorf("expected row %v, got %v", want, row)
+	}
+	if len(records) != 1+len(ApplyFilters(pets, []Filterable{SpeciesFilter{Species: "dog"}})) {
+		t.Errorf("expected one row per dog, got %d rows", len(records)-1)
+	}
+}
+
+func TestBulkArchivePets(t *testing.T) {
+	initializeData()
+	results, archived := BulkArchivePets([]string{"pet-001", "pet-404"})
+	if len(archived) != 1 || !results[0].Success || results[1].Success {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if petsByID["pet-001"].Status != "Archived" || statusCounts["Archived"] != 1 {
+		t.Errorf("expected pet-001 archived and counted, got %s / %v", petsByID["pet-001"].Status, statusCounts)
+	}
+	if results, _ := BulkArchivePets([]string{"pet-001"}); results[0].Success {
+		t.Error("expected already-archived pet to be skipped")
+	}
+	if valid, errs := validatePet(*petsByID["pet-001"]); !valid {
+		t.Errorf("an archived pet should still pass validation so it can be re-saved, got %v", errs)
+	}
+}
+
+func TestAddPetIdempotencyKey(t *testing.T) {
+	initializeData()
+	before := len(pets)
+
+	post := func() *httptest.ResponseRecorder {
+		body := `{"name":"Biscuit","species":"Dog","breed":"Indie","age":2,"status":"Available"}`
+		req := httptest.NewRequest("POST", "/api/pets", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "import-42")
+		rr := httptest.NewRecorder()
+		addPetHandler(rr, req)
+		return rr
+	}
+
+	first := post()
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on first POST, got %d", first.Code)
+	}
+	second := post()
+	if second.Code != http.StatusOK {
+		t.Errorf("expected 200 on replay, got %d", second.Code)
+	}
+
+	var a, b struct {
+		Data Pet `json:"data"`
+	}
+	json.NewDecoder(first.Body).Decode(&a)
+	json.NewDecoder(second.Body).Decode(&b)
+	if a.Data.ID == "" || a.Data.ID != b.Data.ID {
+		t.Errorf("expected replay to return the same pet, got %q and %q", a.Data.ID, b.Data.ID)
+	}
+	if len(pets) != before+1 {
+		t.Errorf("expected exactly one pet created, got %d", len(pets)-before)
+	}
+
+	reapIdempotencyKeys(time.Now().Add(idempotencyKeyTTL + time.Minute))
+	if _, exists := idempotencyKeys["import-42"]; exists {
+		t.Error("expected reaper to expire old idempotency keys")
+	}
+}
+
+func TestAdoptionFunnel(t *testing.T) {
+	initializeData()
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	pets = append(pets,
+		Pet{ID: "pet-004", Status: "Adopted", CreatedAt: time.Now()},
+		Pet{ID: "pet-005", Status: "Adopted", CreatedAt: old},
+	)
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", Status: "Pending", CreatedAt: time.Now()},
+		AdoptionInquiry{ID: "inq-002", Status: "Approved", CreatedAt: time.Now()},
+		AdoptionInquiry{ID: "inq-003", Status: "Rejected", CreatedAt: time.Now()},
+		AdoptionInquiry{ID: "inq-004", Status: "Approved", CreatedAt: old},
+	)
+
+	funnel := adoptionFunnel(time.Time{}, time.Time{})
+	want := map[string]int{"listed": 5, "inquiries": 4, "approved": 2, "adopted": 2}
+	for stage, n := range want {
+		if funnel[stage] != n {
+			t.Errorf("all time %s: expected %d, got %d", stage, n, funnel[stage])
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/statistics/funnel?from=2021-01-01", nil)
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+	var resp struct {
+		Data map[string]int `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	want = map[string]int{"listed": 4, "inquiries": 3, "approved": 1, "adopted": 1}
+	for stage, n := range want {
+		if resp.Data[stage] != n {
+			t.Errorf("since 2021 %s: expected %d, got %d", stage, n, resp.Data[stage])
+		}
+	}
+}
+
+func TestRegisterBlockedUsernames(t *testing.T) {
+	initializeData()
+	orig := blockedUsernameWords
+	blockedUsernameWords = append(blockedUsernameWords, "meanie")
+	defer func() { blockedUsernameWords = orig }()
+
+	register := func(email, username string) int {
+		body := fmt.Sprintf(`{"email":%q,"username":%q,"password":"pass123"}`, email, username)
+		req := httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		registerHandler(rr, req)
+		return rr.Code
+	}
+
+	if code := register("a@example.com", "Admin"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for reserved username Admin, got %d", code)
+	}
+	if code := register("b@example.com", "BigMeanie99"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for blocked word, got %d", code)
+	}
+	if code := register("c@example.com", "priya_k"); code != http.StatusAccepted {
+		t.Errorf("expected 202 for a normal username, got %d", code)
+	}
+}
+
+func TestDisposableEmailBlocked(t *testing.T) {
+	initializeData()
+	domains, err := loadDomainList("disposable_domains.txt")
+	if err != nil {
+		t.Fatalf("loadDomainList: %v", err)
+	}
+	orig := disposableDomains
+	disposableDomains = domains
+	defer func() { disposableDomains = orig }()
+
+	register := func(email string) int {
+		body := fmt.Sprintf(`{"email":%q,"username":"newbie","password":"pass123"}`, email)
+		req := httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		registerHandler(rr, req)
+		return rr.Code
+	}
+
+	if code := register("someone@mailinator.com"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for disposable domain, got %d", code)
+	}
+	if code := register("someone@eu.guerrillamail.com"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for disposable subdomain, got %d", code)
+	}
+	if code := register("someone@gmail.com"); code != http.StatusAccepted {
+		t.Errorf("expected 202 for a normal domain, got %d", code)
+	}
+}
+
+func TestIsValidEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"priya@example.com", true},
+		{"first.last+tag@mail.example.org", true},
+		{"Priya@Example.COM", true},
+		{"notanemail", false},
+		{"", false},
+		{"@example.com", false},
+		{"priya@", false},
+		{"priya@localhost", false},
+		{"priya@@example.com", false},
+		{"Priya <priya@example.com>", false},
+		{"priya @example.com", false},
+	}
+	for _, tt := range tests {
+		if got := isValidEmail(tt.email); got != tt.want {
+			t.Errorf("isValidEmail(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestEmailValidationOnForms(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	post := func(path, body string) int {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rr.Code
+	}
+	adoption := func(email string) string {
+		return fmt.Sprintf(`{"petId":"pet-001","adopterName":"Priya","email":%q,"answers":{"yard":"yes","hoursAlone":"4"}}`, email)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"register malformed", "/api/auth/register", `{"email":"notanemail","username":"newbie","password":"pass123"}`, http.StatusBadRequest},
+		{"register valid", "/api/auth/register", `{"email":" New.User@Example.com ","username":"newbie","password":"pass123"}`, http.StatusAccepted},
+		{"contact malformed", "/api/contact", `{"name":"Asha","email":"asha@","message":"hi"}`, http.StatusBadRequest},
+		{"contact valid", "/api/contact", `{"name":"Asha","email":"Asha@Example.com","message":"hi"}`, http.StatusOK},
+		{"adoption malformed", "/api/adoptions", adoption("priya at example.com"), http.StatusBadRequest},
+		{"adoption valid", "/api/adoptions", adoption("Priya@Example.com"), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		if code := post(tt.path, tt.body); code != tt.wantCode {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.wantCode, code)
+		}
+	}
+
+	if _, ok := pendingRegs["new.user@example.com"]; !ok || len(pendingRegs) != 1 {
+		t.Errorf("expected one pending registration under the lowercased address, got %v", pendingRegs)
+	}
+	if len(contactMessages) != 1 || contactMessages[0].Email != "asha@example.com" {
+		t.Errorf("expected one contact message from asha@example.com, got %+v", contactMessages)
+	}
+	if len(inquiries) != 1 || inquiries[0].Email != "priya@example.com" {
+		t.Errorf("expected one inquiry from priya@example.com, got %+v", inquiries)
+	}
+
+	if _, err := Register("notanemail", "someone", "pass123"); err != ErrInvalidEmail {
+		t.Errorf("Register with malformed email: expected ErrInvalidEmail, got %v", err)
+	}
+	user, err := Register("Mixed@Example.com", "mixed", "pass123")
+	if err != nil || user.Email != "mixed@example.com" {
+		t.Errorf("Register should lowercase the address, got %v, %v", user, err)
+	}
+}
+
+func TestResendReceipt(t *testing.T) {
+	initializeData()
+	donations = append(donations,
+		Donation{ID: "don-001", DonorName: "Meera", DonorEmail: "meera@gmial.com", Amount: 800, Status: "Completed"},
+		Donation{ID: "don-002", DonorName: "Ravi", DonorEmail: "ravi@example.com", Amount: 300, Status: "Pending"},
+	)
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	resend := func(id, body string) int {
+		req := httptest.NewRequest("POST", "/api/donations/"+id+"/resend-receipt", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		newRouter().ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := resend("don-001", `{"email":"not-an-email"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid email, got %d", code)
+	}
+	if code := resend("don-002", `{}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for non-completed donation, got %d", code)
+	}
+	if code := resend("don-999", `{}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown donation, got %d", code)
+	}
+	if code := resend("don-001", `{"email":"meera@gmail.com"}`); code != http.StatusOK {
+		t.Fatalf("expected 200 resending receipt, got %d", code)
+	}
+
+	timeout := time.After(time.Second)
+	for found := false; !found; {
+		select {
+		case job := <-notificationCh:
+			if job.JobType == "receipt" {
+				if job.To != "meera@gmail.com" {
+					t.Errorf("expected receipt to corrected email, got %s", job.To)
+				}
+				found = true
+			}
+		case <-timeout:
+			t.Fatal("expected receipt job to be enqueued")
+		}
+	}
+
+	if len(auditLog) != 1 || auditLog[0].Action != "donation.resend-receipt" || auditLog[0].Actor != "admin@pawtner.com" {
+		t.Errorf("expected resend recorded in audit log, got %+v", auditLog)
+	}
+}
+
+func TestRequestReceipt(t *testing.T) {
+	initializeData()
+	donations = append(donations,
+		Donation{ID: "don-001", DonorName: "Meera", DonorEmail: "meera@example.com", Amount: 800, Status: "Completed"},
+		Donation{ID: "don-002", DonorName: "Ravi", DonorEmail: "ravi@example.com", Amount: 300, Status: "Pending"},
+	)
+	router := newRouter()
+	Register("meera@example.com", "meera", "pass123")
+	Register("ravi@example.com", "ravi", "pass123")
+	meera, _ := Login("meera@example.com", "pass123")
+	ravi, _ := Login("ravi@example.com", "pass123")
+
+	requestAs := func(token, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/donations/"+id+"/receipt", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	request := func(id string) int {
+		return requestAs(meera.Token, id).Code
+	}
+
+	if code := requestAs("", "don-001").Code; code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+	if code := requestAs(ravi.Token, "don-001").Code; code != http.StatusForbidden {
+		t.Errorf("expected 403 for someone else's donation, got %d", code)
+	}
+	if code := request("don-999"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown donation, got %d", code)
+	}
+	if code := requestAs(ravi.Token, "don-002").Code; code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a pending donation, got %d", code)
+	}
+	if code := request("don-001"); code != http.StatusOK {
+		t.Fatalf("expected 200 requesting a receipt, got %d", code)
+	}
+	if code := request("don-001"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for a second request within a minute, got %d", code)
+	}
+
+	var job NotificationJob
+	timeout := time.After(time.Second)
+	for job.JobType != "receipt" {
+		select {
+		case job = <-notificationCh:
+		case <-timeout:
+			t.Fatal("expected receipt job to be enqueued")
+		}
+	}
+	if job.To != "meera@example.com" || !strings.Contains(job.Body, "don-001") {
+		t.Errorf("expected receipt for don-001 to the donor, got %+v", job)
+	}
+
+	emailShouldFail.Store(true)
+	if err := deliverNotification(job, 1); !errors.Is(err, ErrEmailFailed) {
+		t.Errorf("expected delivery to fail while email is down, got %v", err)
+	}
+	emailShouldFail.Store(false)
+	if err := deliverNotification(job, 1); err != nil {
+		t.Errorf("expected receipt to be delivered, got %v", err)
+	}
+
+	receiptRequests["don-001"] = time.Now().Add(-receiptRequestInterval)
+	rr := requestAs(meera.Token, "don-001")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 once the interval has passed, got %d", rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "meera@example.com") || !strings.Contains(rr.Body.String(), "m***@example.com") {
+		t.Errorf("expected the donor's address to be masked, got %s", rr.Body.String())
+	}
+}
+
+func TestPendingCounts(t *testing.T) {
+	initializeData()
+	inquiries = append(inquiries,
+		AdoptionInquiry{ID: "inq-001", Status: "Pending"},
+		AdoptionInquiry{ID: "inq-002", Status: "Approved"},
+	)
+	donations = append(donations, Donation{ID: "don-001", Status: "Failed"})
+	contactMessages = append(contactMessages, ContactForm{Name: "A"}, ContactForm{Name: "B", Handled: true})
+
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	req := httptest.NewRequest("GET", "/api/admin/pending-counts", nil)
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	var resp struct {
+		Data map[string]int `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Data["inquiries"] != 1 {
+		t.Errorf("expected 1 pending inquiry, got %d", resp.Data["inquiries"])
+	}
+	if resp.Data["failedDonations"] != 1 || resp.Data["messages"] != 1 || resp.Data["bookings"] != 0 {
+		t.Errorf("unexpected counts: %v", resp.Data)
+	}
+}
+
+func TestMarkContactHandled(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/contact",
+		strings.NewReader(`{"name":"Asha","email":"asha@example.com","message":"hi","handled":true}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 submitting, got %d", rr.Code)
+	}
+	if len(contactMessages) != 1 || contactMessages[0].Handled || contactMessages[0].ID != "msg-001" {
+		t.Fatalf("submitter must not be able to mark a message handled, got %+v", contactMessages)
+	}
+
+	asAdmin := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			Handled bool   `json:"handled"`
+		} `json:"data"`
+	}
+	json.NewDecoder(asAdmin("GET", "/api/admin/contact-messages?handled=false").Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0].ID != "msg-001" || list.Data[0].Handled {
+		t.Errorf("expected msg-001 listed as unhandled, got %+v", list.Data)
+	}
+
+	if rr := asAdmin("PUT", "/api/admin/contact-messages/msg-001/handled"); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 marking handled, got %d", rr.Code)
+	}
+	if !contactMessages[0].Handled {
+		t.Error("expected msg-001 to be handled")
+	}
+	if rr := asAdmin("PUT", "/api/admin/contact-messages/msg-999/handled"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown message, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("PUT", "/api/admin/contact-messages/msg-001/handled", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without an admin token, got %d", rr.Code)
+	}
+}
+
+func TestStaleListings(t *testing.T) {
+	now := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -120)
+	petList := []Pet{
+		{ID: "pet-001", Status: "Available", CreatedAt: old},
+		{ID: "pet-002", Status: "Available", CreatedAt: old},
+		{ID: "pet-003", Status: "Adopted", CreatedAt: old},
+		{ID: "pet-004", Status: "Available", CreatedAt: now.AddDate(0, 0, -10)},
+	}
+	inquiryList := []AdoptionInquiry{{ID: "inq-001", PetID: "pet-002", Status: "Rejected"}}
+
+	got := staleListings(petList, inquiryList, now, 90*24*time.Hour)
+	if !slices.Equal(got, []string{"pet-001"}) {
+		t.Errorf("expected only the long-listed, inquiry-less pet-001, got %v", got)
+	}
+}
+
+func TestFlagStaleListings(t *testing.T) {
+	initializeData()
+	origEmail := staleListingEmail
+	staleListingEmail = true
+	defer func() { staleListingEmail = origEmail }()
+
+	now := time.Now()
+	for i := range pets {
+		pets[i].CreatedAt = now.Add(-staleListingAge - 24*time.Hour)
+	}
+	inquiries = append(inquiries, AdoptionInquiry{ID: "inq-001", PetID: "pet-002", Status: "Pending"})
+
+	reviewStaleListings(now)
+	if !petsByID["pet-001"].NeedsReview {
+		t.Error("long-available pet-001 with no inquiries should be flagged")
+	}
+	if petsByID["pet-002"].NeedsReview {
+		t.Error("pet-002 has an inquiry and should not be flagged")
+	}
+	flaggedCount := pendingCounts()["needsReview"]
+	if flaggedCount == 0 {
+		t.Error("flagged pets should show up in the attention counts")
+	}
+	select {
+	case job := <-notificationCh:
+		if job.To != adminEmail || !strings.Contains(job.Body, "Max") {
+			t.Errorf("unexpected admin email: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an email listing the flagged pets")
+	}
+
+	// Already flagged pets aren't reported again, and interest clears the flag.
+	inquiries = append(inquiries, AdoptionInquiry{ID: "inq-002", PetID: "pet-001", Status: "Pending"})
+	if flagged := flagStaleListings(now); len(flagged) != 0 {
+		t.Errorf("no new pets should be flagged, got %d", len(flagged))
+	}
+	if petsByID["pet-001"].NeedsReview {
+		t.Error("an inquiry should clear the review flag")
+	}
+	if n := pendingCounts()["needsReview"]; n != flaggedCount-1 {
+		t.Errorf("expected %d pets needing review, got %d", flaggedCount-1, n)
+	}
+}
+
+func TestSummarizeDonations(t *testing.T) {
+	from := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	list := []Donation{
+		{DonorEmail: "a@example.com", Amount: 500, Status: "Completed", Campaign: "Monsoon Rescue", CreatedAt: from},
+		{DonorEmail: "A@example.com", Amount: 1500, Status: "Completed", Campaign: "Shelter Roof", CreatedAt: from.AddDate(0, 0, 2)},
+		{DonorEmail: "b@example.com", Amount: 700, Status: "Completed", Campaign: "Monsoon Rescue", CreatedAt: from.AddDate(0, 0, 6)},
+		{DonorEmail: "c@example.com", Amount: 9000, Status: "Failed", Campaign: "Shelter Roof", CreatedAt: from.AddDate(0, 0, 1)},
+		{DonorEmail: "d@example.com", Amount: 4000, Status: "Completed", Campaign: "Shelter Roof", CreatedAt: to},
+	}
+
+	summary := summarizeDonations(list, from, to)
+	if summary.TotalAmount != 2700 || summary.DonationCount != 3 {
+		t.Errorf("expected 3 donations totalling 2700, got %d totalling %.2f", summary.DonationCount, summary.TotalAmount)
+	}
+	if summary.DonorCount != 2 {
+		t.Errorf("expected 2 unique donors, got %d", summary.DonorCount)
+	}
+	if summary.TopCampaign != "Shelter Roof" || summary.TopCampaignTotal != 1500 {
+		t.Errorf("expected top campaign Shelter Roof (1500), got %s (%.2f)", summary.TopCampaign, summary.TopCampaignTotal)
+	}
+}
+
+func TestGenerateReceipt(t *testing.T) {
+	donation := Donation{
+		ID:        "don-001",
+		DonorName: "John",
+		Amount:    1000.00,
+	}
+	receipt := GenerateReceipt(donation)
+	if receipt.DonationID != "don-001" {
+		t.Errorf("expected DonationID don-001, got %s", receipt.DonationID)
+	}
+	if receipt.Amount != 1000.00 {
+		t.Errorf("expected amount 1000.00, got %.2f", receipt.Amount)
+	}
+	if receipt.Message == "" {
+		t.Error("receipt message should not be empty")
+	}
+	if receipt.ReceiptID == "" {
+		t.Error("receipt ID should not be empty")
+	}
+}
+
+// taxClauseReceipts wraps the default receipt with an 80G exemption note.
+type taxClauseReceipts struct{ calls *int }
+
+func (g taxClauseReceipts) Generate(donation Donation) Receipt {
+	*g.calls++
+	receipt := defaultReceiptGenerator{}.Generate(donation)
+	receipt.Message += " Eligible for deduction under Section 80G."
+	return receipt
+}
+
+func TestCustomReceiptGenerator(t *testing.T) {
+	initializeData()
+	calls := 0
+	orig := receiptGenerator
+	receiptGenerator = taxClauseReceipts{calls: &calls}
+	defer func() { receiptGenerator = orig }()
+
+	receipt, err := ProcessDonation(&Donation{
+		DonorName:     "Priya",
+		DonorEmail:    "priya@example.com",
+		Amount:        2500,
+		PaymentMethod: "UPI",
+	})
+	if err != nil {
+		t.Fatalf("ProcessDonation failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the custom generator to be called once, got %d", calls)
+	}
+	if !strings.HasSuffix(receipt.Message, "Section 80G.") || receipt.Amount != 2500 {
+		t.Errorf("expected the custom receipt, got %+v", receipt)
+	}
+}
+
+func TestDonationDedication(t *testing.T) {
+	receipt := GenerateReceipt(Donation{
+		ID:             "don-001",
+		DonorName:      "John",
+		Amount:         1000.00,
+		DedicationType: "memory",
+		DedicationName: "Bruno",
+	})
+	if !strings.Contains(receipt.Message, "in memory of Bruno") {
+		t.Errorf("expected dedication in receipt message, got %q", receipt.Message)
+	}
+
+	initializeData()
+	_, err := ProcessDonation(&Donation{
+		DonorName:      "A",
+		DonorEmail:     "a@b.com",
+		Amount:         100,
+		PaymentMethod:  "UPI",
+		DedicationType: "honor",
+	})
+	if err == nil {
+		t.Error("expected error for dedication type without a name")
+	}
+}
+
+func TestDedicationNotice(t *testing.T) {
+	initializeData()
+	gift := Donation{
+		ID:             "don-001",
+		DonorName:      "John\r\nBcc: everyone@example.com",
+		DonorEmail:     "john@example.com",
+		Status:         "Pending",
+		DedicationType: "honor",
+		DedicationName: "Bruno",
+		HonoreeEmail:   "bruno@example.com",
+	}
+
+	sendDedicationNotice(gift)
+	select {
+	case job := <-notificationCh:
+		t.Fatalf("no notice expected before payment completes, got %+v", job)
+	default:
+	}
+
+	gift.Status = "Completed"
+	for i := 0; i < dedicationNoticeLimit; i++ {
+		sendDedicationNotice(gift)
+		select {
+		case job := <-notificationCh:
+			if job.JobType != "dedication" || job.To != "bruno@example.com" {
+				t.Errorf("unexpected job: %+v", job)
+			}
+			if strings.Contains(job.Body, "\n") && strings.Contains(job.Body, "John\r\n") {
+				t.Errorf("donor name should be collapsed to one line, got %q", job.Body)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected a dedication notice")
+		}
+	}
+	sendDedicationNotice(gift)
+	select {
+	case job := <-notificationCh:
+		t.Errorf("notice past the daily limit should be dropped, got %+v", job)
+	default:
+	}
+
+	_, err := ProcessDonation(&Donation{
+		DonorName:      "A",
+		DonorEmail:     "a@b.com",
+		Amount:         100,
+		PaymentMethod:  "UPI",
+		DedicationType: "honor",
+		DedicationName: "Bruno",
+		HonoreeEmail:   "not-an-email",
+	})
+	if err == nil {
+		t.Error("expected error for an invalid honoree email")
+	}
+}
+
+func TestBuildGivingStatement(t *testing.T) {
+	initializeData()
+	user, _ := Register("giver@example.com", "giver", "pass123")
+
+	thisYear := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	lastYear := time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)
+	donations = append(donations,
+		Donation{ID: "don-001", UserID: user.ID, Amount: 500, Status: "Completed", CreatedAt: thisYear},
+		Donation{ID: "don-002", DonorEmail: "GIVER@example.com", Amount: 250, Status: "Completed", CreatedAt: thisYear},
+		Donation{ID: "don-003", UserID: user.ID, Amount: 1000, Status: "Completed", CreatedAt: lastYear},
+		Donation{ID: "don-004", UserID: user.ID, Amount: 300, Status: "Failed", CreatedAt: thisYear},
+		Donation{ID: "don-005", UserID: "usr-other", DonorEmail: "other@example.com", Amount: 999, Status: "Completed", CreatedAt: thisYear},
+	)
+
+	st := BuildGivingStatement(*user, 2024)
+	if st.Total != 750 {
+		t.Errorf("expected 2024 total 750, got %.2f", st.Total)
+	}
+	if len(st.Donations) != 2 {
+		t.Errorf("expected 2 donations in 2024 statement, got %d", len(st.Donations))
+	}
+
+	token, _ := Login("giver@example.com", "pass123")
+	req := httptest.NewRequest("GET", "/api/donations/statement?year=2024&format=pdf", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	rr := httptest.NewRecorder()
+	donationStatementHandler(rr, req)
+	if rr.Code != http.StatusOK || !bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")) {
+		t.Errorf("expected PDF statement, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/donations/statement", nil)
+	rr = httptest.NewRecorder()
+	donationStatementHandler(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rr.Code)
+	}
+}
+
+// Test search accuracy, filter combinations
+
+func TestSpeciesFilter(t *testing.T) {
+	initializeData()
+	f := SpeciesFilter{Species: "Dog"}
+	result := f.Filter(pets)
+	for _, p := range result {
+		if p.Species != "Dog" {
+			t.Errorf("expected Dog, got %s", p.Species)
+		}
+	}
+	if len(result) == 0 {
+		t.Error("expected at least one dog in sample data")
+	}
+	if f.Name() != "SpeciesFilter" {
+		t.Errorf("unexpected filter name: %s", f.Name())
+	}
+}
+
+func TestVaccinationFilter(t *testing.T) {
+	initializeData()
+	vaccinated := VaccinationFilter{Vaccinated: true}.Filter(pets)
+	for _, p := range vaccinated {
+		if !p.IsVaccinated || p.Name == "Charlie" {
+			t.Errorf("unexpected pet in vaccinated=true result: %s", p.Name)
+		}
+	}
+	if len(vaccinated) != 2 {
+		t.Errorf("expected 2 vaccinated pets, got %d", len(vaccinated))
+	}
+	unvaccinated := VaccinationFilter{Vaccinated: false}.Filter(pets)
+	if len(unvaccinated) != 1 || unvaccinated[0].Name != "Charlie" {
+		t.Errorf("expected only Charlie unvaccinated, got %v", unvaccinated)
+	}
+	if (VaccinationFilter{}).Name() != "VaccinationFilter" {
+		t.Error("unexpected filter name")
+	}
+
+	composed := ApplyFilters(pets, []Filterable{
+		SpeciesFilter{Species: "Dog"},
+		StatusFilter{Status: "Available"},
+		VaccinationFilter{Vaccinated: true},
+	})
+	if len(composed) != 1 || composed[0].Name != "Max" {
+		t.Errorf("expected only Max for vaccinated available dogs, got %v", composed)
+	}
+
+	tests := []struct {
+		query string
+		code  int
+		count int
+	}{
+		{"", http.StatusOK, 3},
+		{"?vaccinated=true", http.StatusOK, 2},
+		{"?vaccinated=false", http.StatusOK, 1},
+		{"?species=Dog&vaccinated=false", http.StatusOK, 1},
+		{"?vaccinated=maybe", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		getPetsHandler(rr, httptest.NewRequest("GET", "/api/pets"+tt.query, nil))
+		if rr.Code != tt.code {
+			t.Errorf("%q: expected %d, got %d", tt.query, tt.code, rr.Code)
+			continue
+		}
+		var resp struct {
+			Data []Pet `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if len(resp.Data) != tt.count {
+			t.Errorf("%q: expected %d pets, got %d", tt.query, tt.count, len(resp.Data))
+		}
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	initializeData()
+	names := func(list []Pet) []string {
+		var out []string
+		for _, p := range list {
+			out = append(out, p.Name)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"single tag", []string{"friendly"}, []string{"Max"}},
+		{"multiple tags", []string{"Indoor", "PLAYFUL"}, []string{"Charlie", "Luna"}},
+		{"no match", []string{"Aquatic"}, nil},
+		{"empty list", nil, []string{"Charlie", "Luna", "Max"}},
+	}
+	for _, tt := range tests {
+		got := names(TagFilter{Tags: tt.tags}.Filter(pets))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	untagged := []Pet{{ID: "pet-x", Name: "NoTags"}}
+	if got := (TagFilter{Tags: []string{"Friendly"}}).Filter(untagged); len(got) != 0 {
+		t.Errorf("expected pet with nil Tags to be excluded, got %v", got)
+	}
+	if (TagFilter{}).Name() != "TagFilter" {
+		t.Error("unexpected filter name")
+	}
+
+	for _, query := range []string{"?tags=Calm,Young", "?tags=Calm&tags=Young"} {
+		rr := httptest.NewRecorder()
+		getPetsHandler(rr, httptest.NewRequest("GET", "/api/pets"+query, nil))
+		var resp struct {
+			Data []Pet `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if got := names(resp.Data); !reflect.DeepEqual(got, []string{"Charlie", "Luna"}) {
+			t.Errorf("%s: expected Charlie and Luna, got %v", query, got)
+		}
+	}
+}
+
+func TestPetFacets(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	get := func(path string) (map[string]map[string]int, int) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		var resp struct {
+			Data map[string]map[string]int `json:"data"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.Data, rr.Code
+	}
+
+	facets, code := get("/api/pets/facets?attr=Color,Size")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	wantColor := map[string]int{"Golden": 1, "White": 1, "Brown and White": 1}
+	if !reflect.DeepEqual(facets["Color"], wantColor) {
+		t.Errorf("expected Color facets %v, got %v", wantColor, facets["Color"])
+	}
+	wantSize := map[string]int{"Large": 1, "Medium": 2}
+	if !reflect.DeepEqual(facets["Size"], wantSize) {
+		t.Errorf("expected Size facets %v, got %v", wantSize, facets["Size"])
+	}
+
+	facets, _ = get("/api/pets/facets?attr=Size&status=Available")
+	if want := map[string]int{"Large": 1, "Medium": 1}; !reflect.DeepEqual(facets["Size"], want) {
+		t.Errorf("expected Available Size facets %v, got %v", want, facets["Size"])
+	}
+
+	if _, code := get("/api/pets/facets"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without attr, got %d", code)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	initializeData()
+	router := newRouter()
+
+	decode := func(rr *httptest.ResponseRecorder) (code, message string) {
+		var resp struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.Code, resp.Message
+	}
+
+	body := `{"donorName":"Asha","donorEmail":"asha@example.com","amount":-50,"paymentMethod":"UPI"}`
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/donations", strings.NewReader(body)))
+	code, message := decode(rr)
+	if rr.Code != http.StatusBadRequest || code != "INVALID_PAYMENT" {
+		t.Errorf("expected 400 INVALID_PAYMENT for a negative donation, got %d %q", rr.Code, code)
+	}
+	if message != ErrInvalidPayment.Error() {
+		t.Errorf("expected human message to be kept, got %q", message)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/pets/pet-999", nil))
+	if code, _ := decode(rr); code != "PET_NOT_FOUND" {
+		t.Errorf("expected PET_NOT_FOUND, got %q", code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/audit-log", nil))
+	if code, _ := decode(rr); code != "UNAUTHORIZED" {
+		t.Errorf("expected UNAUTHORIZED fallback code, got %q", code)
+	}
+
+	if got := errorCode(fmt.Errorf("wrapped: %w", ErrServiceNotFound), http.StatusBadRequest); got != "SERVICE_NOT_FOUND" {
+		t.Errorf("expected wrapped sentinel to map to SERVICE_NOT_FOUND, got %q", got)
+	}
+	if got := statusErrorCode(http.StatusTooManyRequests); got != "RATE_LIMITED" {
+		t.Errorf("expected RATE_LIMITED for 429, got %q", got)
+	}
+}
+
+func TestDecodeErrorMessages(t *testing.T) {
+	initializeData()
+	router := newRouter()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	post := func(body string) (int, string, string) {
+		req := httptest.NewRequest("POST", "/api/pets", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+admin.Token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp.Code, resp.Message
+	}
+
+	body := `{"name":"Rex","species":"Dog","age":"three"}`
+	status, code, message := post(body)
+	want := fmt.Sprintf("Invalid JSON: field 'age' expected number but got string at offset %d",
+		strings.Index(body, `"three"`)+len(`"three"`))
+	if status != http.StatusBadRequest || code != "INVALID_JSON" {
+		t.Errorf("expected 400 INVALID_JSON, got %d %q", status, code)
+	}
+	if message != want {
+		t.Errorf("wrong-typed field:\n got  %q\n want %q", message, want)
+	}
+
+	if _, _, message := post(`{"name": "Rex",}`); !strings.Contains(message, "at offset 16") {
+		t.Errorf("syntax error should report its offset, got %q", message)
+	}
+	if _, _, message := post(``); message != "Invalid JSON: request body is empty" {
+		t.Errorf("empty body: got %q", message)
+	}
+}
+
+func TestSortPets(t *testing.T) {
+	initializeData()
+	ids := func(list []Pet) []string {
+		var out []string
+		for _, p := range list {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+	original := ids(pets)
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"name", []string{"pet-003", "pet-002", "pet-001"}},
+		{"-name", []string{"pet-001", "pet-002", "pet-003"}},
+		{"age", []string{"pet-003", "pet-002", "pet-001"}},
+		{"-age", []string{"pet-001", "pet-002", "pet-003"}},
+		{"createdAt", []string{"pet-001", "pet-002", "pet-003"}},
+		{"-createdAt", []string{"pet-003", "pet-002", "pet-001"}},
+		{"name_asc", []string{"pet-003", "pet-002", "pet-001"}},
+		{"name_desc", []string{"pet-001", "pet-002", "pet-003"}},
+		{"age_asc", []string{"pet-003", "pet-002", "pet-001"}},
+		{"age_desc", []string{"pet-001", "pet-002", "pet-003"}},
+		{"createdAt_asc", []string{"pet-001", "pet-002", "pet-003"}},
+		{"createdAt_desc", []string{"pet-003", "pet-002", "pet-001"}},
+		{"bogus", []string{"pet-003", "pet-002", "pet-001"}}, // falls back to createdAt_desc
+	}
+	for _, tt := range tests {
+		if got := ids(sortPets(pets, tt.key)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort=%s: expected %v, got %v", tt.key, tt.want, got)
+		}
 	}
-	receipt := GenerateReceipt(donation)
-	if receipt.DonationID != "don-001" {
-		t.Errorf("expected DonationID don-001, got %s", receipt.DonationID)
+	if got := ids(pets); !reflect.DeepEqual(got, original) {
+		t.Errorf("sortPets should not reorder the input, got %v", got)
 	}
-	if receipt.Amount != 1000.00 {
-		t.Errorf("expected amount 1000.00, got %.2f", receipt.Amount)
+
+	tied := []Pet{{ID: "b", Age: 2}, {ID: "a", Age: 2}, {ID: "c", Age: 1}}
+	if got := ids(sortPets(tied, "-age")); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("expected ties broken by ID, got %v", got)
 	}
-	if receipt.Message == "" {
-		t.Error("receipt message should not be empty")
+
+	rr := httptest.NewRecorder()
+	getPetsHandler(rr, httptest.NewRequest("GET", "/api/pets?species=Dog&sort=-age", nil))
+	var resp struct {
+		Data []Pet `json:"data"`
 	}
-	if receipt.ReceiptID == "" {
-		t.Error("receipt ID should not be empty")
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if got := ids(resp.Data); !reflect.DeepEqual(got, []string{"pet-001", "pet-003"}) {
+		t.Errorf("expected dogs oldest first, got %v", got)
 	}
 }
 
-// Test search accuracy, filter combinations
-
-func TestSpeciesFilter(t *testing.T) {
+func TestBreedFilter(t *testing.T) {
 	initializeData()
-	f := SpeciesFilter{Species: "Dog"}
+	f := BreedFilter{Breed: "beagle"}
 	result := f.Filter(pets)
 	for _, p := range result {
-		if p.Species != "Dog" {
-			t.Errorf("expected Dog, got %s", p.Species)
+		if p.Breed != "Beagle" {
+			t.Errorf("expected Beagle, got %s", p.Breed)
 		}
 	}
 	if len(result) == 0 {
-		t.Error("expected at least one dog in sample data")
+		t.Error("expected at least one beagle in sample data")
 	}
-	if f.Name() != "SpeciesFilter" {
+	if f.Name() != "BreedFilter" {
 		t.Errorf("unexpected filter name: %s", f.Name())
 	}
+
+	if all := (BreedFilter{}).Filter(pets); len(all) != len(pets) {
+		t.Errorf("expected empty breed to return all %d pets, got %d", len(pets), len(all))
+	}
+
+	req := httptest.NewRequest("GET", "/api/pets?species=Dog&breed=Beagle", nil)
+	rr := httptest.NewRecorder()
+	getPetsHandler(rr, req)
+	var resp struct {
+		Data []Pet `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Data) != 1 || resp.Data[0].Breed != "Beagle" {
+		t.Errorf("expected one Beagle for ?species=Dog&breed=Beagle, got %v", resp.Data)
+	}
 }
 
 func TestStatusFilter(t *testing.T) {
@@ -319,7 +4412,7 @@ func TestApplyFilters(t *testing.T) {
 func TestSearchPets(t *testing.T) {
 	initializeData()
 
-	result, err := SearchPets("Max", nil)
+	result, _, err := SearchPets("Max", nil, 1, 0)
 	if err != nil {
 		t.Fatalf("SearchPets failed: %v", err)
 	}
@@ -327,7 +4420,7 @@ func TestSearchPets(t *testing.T) {
 		t.Error("expected to find Max")
 	}
 
-	result, err = SearchPets("dog", nil)
+	result, _, err = SearchPets("dog", nil, 1, 0)
 	if err != nil {
 		t.Fatalf("SearchPets by species failed: %v", err)
 	}
@@ -335,12 +4428,12 @@ func TestSearchPets(t *testing.T) {
 		t.Error("expected dogs in results")
 	}
 
-	_, err = SearchPets("", nil)
+	_, _, err = SearchPets("", nil, 1, 0)
 	if err == nil {
 		t.Error("expected error for empty query with no filters")
 	}
 
-	result, err = SearchPets("", []Filterable{SpeciesFilter{Species: "Cat"}})
+	result, _, err = SearchPets("", []Filterable{SpeciesFilter{Species: "Cat"}}, 1, 0)
 	if err != nil {
 		t.Fatalf("SearchPets with filter failed: %v", err)
 	}
@@ -351,10 +4444,75 @@ func TestSearchPets(t *testing.T) {
 	}
 }
 
+func TestSearchPetsPaged(t *testing.T) {
+	initializeData()
+	for i := 0; i < 7; i++ {
+		pets = append(pets, Pet{ID: fmt.Sprintf("pet-1%02d", i), Name: fmt.Sprintf("Pup %d", i), Species: "Dog", Status: "Available"})
+	}
+
+	// 2 seeded dogs + 7 pups = 9 matches; page 2 of size 4 holds matches 5-8.
+	result, total, err := SearchPets("dog", nil, 2, 4)
+	if err != nil {
+		t.Fatalf("SearchPets failed: %v", err)
+	}
+	if total != 9 {
+		t.Errorf("expected total 9, got %d", total)
+	}
+	if len(result) != 4 || result[0].ID != "pet-102" {
+		t.Errorf("expected 4 results starting at pet-102, got %d starting at %v", len(result), result)
+	}
+
+	result, total, _ = SearchPets("dog", nil, 3, 4)
+	if total != 9 || len(result) != 1 {
+		t.Errorf("expected last page of 1 with total 9, got %d of %d", len(result), total)
+	}
+}
+
+func BenchmarkSearchPets(b *testing.B) {
+	initializeData()
+	for i := 0; i < 5000; i++ {
+		pets = append(pets, Pet{ID: fmt.Sprintf("pet-b%04d", i), Name: "Bench", Species: "Dog", Breed: "Mixed", Status: "Available"})
+	}
+	filters := []Filterable{ExcludeStatusFilter{Statuses: unlistedStatuses}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SearchPets("dog", filters, 3, 20)
+	}
+}
+
+func TestSearchExcludesAdoptedByDefault(t *testing.T) {
+	initializeData()
+	UpdatePet("pet-001", Pet{Status: "Adopted"})
+	admin, _ := Login("admin@pawtner.com", "admin123")
+
+	search := func(url, token string) float64 {
+		req := httptest.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		getPetsHandler(rr, req)
+		var resp map[string]interface{}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp["count"].(float64)
+	}
+
+	if n := search("/api/pets?q=Max", ""); n != 0 {
+		t.Errorf("expected adopted Max to be excluded from default search, got %v results", n)
+	}
+	if n := search("/api/pets?q=Max&includeAdopted=true", ""); n != 0 {
+		t.Errorf("includeAdopted should require admin, got %v results", n)
+	}
+	if n := search("/api/pets?q=Max&includeAdopted=true", admin.Token); n != 1 {
+		t.Errorf("expected admin search with includeAdopted to find Max, got %v results", n)
+	}
+}
+
 // Test email delivery, retry mechanism
 
 func TestSendEmail(t *testing.T) {
-	emailShouldFail = false
+	emailShouldFail.Store(false)
 	err := SendEmail("test@example.com", "Subject", "Body")
 	if err != nil {
 		t.Errorf("SendEmail should succeed: %v", err)
@@ -372,18 +4530,44 @@ func TestSendEmail(t *testing.T) {
 }
 
 func TestSendEmailWithRetry(t *testing.T) {
-	emailShouldFail = false
+	emailShouldFail.Store(false)
 	err := SendEmailWithRetry("test@example.com", "Hello", "Body", 3)
 	if err != nil {
 		t.Errorf("SendEmailWithRetry should succeed: %v", err)
 	}
 
-	emailShouldFail = true
+	emailShouldFail.Store(true)
 	err = SendEmailWithRetry("test@example.com", "Hello", "Body", 3)
 	if err == nil {
 		t.Error("expected error when email should fail")
 	}
-	emailShouldFail = false
+	emailShouldFail.Store(false)
+}
+
+func TestTemplateRenderFallback(t *testing.T) {
+	initializeData()
+
+	orig := welcomeEmailTmpl
+	// Parses fine but fails at execution: .Username is a string with no fields.
+	welcomeEmailTmpl = template.Must(template.New("welcome").Parse("{{.Username.Broken}}"))
+	defer func() { welcomeEmailTmpl = orig }()
+
+	sendWelcomeEmail(&User{Email: "fallback@example.com", Username: "fallbackuser", CreatedAt: time.Now()})
+	select {
+	case job := <-notificationCh:
+		if !job.PlainText {
+			t.Error("expected plain-text fallback email")
+		}
+		if job.To != "fallback@example.com" || !strings.Contains(job.Body, "fallbackuser") {
+			t.Errorf("unexpected fallback job: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected fallback email to be dispatched")
+	}
+
+	if err := deliverNotification(NotificationJob{To: "fallback@example.com", Subject: "Hi", Body: "text", PlainText: true}, 1); err != nil {
+		t.Errorf("plain-text delivery should succeed: %v", err)
+	}
 }
 
 // Test email delivery, retry mechanism
@@ -394,14 +4578,18 @@ func TestCORSMiddleware(t *testing.T) {
 	})
 
 	req := httptest.NewRequest("OPTIONS", "/api/pets", nil)
+	req.Header.Set("Origin", "http://localhost:8080")
 	rr := httptest.NewRecorder()
 	handler(rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Errorf("expected 200 for OPTIONS, got %d", rr.Code)
 	}
-	if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
-		t.Error("expected Access-Control-Allow-Origin: *")
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:8080" {
+		t.Errorf("expected allowed origin to be echoed on preflight, got %q", got)
+	}
+	if rr.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("expected Access-Control-Max-Age: 600, got %q", rr.Header().Get("Access-Control-Max-Age"))
 	}
 
 	req = httptest.NewRequest("GET", "/api/pets", nil)
@@ -412,9 +4600,77 @@ func TestCORSMiddleware(t *testing.T) {
 	}
 }
 
+func TestPreflightOnAllRoutes(t *testing.T) {
+	initializeData()
+	handler := newHandler()
+
+	preflight := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		req.Header.Set("Origin", "http://localhost:8080")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	origStatus := corsPreflightStatus
+	defer func() { corsPreflightStatus = origStatus }()
+	for _, status := range []int{http.StatusOK, http.StatusNoContent} {
+		corsPreflightStatus = status
+		for _, path := range []string{"/api/pets", "/dashboard.html", "/no-such-page"} {
+			rr := preflight(path)
+			if rr.Code != status {
+				t.Errorf("OPTIONS %s: expected %d, got %d", path, status, rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:8080" {
+				t.Errorf("OPTIONS %s: expected origin echoed, got %q", path, got)
+			}
+			if rr.Header().Get("Access-Control-Allow-Methods") == "" || rr.Header().Get("Access-Control-Max-Age") == "" {
+				t.Errorf("OPTIONS %s: expected preflight headers, got %v", path, rr.Header())
+			}
+			if rr.Body.Len() != 0 {
+				t.Errorf("OPTIONS %s: expected an empty body, got %q", path, rr.Body.String())
+			}
+		}
+	}
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	handler := enableCORS(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, method := range []string{"GET", "OPTIONS"} {
+		req := httptest.NewRequest(method, "/api/pets", nil)
+		req.Header.Set("Origin", "http://evil.com")
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s from evil.com: expected no ACAO header, got %q", method, got)
+		}
+		if rr.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("%s from evil.com: expected no credentials header", method)
+		}
+
+		req = httptest.NewRequest(method, "/api/pets", nil)
+		req.Header.Set("Origin", "http://localhost:8080")
+		rr = httptest.NewRecorder()
+		handler(rr, req)
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:8080" {
+			t.Errorf("%s from localhost: expected origin echoed, got %q", method, got)
+		}
+		if rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s from localhost: expected Access-Control-Allow-Credentials: true", method)
+		}
+		if rr.Header().Get("Vary") != "Origin" {
+			t.Errorf("%s: expected Vary: Origin", method)
+		}
+	}
+}
+
 func TestGetPetsHandler(t *testing.T) {
 	initializeData()
-	startWorkers()
+	startWorkers(context.Background())
 
 	req := httptest.NewRequest("GET", "/api/pets", nil)
 	rr := httptest.NewRecorder()
@@ -433,9 +4689,43 @@ func TestGetPetsHandler(t *testing.T) {
 	}
 }
 
+func TestListLimitClamped(t *testing.T) {
+	initializeData()
+	maxPageSize = 2
+	defer func() { maxPageSize = 100 }()
+
+	req := httptest.NewRequest("GET", "/api/pets?limit=100000", nil)
+	rr := httptest.NewRecorder()
+	getPetsHandler(rr, req)
+
+	var resp map[string]interface{}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp["count"] != 2.0 || resp["limit"] != 2.0 {
+		t.Errorf("expected page clamped to 2, got count=%v limit=%v", resp["count"], resp["limit"])
+	}
+	if resp["total"] != 3.0 {
+		t.Errorf("expected total 3, got %v", resp["total"])
+	}
+
+	req = httptest.NewRequest("GET", "/api/services?page=2&limit=3", nil)
+	rr = httptest.NewRecorder()
+	getServicesHandler(rr, req)
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp["count"] != 2.0 || resp["page"] != 2.0 {
+		t.Errorf("expected second page of services clamped to 2 items, got count=%v page=%v", resp["count"], resp["page"])
+	}
+
+	req = httptest.NewRequest("GET", "/api/pets?page=-1", nil)
+	rr = httptest.NewRecorder()
+	getPetsHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative page, got %d", rr.Code)
+	}
+}
+
 func TestAddPetHandler(t *testing.T) {
 	initializeData()
-	startWorkers()
+	startWorkers(context.Background())
 
 	body := bytes.NewBufferString(`{"name":"Buddy","species":"Dog","breed":"Labrador","age":2,"status":"Available"}`)
 	req := httptest.NewRequest("POST", "/api/pets", body)
@@ -458,6 +4748,86 @@ func TestAddPetHandler(t *testing.T) {
 	}
 }
 
+func TestAddPetHandlerTooManyTags(t *testing.T) {
+	initializeData()
+
+	tags := make([]string, maxPetTags+1)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag-%d", i)
+	}
+	payload, _ := json.Marshal(Pet{Name: "Buddy", Species: "Dog", Age: 2, Status: "Available", Tags: tags})
+	req := httptest.NewRequest("POST", "/api/pets", bytes.NewReader(payload))
+	rr := httptest.NewRecorder()
+	addPetHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for too many tags, got %d", rr.Code)
+	}
+	if len(pets) != 3 {
+		t.Errorf("pet should not have been added, have %d pets", len(pets))
+	}
+
+	longValue := strings.Repeat("x", maxAttributeValueLength+1)
+	payload, _ = json.Marshal(Pet{Attributes: map[string]string{"Color": longValue}})
+	req = httptest.NewRequest("PUT", "/api/pets/pet-001", bytes.NewReader(payload))
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	req.Header.Set("Authorization", "Bearer "+admin.Token)
+	rr = httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for oversized attribute on update, got %d", rr.Code)
+	}
+}
+
+func TestAdminOnlyRoutes(t *testing.T) {
+	initializeData()
+	admin, _ := Login("admin@pawtner.com", "admin123")
+	Register("plain@example.com", "plainuser", "plainpass")
+	user, _ := Login("plain@example.com", "plainpass")
+	handler := newHandler()
+
+	do := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	routes := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/api/pets", `{"name":"Rex","species":"Dog","age":2,"status":"Available"}`, http.StatusCreated},
+		{"PUT", "/api/pets/pet-002", `{"age":3}`, http.StatusOK},
+		{"GET", "/api/donations", "", http.StatusOK},
+		{"DELETE", "/api/pets/pet-003", "", http.StatusOK},
+	}
+	for _, rt := range routes {
+		if code := do(rt.method, rt.path, rt.body, ""); code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: expected 401, got %d", rt.method, rt.path, code)
+		}
+		if code := do(rt.method, rt.path, rt.body, "not-a-token"); code != http.StatusUnauthorized {
+			t.Errorf("%s %s with a bad token: expected 401, got %d", rt.method, rt.path, code)
+		}
+		if code := do(rt.method, rt.path, rt.body, user.Token); code != http.StatusForbidden {
+			t.Errorf("%s %s as a normal user: expected 403, got %d", rt.method, rt.path, code)
+		}
+		if code := do(rt.method, rt.path, rt.body, admin.Token); code != rt.want {
+			t.Errorf("%s %s as admin: expected %d, got %d", rt.method, rt.path, rt.want, code)
+		}
+	}
+
+	if code := do("OPTIONS", "/api/pets", "", ""); code != http.StatusOK {
+		t.Errorf("CORS preflight should not need a token, got %d", code)
+	}
+	if code := do("GET", "/api/pets", "", ""); code != http.StatusOK {
+		t.Errorf("listing pets should stay public, got %d", code)
+	}
+}
+
 func TestRegisterHandler(t *testing.T) {
 	initializeData()
 
@@ -471,19 +4841,134 @@ func TestRegisterHandler(t *testing.T) {
 		t.Errorf("expected 202, got %d", rr.Code)
 	}
 
-	body = bytes.NewBufferString(`{"email":"handler@test.com","username":"handleruser","password":"pass123"}`)
+	body = bytes.NewBufferString(`{"email":"admin@pawtner.com","username":"handleruser","password":"pass123"}`)
 	req = httptest.NewRequest("POST", "/api/auth/register", body)
 	rr = httptest.NewRecorder()
 	registerHandler(rr, req)
 
 	if rr.Code != http.StatusConflict {
-		t.Errorf("expected 409 for duplicate email, got %d", rr.Code)
+		t.Errorf("expected 409 for an existing account, got %d", rr.Code)
+	}
+}
+
+func TestRegisterTwiceKeepsFirstCode(t *testing.T) {
+	initializeData()
+
+	register := func(password string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"email":"twice@example.com","username":"twiceuser","password":%q}`, password)
+		rr := httptest.NewRecorder()
+		registerHandler(rr, httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body)))
+		return rr
+	}
+	nextCode := func() string {
+		select {
+		case job := <-notificationCh:
+			return job.Body
+		case <-time.After(100 * time.Millisecond):
+			return ""
+		}
+	}
+
+	if rr := register("firstpass"); rr.Code != http.StatusAccepted {
+		t.Fatalf("first register: expected 202, got %d", rr.Code)
+	}
+	first := *pendingRegs["twice@example.com"]
+	if nextCode() == "" {
+		t.Fatal("expected the first code to be emailed")
+	}
+
+	// Registering again while the code is fresh keeps it and sends nothing.
+	if rr := register("secondpass"); rr.Code != http.StatusAccepted {
+		t.Fatalf("second register: expected 202, got %d", rr.Code)
+	}
+	second := pendingRegs["twice@example.com"]
+	if second.Code != first.Code || second.HashedPassword != first.HashedPassword || !second.ExpiresAt.Equal(first.ExpiresAt) {
+		t.Error("a repeat registration must not replace the pending code or details")
+	}
+	if body := nextCode(); body != "" {
+		t.Errorf("no email expected while the first code is fresh, got %q", body)
+	}
+
+	// Near expiry, the same code is resent with a fresh expiry.
+	second.ExpiresAt = time.Now().Add(otpReminderWindow / 2)
+	if rr := register("thirdpass"); rr.Code != http.StatusAccepted {
+		t.Fatalf("third register: expected 202, got %d", rr.Code)
+	}
+	if body := nextCode(); !strings.Contains(body, first.Code) {
+		t.Errorf("expected the original code to be resent, got %q", body)
+	}
+	third := pendingRegs["twice@example.com"]
+	if third.Code != first.Code || time.Until(third.ExpiresAt) <= otpReminderWindow {
+		t.Errorf("expected the same code with a fresh expiry, got code %s expiring %v", third.Code, third.ExpiresAt)
+	}
+
+	// The first code still verifies the account with the first password.
+	verify := fmt.Sprintf(`{"email":"twice@example.com","code":%q}`, first.Code)
+	rr := httptest.NewRecorder()
+	verifyEmailHandler(rr, httptest.NewRequest("POST", "/api/auth/verify-email", strings.NewReader(verify)))
+	if rr.Code != http.StatusCreated && rr.Code != http.StatusOK {
+		t.Fatalf("verify with the first code: expected success, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := Login("twice@example.com", "firstpass"); err != nil {
+		t.Errorf("account should use the first registration's password: %v", err)
+	}
+
+	if rr := register("fourthpass"); rr.Code != http.StatusConflict {
+		t.Errorf("registering a verified email: expected 409, got %d", rr.Code)
+	}
+
+	// Once a code has expired, registering again starts over with new details.
+	pendingRegs["late@example.com"] = &PendingRegistration{Email: "late@example.com", Username: "late", Code: "000000", ExpiresAt: time.Now().Add(-time.Second)}
+	body := `{"email":"late@example.com","username":"lateuser","password":"latepass"}`
+	registerHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body)))
+	if late := pendingRegs["late@example.com"]; late.Username != "lateuser" || !late.ExpiresAt.After(time.Now()) {
+		t.Errorf("an expired registration should be replaced, got %+v", late)
+	}
+}
+
+func TestVerifyEmailAttemptLimit(t *testing.T) {
+	initializeData()
+
+	body := `{"email":"guess@example.com","username":"guesser","password":"secret123"}`
+	registerHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body)))
+	code := pendingRegs["guess@example.com"].Code
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	verify := func(code string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"email":"guess@example.com","code":%q}`, code)
+		verifyEmailHandler(rr, httptest.NewRequest("POST", "/api/auth/verify-email", strings.NewReader(body)))
+		return rr
+	}
+
+	for i := 1; i < maxVerifyAttempts; i++ {
+		verify(wrong)
+	}
+	// A resend near expiry keeps the count, so the code can't be kept alive
+	// and guessed at forever.
+	pendingRegs["guess@example.com"].ExpiresAt = time.Now().Add(otpReminderWindow / 2)
+	registerHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/auth/register", strings.NewReader(body)))
+	if got := pendingRegs["guess@example.com"].Attempts; got != maxVerifyAttempts-1 {
+		t.Fatalf("expected %d attempts carried over the resend, got %d", maxVerifyAttempts-1, got)
+	}
+
+	if rr := verify(wrong); !strings.Contains(rr.Body.String(), "Too many incorrect codes") {
+		t.Errorf("expected the last wrong guess to drop the registration, got %s", rr.Body.String())
+	}
+	if rr := verify(code); rr.Code != http.StatusBadRequest {
+		t.Errorf("the right code must not work once the registration is dropped, got %d", rr.Code)
+	}
+	if _, exists := usersByEmail["guess@example.com"]; exists {
+		t.Error("no account should have been created")
 	}
 }
 
 func TestCreateDonationHandler(t *testing.T) {
 	initializeData()
-	startWorkers()
+	startWorkers(context.Background())
 
 	body := bytes.NewBufferString(`{"donorName":"Bob","donorEmail":"bob@test.com","amount":1000,"paymentMethod":"Card"}`)
 	req := httptest.NewRequest("POST", "/api/donations", body)
@@ -505,4 +4990,284 @@ func TestCreateDonationHandler(t *testing.T) {
 	}
 }
 
+func TestDonationRedirect(t *testing.T) {
+	initializeData()
+	orig := donationSuccessURL
+	donationSuccessURL = "https://example.org/thanks?src=donate"
+	defer func() { donationSuccessURL = orig }()
+
+	donate := func(path, accept string) *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"donorName":"Bob","donorEmail":"bob@test.com","amount":500,"paymentMethod":"UPI"}`)
+		req := httptest.NewRequest("POST", path, body)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		createDonationHandler(rr, req)
+		return rr
+	}
+
+	rr := donate("/api/donations?redirect=true", "")
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", rr.Code)
+	}
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("bad Location header: %v", err)
+	}
+	receiptID := location.Query().Get("receipt")
+	if location.Host != "example.org" || location.Path != "/thanks" || location.Query().Get("src") != "donate" {
+		t.Errorf("unexpected redirect target %s", location)
+	}
+	if !strings.HasPrefix(receiptID, "rcpt-") {
+		t.Errorf("expected the receipt ID in the redirect, got %q", receiptID)
+	}
+
+	if rr := donate("/api/donations", "text/html,application/xhtml+xml"); rr.Code != http.StatusSeeOther {
+		t.Errorf("Accept: text/html: expected 303, got %d", rr.Code)
+	}
+	if rr := donate("/api/donations", "application/json"); rr.Code != http.StatusCreated {
+		t.Errorf("JSON should stay the default, got %d", rr.Code)
+	}
+
+	donationSuccessURL = ""
+	if rr := donate("/api/donations?redirect=true", ""); rr.Code != http.StatusCreated {
+		t.Errorf("without a success URL: expected 201, got %d", rr.Code)
+	}
+}
+
 // Test middleware behavior, routing logic
+
+// Test MongoDB sync policies
+
+// mockCollection records writes instead of talking to MongoDB.
+// The first `failures` writes return an error.
+type mockCollection struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	writes   chan interface{}
+
+	hold   time.Duration // how long each ReplaceOne takes
+	active int
+	peak   int // most ReplaceOne calls seen running at once
+}
+
+func (m *mockCollection) ReplaceOne(_ context.Context, _ any, replacement any, _ ...options.Lister[options.ReplaceOptions]) (*mongo.UpdateResult, error) {
+	m.mu.Lock()
+	m.calls++
+	m.active++
+	m.peak = max(m.peak, m.active)
+	m.mu.Unlock()
+	time.Sleep(m.hold)
+	m.mu.Lock()
+	m.active--
+	if m.failures > 0 {
+		m.failures--
+		m.mu.Unlock()
+		return nil, errors.New("mock: transient failure")
+	}
+	m.mu.Unlock()
+	m.writes <- replacement
+	return &mongo.UpdateResult{}, nil
+}
+
+func (m *mockCollection) DeleteOne(_ context.Context, filter any, _ ...options.Lister[options.DeleteOneOptions]) (*mongo.DeleteResult, error) {
+	m.writes <- filter
+	return &mongo.DeleteResult{}, nil
+}
+
+func TestMongoConcurrencyLimit(t *testing.T) {
+	origLimit, origSlots := mongoMaxConcurrent, mongoSlots
+	mongoMaxConcurrent = 3
+	mongoSlots = make(chan struct{}, mongoMaxConcurrent)
+	defer func() { mongoMaxConcurrent, mongoSlots = origLimit, origSlots }()
+
+	const syncs = 30
+	mock := &mockCollection{writes: make(chan interface{}, syncs), hold: 5 * time.Millisecond}
+	orig := writeCollection
+	writeCollection = func(string, *writeconcern.WriteConcern) docCollection { return mock }
+	defer func() { writeCollection = orig }()
+
+	for i := 0; i < syncs; i++ {
+		syncPetToDB(Pet{ID: fmt.Sprintf("pet-%03d", i)})
+	}
+	for i := 0; i < syncs; i++ {
+		select {
+		case <-mock.writes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of %d syncs completed", i, syncs)
+		}
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.peak > mongoMaxConcurrent {
+		t.Errorf("expected at most %d concurrent writes, saw %d", mongoMaxConcurrent, mock.peak)
+	}
+	if mock.peak < 2 {
+		t.Errorf("expected writes to run concurrently up to the limit, peak was %d", mock.peak)
+	}
+}
+
+func TestDonationWriteConcern(t *testing.T) {
+	mock := &mockCollection{writes: make(chan interface{}, 1)}
+	var gotName string
+	var gotWC *writeconcern.WriteConcern
+	orig := writeCollection
+	writeCollection = func(name string, wc *writeconcern.WriteConcern) docCollection {
+		gotName, gotWC = name, wc
+		return mock
+	}
+	defer func() { writeCollection = orig }()
+
+	syncDonationToDB(Donation{ID: "don-001", Amount: 100})
+	select {
+	case <-mock.writes:
+	case <-time.After(time.Second):
+		t.Fatal("expected donation write")
+	}
+	if gotName != "donations" {
+		t.Errorf("expected donations collection, got %s", gotName)
+	}
+	if gotWC == nil || gotWC.W != "majority" || gotWC.Journal == nil || !*gotWC.Journal {
+		t.Errorf("expected journaled majority write concern for donations, got %+v", gotWC)
+	}
+
+	syncPetToDB(Pet{ID: "pet-001"})
+	<-mock.writes
+	if gotWC == nil || gotWC.W != 1 {
+		t.Errorf("expected w:1 for pets, got %+v", gotWC)
+	}
+	if writePolicy("pets").Timeout >= writePolicy("donations").Timeout {
+		t.Error("pets should use a shorter timeout than donations")
+	}
+}
+
+func TestParseWriteConcern(t *testing.T) {
+	wc, err := parseWriteConcern("majority+journal")
+	if err != nil || wc.W != "majority" || !*wc.Journal {
+		t.Errorf("unexpected result for majority+journal: %+v, %v", wc, err)
+	}
+	wc, err = parseWriteConcern("2")
+	if err != nil || wc.W != 2 {
+		t.Errorf("unexpected result for 2: %+v, %v", wc, err)
+	}
+	if _, err := parseWriteConcern("sometimes"); err == nil {
+		t.Error("expected error for invalid write concern")
+	}
+}
+
+func TestMongoSyncRetry(t *testing.T) {
+	startWorkers(context.Background())
+	mongoRetryBackoff = time.Millisecond
+	defer func() { mongoRetryBackoff = 2 * time.Second }()
+
+	mock := &mockCollection{failures: 3, writes: make(chan interface{}, 1)}
+	orig := writeCollection
+	writeCollection = func(string, *writeconcern.WriteConcern) docCollection { return mock }
+	defer func() { writeCollection = orig }()
+
+	syncPetToDB(Pet{ID: "pet-001"})
+	select {
+	case <-mock.writes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected write to eventually succeed")
+	}
+	mock.mu.Lock()
+	if mock.calls != 4 {
+		t.Errorf("expected 4 attempts (3 failures + success), got %d", mock.calls)
+	}
+	mock.mu.Unlock()
+
+	// A write that never succeeds is recorded for reconciliation.
+	mock.mu.Lock()
+	mock.failures = 100
+	mock.mu.Unlock()
+	mu.Lock()
+	before := len(failedSyncs)
+	mu.Unlock()
+	syncPetToDB(Pet{ID: "pet-002"})
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(failedSyncs)
+		mu.Unlock()
+		if n > before {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failedSyncs) != before+1 || failedSyncs[before].ID != "pet-002" {
+		t.Errorf("expected pet-002 recorded as failed sync, got %+v", failedSyncs)
+	}
+}
+
+func TestMongoRetrySuperseded(t *testing.T) {
+	startWorkers(context.Background())
+	mongoRetryBackoff = 20 * time.Millisecond
+	defer func() { mongoRetryBackoff = 2 * time.Second }()
+
+	mock := &mockCollection{failures: 1, writes: make(chan interface{}, 2)}
+	orig := writeCollection
+	writeCollection = func(string, *writeconcern.WriteConcern) docCollection { return mock }
+	defer func() { writeCollection = orig }()
+
+	// The first write fails and is queued for retry...
+	syncPetToDB(Pet{ID: "pet-001", Name: "Old Name"})
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mock.mu.Lock()
+		calls := mock.calls
+		mock.mu.Unlock()
+		if calls == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// ...but a newer write lands before the retry comes round.
+	syncPetToDB(Pet{ID: "pet-001", Name: "New Name"})
+	select {
+	case doc := <-mock.writes:
+		if doc.(Pet).Name != "New Name" {
+			t.Fatalf("expected the newer write first, got %+v", doc)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the newer write to succeed")
+	}
+
+	select {
+	case doc := <-mock.writes:
+		t.Errorf("the stale retry must not overwrite the newer write, got %+v", doc)
+	case <-time.After(10 * mongoRetryBackoff):
+	}
+}
+
+func TestPetSeqPersisted(t *testing.T) {
+	initializeData()
+	seeded := petSeq
+	mock := &mockCollection{writes: make(chan interface{}, 4)}
+	orig := writeCollection
+	writeCollection = func(string, *writeconcern.WriteConcern) docCollection { return mock }
+	defer func() { writeCollection = orig }()
+
+	mu.Lock()
+	pet := addPetLocked(Pet{Name: "Pip", Species: "Dog", Status: "Available"})
+	mu.Unlock()
+	DeletePet(pet.ID)
+
+	select {
+	case doc := <-mock.writes:
+		if c, ok := doc.(seqCounter); !ok || c.ID != "pets" || c.Seq != petSeq {
+			t.Errorf("expected the pet sequence %d to be persisted, got %+v", petSeq, doc)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the pet sequence to be synced to MongoDB")
+	}
+	if petSeq != seeded+1 {
+		t.Errorf("deleting a pet must not lower the sequence, got %d", petSeq)
+	}
+}